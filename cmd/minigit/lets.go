@@ -3,6 +3,7 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"os"
 
@@ -11,6 +12,12 @@ import (
 
 func main() {
 	if err := cli.Execute(); err != nil {
+		// Some commands report through their exit status alone
+		var code cli.ExitCode
+		if errors.As(err, &code) {
+			os.Exit(int(code))
+		}
+
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
@@ -2,7 +2,9 @@ package cli
 
 import (
 	"fmt"
+	"minigit/internal/ignore"
 	"minigit/internal/objects"
+	"minigit/internal/parallel"
 	"minigit/internal/repository"
 	"os"
 	"path/filepath"
@@ -14,27 +16,48 @@ func handleAdd(args []string) error {
 		return fmt.Errorf("nothing specified, nothing added")
 	}
 
+	force := false
+	var paths []string
+	for _, arg := range args {
+		if arg == "-f" || arg == "--force" {
+			force = true
+			continue
+		}
+		paths = append(paths, arg)
+	}
+	if len(paths) == 0 {
+		return fmt.Errorf("nothing specified, nothing added")
+	}
+
 	repo, err := findRepository()
 	if err != nil {
 		return err
 	}
 
-	for _, arg := range args {
+	for _, arg := range paths {
 		// Special case for "."
 		if arg == "." {
 			repoRoot := repo.GetWorkingDirectory()
-			err := addFile(repo, repoRoot)
+			err := addFile(repo, repoRoot, force)
 			if err != nil {
 				return fmt.Errorf("failed to add directory: %w", err)
 			}
 			continue
 		}
 
-		if err := addFile(repo, arg); err != nil {
+		if err := addFile(repo, arg, force); err != nil {
 			return fmt.Errorf("failed to add '%s': %w", arg, err)
 		}
 	}
 
+	index, err := repo.GetIndex()
+	if err != nil {
+		return err
+	}
+	if err := index.Write(); err != nil {
+		return fmt.Errorf("failed to update index: %w", err)
+	}
+
 	return nil
 }
 
@@ -48,7 +71,7 @@ func findRepository() (*repository.Repository, error) {
 	for {
 		minigitDir := filepath.Join(dir, ".minigit")
 		if _, err := os.Stat(minigitDir); err == nil {
-			return repository.NewRepository(dir)
+			return openRepository(dir)
 		}
 
 		parentDir := filepath.Dir(dir)
@@ -61,70 +84,271 @@ func findRepository() (*repository.Repository, error) {
 	return nil, fmt.Errorf("fatal: not a minigit repository (or any of the parent directories): .minigit")
 }
 
-func addFile(repo *repository.Repository, filePath string) error {
+// Opens a repository whose ref updates are logged under the committer's identity
+func openRepository(dir string) (*repository.Repository, error) {
+	repo, err := repository.NewRepository(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	refsMan, err := repo.GetRefsManager()
+	if err != nil {
+		return nil, err
+	}
+	refsMan.SetIdentity(func() objects.Signature { return reflogIdentity(repo) })
+	return repo, nil
+}
+
+// Converts a command line path to a slash-separated path relative to the repository root
+func toRepoPath(repo *repository.Repository, arg string) (string, error) {
+	absPath, err := filepath.Abs(arg)
+	if err != nil {
+		return "", fmt.Errorf("invalid path: %w", err)
+	}
+
+	relPath, err := filepath.Rel(repo.GetWorkingDirectory(), absPath)
+	if err != nil || relPath == ".." || strings.HasPrefix(relPath, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("fatal: %s: '%s' is outside repository", arg, arg)
+	}
+
+	return filepath.ToSlash(relPath), nil
+}
+
+// Reports whether path is the pathspec itself or lies underneath it
+func matchesPathspec(path, pathspec string) bool {
+	return pathspec == "." || path == pathspec || strings.HasPrefix(path, pathspec+"/")
+}
+
+func addFile(repo *repository.Repository, filePath string, force bool) error {
 	absPath, err := filepath.Abs(filePath)
 	if err != nil {
 		return fmt.Errorf("invalid path: %w", err)
 	}
 
-	info, err := os.Stat(absPath)
+	info, err := os.Lstat(absPath)
 	if err != nil {
-		return fmt.Errorf("fatal: pathspec '%s' did not match any files", filePath)
+		// A tracked path that is gone from disk stages its removal
+		removed, removeErr := removeMissingFromIndex(repo, absPath)
+		if removeErr != nil {
+			return removeErr
+		}
+		if removed == 0 {
+			return fmt.Errorf("fatal: pathspec '%s' did not match any files", filePath)
+		}
+		return nil
 	}
 
 	if info.IsDir() {
-		return addDirectory(repo, absPath)
+		if err := addDirectory(repo, absPath, force); err != nil {
+			return err
+		}
+		_, err := removeMissingFromIndex(repo, absPath)
+		return err
+	}
+
+	// Naming an ignored file explicitly needs --force, unless it is already tracked
+	if !force {
+		relPath, err := toRepoPath(repo, absPath)
+		if err != nil {
+			return err
+		}
+		tracked, err := trackedPaths(repo)
+		if err != nil {
+			return err
+		}
+		if !tracked[relPath] {
+			matcher, err := newIgnoreMatcher(repo)
+			if err != nil {
+				return err
+			}
+			if ignored, err := matcher.IsIgnored(relPath, false); err != nil {
+				return err
+			} else if ignored {
+				return fmt.Errorf("The following paths are ignored by one of your %s files:\n%s\nhint: Use -f if you really want to add them.", ignore.FileName, relPath)
+			}
+		}
 	}
 
 	return addSingleFile(repo, absPath, info)
 }
 
-func addDirectory(repo *repository.Repository, dirPath string) error {
-	repoRoot := repo.GetWorkingDirectory()
-	if repoRoot == "" {
-		return fmt.Errorf("not in a repository")
+func addDirectory(repo *repository.Repository, dirPath string, force bool) error {
+	var files []workingFile
+	err := walkWorkingTree(repo, dirPath, force, func(absPath, relPath string, info os.FileInfo) error {
+		files = append(files, workingFile{absPath: absPath, relPath: filepath.FromSlash(relPath), info: info})
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return addFiles(repo, files)
+}
+
+// Walks the files under dir in the working tree, skipping repository
+// metadata and, unless includeIgnored is set, ignored files that are not tracked
+func walkWorkingTree(repo *repository.Repository, dir string, includeIgnored bool, visit func(absPath, relPath string, info os.FileInfo) error) error {
+	workDir := repo.GetWorkingDirectory()
+
+	matcher, err := newIgnoreMatcher(repo)
+	if err != nil {
+		return err
+	}
+
+	tracked, err := trackedPaths(repo)
+	if err != nil {
+		return err
 	}
 
-	return filepath.Walk(dirPath, func(path string, info os.FileInfo, err error) error {
+	return filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
 
-		// Skip directories and .*git/
-		if info.IsDir() || strings.Contains(path, ".minigit") || strings.Contains(path, ".git") {
+		relPath, err := filepath.Rel(workDir, path)
+		if err != nil {
+			return err
+		}
+		relPath = filepath.ToSlash(relPath)
+
+		if info.IsDir() {
+			if relPath == "." {
+				return nil
+			}
+			if info.Name() == ".minigit" || info.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			if includeIgnored {
+				return nil
+			}
+			// Ignored directories are still entered for the tracked files inside them
+			if ignored, err := matcher.IsIgnored(relPath, true); err != nil {
+				return err
+			} else if ignored && !hasTrackedUnder(tracked, relPath) {
+				return filepath.SkipDir
+			}
 			return nil
 		}
 
-		return addSingleFile(repo, path, info)
+		if !includeIgnored && !tracked[relPath] {
+			if ignored, err := matcher.IsIgnored(relPath, false); err != nil {
+				return err
+			} else if ignored {
+				return nil
+			}
+		}
+
+		return visit(path, relPath, info)
 	})
 }
 
+func newIgnoreMatcher(repo *repository.Repository) (*ignore.Matcher, error) {
+	return ignore.NewMatcher(repo.GetWorkingDirectory(), repo.GetMinigitDirectory())
+}
+
+// Returns the slash-separated paths recorded in the index, including unmerged ones
+func trackedPaths(repo *repository.Repository) (map[string]bool, error) {
+	idx, err := repo.GetIndex()
+	if err != nil {
+		return nil, err
+	}
+
+	tracked := make(map[string]bool)
+	for path := range idx.GetEntries() {
+		tracked[filepath.ToSlash(path)] = true
+	}
+	for path := range idx.GetConflicts() {
+		tracked[filepath.ToSlash(path)] = true
+	}
+	return tracked, nil
+}
+
+func hasTrackedUnder(tracked map[string]bool, dir string) bool {
+	for path := range tracked {
+		if strings.HasPrefix(path, dir+"/") {
+			return true
+		}
+	}
+	return false
+}
+
 func addSingleFile(repo *repository.Repository, absPath string, info os.FileInfo) error {
-	content, err := os.ReadFile(absPath)
+	repoRoot := repo.GetWorkingDirectory()
+	relPath, err := filepath.Rel(repoRoot, absPath)
+	if err != nil {
+		return fmt.Errorf("failed to get relative path: %w", err)
+	}
+
+	return addFiles(repo, []workingFile{{absPath: absPath, relPath: relPath, info: info}})
+}
+
+// Stores the blobs of files on the worker pool, then stages them in order
+func addFiles(repo *repository.Repository, files []workingFile) error {
+	idx, err := repo.GetIndex()
 	if err != nil {
-		return fmt.Errorf("failed to read file: %w", err)
+		return err
 	}
 
-	// Store as blob object and get hash
 	store, err := repo.GetObjectStore()
 	if err != nil {
 		return fmt.Errorf("failed to access object store: %w", err)
 	}
 
-	hash, err := store.StoreObject(objects.BlobObject, content)
+	hashes, err := parallel.Map(files, func(file workingFile) (string, error) {
+		// Files whose stat data matches their entry are already staged as they are
+		if entry, tracked := idx.GetEntry(file.relPath); tracked && idx.IsUpToDate(entry, file.info) {
+			return "", nil
+		}
+
+		hash, err := storeWorkingFile(store, file.absPath, file.info)
+		if err != nil {
+			return "", fmt.Errorf("failed to store object: %w", err)
+		}
+		return hash, nil
+	})
 	if err != nil {
-		return fmt.Errorf("failed to store object: %w", err)
+		return err
 	}
 
-	repoRoot := repo.GetWorkingDirectory()
-	relPath, err := filepath.Rel(repoRoot, absPath)
+	for i, file := range files {
+		if hashes[i] == "" {
+			continue
+		}
+		if err := repo.AddToIndex(file.relPath, hashes[i], file.info); err != nil {
+			return fmt.Errorf("failed to add to index: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// Drops index entries at or below absPath whose files no longer exist,
+// returning how many were removed
+func removeMissingFromIndex(repo *repository.Repository, absPath string) (int, error) {
+	pathspec, err := toRepoPath(repo, absPath)
 	if err != nil {
-		return fmt.Errorf("failed to get relative path: %w", err)
+		return 0, err
 	}
 
-	if err := repo.AddToIndex(relPath, hash, info); err != nil {
-		return fmt.Errorf("failed to add to index: %w", err)
+	index, err := repo.GetIndex()
+	if err != nil {
+		return 0, err
 	}
 
-	return nil
+	removed := 0
+	for path := range index.GetEntries() {
+		if !matchesPathspec(filepath.ToSlash(path), pathspec) {
+			continue
+		}
+
+		// A symlink whose target is missing is still there
+		if _, err := os.Lstat(filepath.Join(repo.GetWorkingDirectory(), path)); !os.IsNotExist(err) {
+			continue
+		}
+
+		index.RemoveEntry(path)
+		removed++
+	}
+
+	return removed, nil
 }
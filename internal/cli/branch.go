@@ -1,8 +1,212 @@
 package cli
 
-import "fmt"
+import (
+	"fmt"
+	"minigit/internal/refs"
+	"minigit/internal/repository"
+)
 
 func handleBranch(args []string) error {
-	fmt.Println("Not Implemented")
+	repo, err := findRepository()
+	if err != nil {
+		return err
+	}
+
+	if len(args) == 0 {
+		return listBranches(repo)
+	}
+
+	switch args[0] {
+	case "-d", "--delete":
+		return deleteBranches(repo, args[1:], false)
+	case "-D":
+		return deleteBranches(repo, args[1:], true)
+	case "-m", "--move":
+		return renameBranch(repo, args[1:])
+	}
+
+	if len(args) > 2 {
+		return fmt.Errorf("too many arguments: usage: branch <name> [<start-point>]")
+	}
+
+	startPoint := ""
+	if len(args) == 2 {
+		startPoint = args[1]
+	}
+	return createBranch(repo, args[0], startPoint)
+}
+
+func listBranches(repo *repository.Repository) error {
+	refsMan, err := repo.GetRefsManager()
+	if err != nil {
+		return fmt.Errorf("failed to get refs manager: %w", err)
+	}
+
+	branches, err := refsMan.ListBranches()
+	if err != nil {
+		return fmt.Errorf("failed to list branches: %w", err)
+	}
+
+	current, err := refsMan.CurrentBranch()
+	if err != nil {
+		return fmt.Errorf("failed to get HEAD: %w", err)
+	}
+
+	if current == "" {
+		headCommit, err := refsMan.ResolveHead()
+		if err != nil {
+			return fmt.Errorf("failed to get HEAD: %w", err)
+		}
+		fmt.Printf("* (HEAD detached at %s)\n", shortenHash(headCommit))
+	}
+
+	for _, branch := range branches {
+		if branch == current {
+			fmt.Printf("* %s\n", branch)
+		} else {
+			fmt.Printf("  %s\n", branch)
+		}
+	}
+
+	return nil
+}
+
+func createBranch(repo *repository.Repository, name, startPoint string) error {
+	if !refs.ValidBranchName(name) {
+		return fmt.Errorf("fatal: '%s' is not a valid branch name", name)
+	}
+
+	refsMan, err := repo.GetRefsManager()
+	if err != nil {
+		return fmt.Errorf("failed to get refs manager: %w", err)
+	}
+
+	if refsMan.BranchExists(name) {
+		return fmt.Errorf("fatal: a branch named '%s' already exists", name)
+	}
+
+	commit, err := resolveRevision(repo, startPoint)
+	if err != nil {
+		return err
+	}
+	if startPoint == "" {
+		startPoint = "HEAD"
+	}
+	if commit == "" {
+		return fmt.Errorf("fatal: not a valid object name: '%s'", startPoint)
+	}
+
+	if err := refsMan.UpdateRef("refs/heads/"+name, commit, refs.ZeroHash, "branch: Created from "+startPoint); err != nil {
+		return fmt.Errorf("failed to create branch: %w", err)
+	}
+
+	return nil
+}
+
+func deleteBranches(repo *repository.Repository, names []string, force bool) error {
+	if len(names) == 0 {
+		return fmt.Errorf("fatal: branch name required")
+	}
+
+	refsMan, err := repo.GetRefsManager()
+	if err != nil {
+		return fmt.Errorf("failed to get refs manager: %w", err)
+	}
+
+	store, err := repo.GetObjectStore()
+	if err != nil {
+		return err
+	}
+
+	current, err := refsMan.CurrentBranch()
+	if err != nil {
+		return fmt.Errorf("failed to get HEAD: %w", err)
+	}
+
+	headCommit, err := refsMan.ResolveHead()
+	if err != nil {
+		return fmt.Errorf("failed to get HEAD: %w", err)
+	}
+
+	for _, name := range names {
+		if !refsMan.BranchExists(name) {
+			return fmt.Errorf("error: branch '%s' not found", name)
+		}
+		if name == current {
+			return fmt.Errorf("error: cannot delete branch '%s' checked out at '%s'", name, repo.GetWorkingDirectory())
+		}
+
+		commit, err := refsMan.GetBranch(name)
+		if err != nil {
+			return fmt.Errorf("failed to read branch '%s': %w", name, err)
+		}
+
+		if !force {
+			merged := false
+			if headCommit != "" {
+				if merged, err = isAncestor(store, commit, headCommit); err != nil {
+					return err
+				}
+			}
+			if !merged {
+				return fmt.Errorf("error: the branch '%s' is not fully merged\n"+
+					"If you are sure you want to delete it, run 'mygit branch -D %s'", name, name)
+			}
+		}
+
+		if err := refsMan.DeleteBranch(name); err != nil {
+			return fmt.Errorf("failed to delete branch '%s': %w", name, err)
+		}
+
+		fmt.Printf("Deleted branch %s (was %s).\n", name, shortenHash(commit))
+	}
+
+	return nil
+}
+
+func renameBranch(repo *repository.Repository, args []string) error {
+	refsMan, err := repo.GetRefsManager()
+	if err != nil {
+		return fmt.Errorf("failed to get refs manager: %w", err)
+	}
+
+	current, err := refsMan.CurrentBranch()
+	if err != nil {
+		return fmt.Errorf("failed to get HEAD: %w", err)
+	}
+
+	var oldName, newName string
+	switch len(args) {
+	case 1:
+		// Rename the current branch
+		oldName = current
+		if oldName == "" {
+			return fmt.Errorf("fatal: cannot rename the current branch while not on any")
+		}
+		newName = args[0]
+	case 2:
+		oldName, newName = args[0], args[1]
+	default:
+		return fmt.Errorf("usage: branch -m [<old>] <new>")
+	}
+
+	if !refs.ValidBranchName(newName) {
+		return fmt.Errorf("fatal: '%s' is not a valid branch name", newName)
+	}
+	if refsMan.BranchExists(newName) {
+		return fmt.Errorf("fatal: a branch named '%s' already exists", newName)
+	}
+	if !refsMan.BranchExists(oldName) {
+		if oldName == current {
+			// Unborn branch, only HEAD needs to change
+			return refsMan.SetHead("refs/heads/"+newName, "")
+		}
+		return fmt.Errorf("error: no branch named '%s'", oldName)
+	}
+
+	if err := refsMan.RenameBranch(oldName, newName); err != nil {
+		return fmt.Errorf("failed to rename branch: %w", err)
+	}
+
 	return nil
 }
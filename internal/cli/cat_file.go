@@ -0,0 +1,192 @@
+package cli
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"minigit/internal/objects"
+	"minigit/internal/repository"
+)
+
+func handleCatFile(args []string) error {
+	var mode, expectedType string
+	var names []string
+
+	for _, arg := range args {
+		switch arg {
+		case "-t", "-s", "-p", "-e", "--batch", "--batch-check":
+			if mode != "" {
+				return fmt.Errorf("fatal: options '%s' and '%s' cannot be used together", mode, arg)
+			}
+			mode = arg
+		default:
+			if strings.HasPrefix(arg, "-") {
+				return fmt.Errorf("unknown option: %s", arg)
+			}
+			names = append(names, arg)
+		}
+	}
+
+	repo, err := findRepository()
+	if err != nil {
+		return err
+	}
+	store, err := repo.GetObjectStore()
+	if err != nil {
+		return err
+	}
+
+	if mode == "--batch" || mode == "--batch-check" {
+		if len(names) > 0 {
+			return fmt.Errorf("fatal: %s takes no arguments", mode)
+		}
+		return catFileBatch(repo, store, os.Stdin, mode == "--batch")
+	}
+
+	// "cat-file <type> <object>" prints the content, checking the type
+	if mode == "" {
+		if len(names) != 2 {
+			return fmt.Errorf("usage: cat-file (-t | -s | -e | -p | <type>) <object>")
+		}
+		expectedType, names = names[0], names[1:]
+	}
+	if len(names) != 1 {
+		return fmt.Errorf("usage: cat-file (-t | -s | -e | -p | <type>) <object>")
+	}
+
+	hash, err := resolveObjectName(repo, names[0])
+	if mode == "-e" {
+		// Only the exit status tells whether the object exists
+		if err != nil || !store.HasObject(hash) {
+			return ExitCode(1)
+		}
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("fatal: Not a valid object name %s", names[0])
+	}
+
+	// Content is streamed, so printing a large blob doesn't load it whole
+	header, content, err := store.OpenObject(hash)
+	if err != nil {
+		return fmt.Errorf("fatal: Not a valid object name %s", names[0])
+	}
+	defer content.Close()
+
+	switch mode {
+	case "-t":
+		fmt.Println(header.Type)
+	case "-s":
+		fmt.Println(header.Size)
+	case "-p":
+		return prettyPrintObject(store, hash, header, content)
+	default:
+		if string(header.Type) != expectedType {
+			return fmt.Errorf("fatal: git cat-file %s: bad file", names[0])
+		}
+		_, err := io.Copy(os.Stdout, content)
+		return err
+	}
+
+	return nil
+}
+
+// Resolves a name to an object hash; unlike revisions, an unborn HEAD is an error
+func resolveObjectName(repo *repository.Repository, name string) (string, error) {
+	resolver, err := newResolver(repo)
+	if err != nil {
+		return "", err
+	}
+	return resolver.Resolve(name)
+}
+
+// Resolves a name to an object of the given type; commits stand in for their tree
+func resolveObjectOfType(repo *repository.Repository, store *objects.Store, name string, objType objects.ObjectType) (string, error) {
+	hash, err := resolveObjectName(repo, name)
+	if err != nil {
+		return "", fmt.Errorf("fatal: Not a valid object name %s", name)
+	}
+
+	if objType == objects.TreeObject {
+		if hash, err = peelToTree(store, hash); err == nil {
+			return hash, nil
+		}
+	} else if obj, err := store.LoadObject(hash); err == nil && obj.Type == objType {
+		return hash, nil
+	}
+
+	return "", fmt.Errorf("fatal: %s is not a valid '%s' object", name, objType)
+}
+
+// Prints trees as one "mode type hash\tname" line per entry and every
+// other object as is
+func prettyPrintObject(store *objects.Store, hash string, header objects.ObjectHeader, content io.Reader) error {
+	if header.Type != objects.TreeObject {
+		_, err := io.Copy(os.Stdout, content)
+		return err
+	}
+
+	data, err := io.ReadAll(content)
+	if err != nil {
+		return fmt.Errorf("fatal: corrupt tree %s: %w", hash, err)
+	}
+	tree, err := store.ParseTree(data)
+	if err != nil {
+		return fmt.Errorf("fatal: corrupt tree %s: %w", hash, err)
+	}
+
+	for _, entry := range tree.Entries {
+		fmt.Printf("%06s %s %s\t%s\n", treeEntryMode(entry), entry.Type, entry.Hash, entry.Name)
+	}
+	return nil
+}
+
+// Formats a tree entry's mode padded to six digits, as Git prints it
+func treeEntryMode(entry objects.TreeEntry) string {
+	mode := objects.FormatMode(entry.Mode)
+	if entry.Type == objects.TreeObject {
+		mode = objects.ModeTree
+	}
+	return strings.Repeat("0", 6-len(mode)) + mode
+}
+
+// Answers one object name per input line with "<hash> <type> <size>",
+// followed by the content when printContents is set
+func catFileBatch(repo *repository.Repository, store *objects.Store, input io.Reader, printContents bool) error {
+	scanner := bufio.NewScanner(input)
+	out := bufio.NewWriter(os.Stdout)
+	defer out.Flush()
+
+	for scanner.Scan() {
+		name := strings.TrimSpace(scanner.Text())
+		if name == "" {
+			continue
+		}
+
+		var header objects.ObjectHeader
+		var content io.ReadCloser
+		hash, err := resolveObjectName(repo, name)
+		if err == nil {
+			header, content, err = store.OpenObject(hash)
+		}
+		if err != nil {
+			fmt.Fprintf(out, "%s missing\n", name)
+			continue
+		}
+
+		fmt.Fprintf(out, "%s %s %d\n", hash, header.Type, header.Size)
+		if printContents {
+			_, err = io.Copy(out, content)
+			out.WriteString("\n")
+		}
+		content.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	return scanner.Err()
+}
@@ -0,0 +1,67 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func handleCheckIgnore(args []string) error {
+	verbose := false
+	var paths []string
+
+	for _, arg := range args {
+		switch {
+		case arg == "-v" || arg == "--verbose":
+			verbose = true
+		case strings.HasPrefix(arg, "-"):
+			return fmt.Errorf("unknown option: %s", arg)
+		default:
+			paths = append(paths, arg)
+		}
+	}
+
+	if len(paths) == 0 {
+		return fmt.Errorf("fatal: no path specified")
+	}
+
+	repo, err := findRepository()
+	if err != nil {
+		return err
+	}
+
+	matcher, err := newIgnoreMatcher(repo)
+	if err != nil {
+		return err
+	}
+
+	for _, arg := range paths {
+		relPath, err := toRepoPath(repo, arg)
+		if err != nil {
+			return err
+		}
+
+		isDir := strings.HasSuffix(arg, "/")
+		if info, err := os.Stat(filepath.Join(repo.GetWorkingDirectory(), filepath.FromSlash(relPath))); err == nil {
+			isDir = info.IsDir()
+		}
+
+		pattern, err := matcher.Match(relPath, isDir)
+		if err != nil {
+			return err
+		}
+		if pattern == nil {
+			continue
+		}
+
+		// Negated rules only show up when explaining matches
+		if verbose {
+			fmt.Printf("%s:%d:%s\t%s\n", pattern.Source, pattern.Line, pattern.Text, arg)
+		} else if !pattern.Negated() {
+			fmt.Println(arg)
+		}
+	}
+
+	return nil
+}
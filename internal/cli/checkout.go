@@ -1,8 +1,428 @@
 package cli
 
-import "fmt"
+import (
+	"fmt"
+	"io"
+	"minigit/internal/objects"
+	"minigit/internal/refs"
+	"minigit/internal/repository"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
 
 func handleCheckout(args []string) error {
-	fmt.Println("Not Implemented")
+	var newBranch, target string
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "-":
+			// Short for the previously checked out branch
+			if target != "" {
+				return fmt.Errorf("only one branch or commit may be given")
+			}
+			target = "@{-1}"
+		case arg == "-b":
+			if i+1 >= len(args) {
+				return fmt.Errorf("switch `b' requires a value")
+			}
+			i++
+			newBranch = args[i]
+		case strings.HasPrefix(arg, "-"):
+			return fmt.Errorf("unknown option: %s", arg)
+		default:
+			if target != "" {
+				return fmt.Errorf("only one branch or commit may be given")
+			}
+			target = arg
+		}
+	}
+
+	if newBranch == "" && target == "" {
+		return fmt.Errorf("usage: checkout [-b <new-branch>] <branch|commit>")
+	}
+
+	repo, err := findRepository()
+	if err != nil {
+		return err
+	}
+
+	refsMan, err := repo.GetRefsManager()
+	if err != nil {
+		return fmt.Errorf("failed to get refs manager: %w", err)
+	}
+
+	currentBranch, err := refsMan.CurrentBranch()
+	if err != nil {
+		return fmt.Errorf("failed to get HEAD: %w", err)
+	}
+
+	currentCommit, err := refsMan.ResolveHead()
+	if err != nil {
+		return fmt.Errorf("failed to get HEAD: %w", err)
+	}
+
+	// Where HEAD was, for the reflog
+	from := currentBranch
+	if from == "" {
+		from = currentCommit
+	}
+
+	if newBranch != "" {
+		if !refs.ValidBranchName(newBranch) {
+			return fmt.Errorf("fatal: '%s' is not a valid branch name", newBranch)
+		}
+		if refsMan.BranchExists(newBranch) {
+			return fmt.Errorf("fatal: a branch named '%s' already exists", newBranch)
+		}
+
+		targetCommit, err := resolveRevision(repo, target)
+		if err != nil {
+			return err
+		}
+
+		if err := switchWorkingTree(repo, currentCommit, targetCommit); err != nil {
+			return err
+		}
+
+		// An unborn HEAD has nothing to point the new branch at yet
+		if targetCommit != "" {
+			startPoint := target
+			if startPoint == "" {
+				startPoint = "HEAD"
+			}
+			if err := refsMan.UpdateRef("refs/heads/"+newBranch, targetCommit, refs.ZeroHash, "branch: Created from "+startPoint); err != nil {
+				return fmt.Errorf("failed to create branch: %w", err)
+			}
+		}
+		if err := refsMan.SetHead("refs/heads/"+newBranch, checkoutReason(from, newBranch)); err != nil {
+			return fmt.Errorf("failed to update HEAD: %w", err)
+		}
+
+		fmt.Printf("Switched to a new branch '%s'\n", newBranch)
+		return nil
+	}
+
+	// Going back to a previous branch checks out the branch, not its commit
+	if newBranch == "" {
+		resolver, err := newResolver(repo)
+		if err != nil {
+			return err
+		}
+		if target, err = resolver.ExpandPrevious(target); err != nil {
+			return err
+		}
+	}
+
+	if refsMan.BranchExists(target) {
+		if target == currentBranch {
+			fmt.Printf("Already on '%s'\n", target)
+			return nil
+		}
+
+		targetCommit, err := refsMan.GetBranch(target)
+		if err != nil {
+			return fmt.Errorf("failed to read branch '%s': %w", target, err)
+		}
+
+		if err := switchWorkingTree(repo, currentCommit, targetCommit); err != nil {
+			return err
+		}
+		if err := refsMan.SetHead("refs/heads/"+target, checkoutReason(from, target)); err != nil {
+			return fmt.Errorf("failed to update HEAD: %w", err)
+		}
+
+		fmt.Printf("Switched to branch '%s'\n", target)
+		return nil
+	}
+
+	// Anything else is checked out as a detached HEAD
+	targetCommit, err := resolveRevision(repo, target)
+	if err != nil {
+		return err
+	}
+	if targetCommit == "" {
+		return fmt.Errorf("fatal: reference is not a tree: '%s'", target)
+	}
+
+	store, err := repo.GetObjectStore()
+	if err != nil {
+		return err
+	}
+
+	commit, err := loadCommit(store, targetCommit)
+	if err != nil {
+		return fmt.Errorf("fatal: reference is not a tree: '%s'", target)
+	}
+
+	if err := switchWorkingTree(repo, currentCommit, targetCommit); err != nil {
+		return err
+	}
+	if err := refsMan.SetHead(targetCommit, checkoutReason(from, target)); err != nil {
+		return fmt.Errorf("failed to update HEAD: %w", err)
+	}
+
+	subject, _, _ := strings.Cut(commit.Message, "\n")
+	fmt.Printf("Note: switching to '%s'.\n\n", target)
+	fmt.Println("You are in 'detached HEAD' state. Commits made here will be lost when you")
+	fmt.Println("switch away unless you create a branch for them with `./mygit checkout -b <name>`.")
+	fmt.Println()
+	fmt.Printf("HEAD is now at %s %s\n", shortenHash(targetCommit), subject)
+	return nil
+}
+
+// Describes a checkout in HEAD's reflog, where "checkout -" and @{-n} read it back
+func checkoutReason(from, to string) string {
+	return fmt.Sprintf("checkout: moving from %s to %s", from, to)
+}
+
+// Moves the working tree and index from one commit's snapshot to another's.
+// Paths that differ between the two commits are rewritten; local changes to
+// those paths abort the switch before anything is touched.
+func switchWorkingTree(repo *repository.Repository, fromCommit, toCommit string) error {
+	store, err := repo.GetObjectStore()
+	if err != nil {
+		return err
+	}
+
+	index, err := repo.GetIndex()
+	if err != nil {
+		return fmt.Errorf("failed to get index: %w", err)
+	}
+
+	fromFiles, err := readCommitFiles(store, fromCommit)
+	if err != nil {
+		return fmt.Errorf("failed to read current tree: %w", err)
+	}
+
+	toFiles, err := readCommitFiles(store, toCommit)
+	if err != nil {
+		return fmt.Errorf("failed to read target tree: %w", err)
+	}
+
+	changed := changedPaths(fromFiles, toFiles)
+	workDir := repo.GetWorkingDirectory()
+	staged := index.GetEntries()
+
+	var dirty, untracked []string
+	for _, path := range changed {
+		from, inFrom := fromFiles[path]
+		to, inTo := toFiles[path]
+		entry, inIndex := staged[path]
+
+		// Staged changes survive only if they already match the target
+		stagedChange := inIndex != inFrom || (inIndex && entry.Hash != from.Hash)
+		matchesTarget := inIndex == inTo && (!inIndex || entry.Hash == to.Hash)
+		if stagedChange && !matchesTarget {
+			dirty = append(dirty, path)
+			continue
+		}
+
+		content, err := readWorkingFile(filepath.Join(workDir, path))
+		if err != nil {
+			// Missing files are simply recreated
+			continue
+		}
+
+		workHash := calculateFileHash(content)
+		if inTo && workHash == to.Hash {
+			continue
+		}
+		if inIndex && workHash != entry.Hash {
+			dirty = append(dirty, path)
+		} else if !inIndex {
+			untracked = append(untracked, path)
+		}
+	}
+
+	if len(dirty) > 0 || len(untracked) > 0 {
+		var msg strings.Builder
+		if len(dirty) > 0 {
+			msg.WriteString("error: Your local changes to the following files would be overwritten by checkout:\n")
+			for _, path := range dirty {
+				fmt.Fprintf(&msg, "\t%s\n", path)
+			}
+			msg.WriteString("Please commit your changes or stash them before you switch branches.\n")
+		}
+		if len(untracked) > 0 {
+			msg.WriteString("error: The following untracked working tree files would be overwritten by checkout:\n")
+			for _, path := range untracked {
+				fmt.Fprintf(&msg, "\t%s\n", path)
+			}
+			msg.WriteString("Please move or remove them before you switch branches.\n")
+		}
+		msg.WriteString("Aborting")
+		return fmt.Errorf("%s", msg.String())
+	}
+
+	// Removals go first so that a directory emptied by the switch is gone
+	// before a file takes its place
+	for _, path := range changed {
+		if _, inTo := toFiles[path]; !inTo {
+			if err := removeWorkingFile(workDir, path); err != nil {
+				return err
+			}
+			index.RemoveEntry(path)
+		}
+	}
+
+	for _, path := range changed {
+		to, inTo := toFiles[path]
+		if !inTo {
+			continue
+		}
+
+		if err := writeWorkingFile(store, workDir, to); err != nil {
+			return err
+		}
+
+		info, err := os.Lstat(filepath.Join(workDir, path))
+		if err != nil {
+			return fmt.Errorf("failed to stat %s: %w", path, err)
+		}
+		index.AddEntry(path, to.Hash, info)
+	}
+
+	if err := index.Write(); err != nil {
+		return fmt.Errorf("failed to update index: %w", err)
+	}
+	return nil
+}
+
+// Returns the sorted paths whose content or mode differs between two snapshots
+func changedPaths(fromFiles, toFiles map[string]*objects.IndexEntry) []string {
+	var paths []string
+
+	for path, from := range fromFiles {
+		to, exists := toFiles[path]
+		if !exists || to.Hash != from.Hash || to.Mode != from.Mode {
+			paths = append(paths, path)
+		}
+	}
+	for path := range toFiles {
+		if _, exists := fromFiles[path]; !exists {
+			paths = append(paths, path)
+		}
+	}
+
+	sort.Strings(paths)
+	return paths
+}
+
+// Writes a blob from the object store into the working tree, streaming its
+// content rather than loading it whole
+func writeWorkingFile(store *objects.Store, workDir string, entry *objects.IndexEntry) error {
+	_, blob, err := store.OpenObject(entry.Hash)
+	if err != nil {
+		return fmt.Errorf("failed to load blob for %s: %w", entry.Path, err)
+	}
+	defer blob.Close()
+
+	absPath := filepath.Join(workDir, filepath.FromSlash(entry.Path))
+	// 0755 ~~ rwxr-xr-x
+	if err := os.MkdirAll(filepath.Dir(absPath), 0755); err != nil {
+		return fmt.Errorf("failed to create directory for %s: %w", entry.Path, err)
+	}
+
+	// Writing through an existing symbolic link would change its target instead
+	if info, err := os.Lstat(absPath); err == nil && info.Mode()&os.ModeSymlink != 0 {
+		if err := os.Remove(absPath); err != nil {
+			return fmt.Errorf("failed to remove %s: %w", entry.Path, err)
+		}
+	}
+
+	if entry.Mode&os.ModeSymlink != 0 {
+		if err := os.Remove(absPath); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove %s: %w", entry.Path, err)
+		}
+		target, err := io.ReadAll(blob)
+		if err != nil {
+			return fmt.Errorf("failed to load blob for %s: %w", entry.Path, err)
+		}
+		if err := os.Symlink(filepath.FromSlash(string(target)), absPath); err != nil {
+			return fmt.Errorf("failed to create symlink %s: %w", entry.Path, err)
+		}
+		return nil
+	}
+
+	perm := entry.Mode.Perm()
+	if perm == 0 {
+		perm = 0644
+	}
+
+	file, err := os.OpenFile(absPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", entry.Path, err)
+	}
+	if _, err := io.Copy(file, blob); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write %s: %w", entry.Path, err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", entry.Path, err)
+	}
+	// Existing files keep their permissions when opened
+	return os.Chmod(absPath, perm)
+}
+
+// Reads a working tree file as it is stored in a blob. Symbolic links are
+// recorded by their target rather than followed.
+func readWorkingFile(absPath string) ([]byte, error) {
+	info, err := os.Lstat(absPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if info.Mode()&os.ModeSymlink != 0 {
+		target, err := os.Readlink(absPath)
+		if err != nil {
+			return nil, err
+		}
+		return []byte(filepath.ToSlash(target)), nil
+	}
+
+	return os.ReadFile(absPath)
+}
+
+// Stores a working tree file as a blob. Regular files are streamed into the
+// object store so large ones are never held in memory whole.
+func storeWorkingFile(store *objects.Store, absPath string, info os.FileInfo) (string, error) {
+	if info.Mode()&os.ModeSymlink != 0 {
+		target, err := readWorkingFile(absPath)
+		if err != nil {
+			return "", err
+		}
+		return store.StoreObject(objects.BlobObject, target)
+	}
+
+	file, err := os.Open(absPath)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	// The size of the file as opened, in case it changed since it was found
+	stat, err := file.Stat()
+	if err != nil {
+		return "", err
+	}
+	return store.StoreObjectFromReader(objects.BlobObject, stat.Size(), file)
+}
+
+// Deletes a file from the working tree along with any directories it leaves empty
+func removeWorkingFile(workDir, path string) error {
+	absPath := filepath.Join(workDir, filepath.FromSlash(path))
+	if err := os.Remove(absPath); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove %s: %w", path, err)
+	}
+
+	for dir := filepath.Dir(absPath); dir != workDir && strings.HasPrefix(dir, workDir); dir = filepath.Dir(dir) {
+		if err := os.Remove(dir); err != nil {
+			break
+		}
+	}
+
 	return nil
 }
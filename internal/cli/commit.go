@@ -4,7 +4,12 @@ import (
 	"fmt"
 	"minigit/internal/diff"
 	"minigit/internal/objects"
+	"minigit/internal/refs"
+	"minigit/internal/repository"
+	"os"
+	"os/user"
 	"strings"
+	"time"
 )
 
 func handleCommit(args []string) error {
@@ -31,16 +36,22 @@ func handleCommit(args []string) error {
 		return err
 	}
 
-	index, err := repo.GetIndex()
+	idx, err := repo.GetIndex()
 	if err != nil {
 		return fmt.Errorf("failed to get index: %w", err)
 	}
 
-	entries := index.GetEntries()
-	if len(entries) == 0 {
-		return fmt.Errorf("no changes added to commit (use \"mygit add\")")
+	if idx.HasConflicts() {
+		return fmt.Errorf("error: Committing is not possible because you have unmerged files.\nfatal: Exiting because of an unresolved conflict.")
 	}
 
+	mergeHead, err := readMergeHead(repo)
+	if err != nil {
+		return err
+	}
+
+	entries := idx.GetEntries()
+
 	store, err := repo.GetObjectStore()
 	if err != nil {
 		return err
@@ -93,11 +104,11 @@ func handleCommit(args []string) error {
 		}
 	}
 
-	// If the tree hasn't changed, don't create a new commit
-	if lastCommitTreeHash == newTreeHash {
-		fmt.Println("On branch main")
-		fmt.Println("nothing to commit, working tree clean")
-		return nil
+	// If the tree hasn't changed, don't create a new commit (a merge may keep
+	// the tree as is). Removing every file records the empty tree, but a
+	// first commit needs something in it.
+	if (lastCommitTreeHash == newTreeHash || (lastCommitTreeHash == "" && len(entries) == 0)) && mergeHead == "" {
+		return fmt.Errorf("no changes added to commit (use \"mygit add\")")
 	}
 
 	var parents []string
@@ -113,37 +124,37 @@ func handleCommit(args []string) error {
 		// If branch doesn't exist yet, parents will be empty (first commit)
 	}
 
-	commitHash, err := store.CreateCommit(newTreeHash, parents, "", message)
+	// Concluding a merge records the merged commit as the second parent
+	if mergeHead != "" {
+		parents = append(parents, mergeHead)
+	}
+
+	commitHash, err := createCommit(repo, store, newTreeHash, parents, message)
 	if err != nil {
 		return fmt.Errorf("failed to create commit: %w", err)
 	}
 
-	// Update current branch
-	headRef, err = refsMan.GetHead()
-	if err != nil {
-		return fmt.Errorf("failed to get HEAD: %w", err)
+	// Update the current branch, or HEAD itself when detached, unless another
+	// process moved it since the parents were read
+	expectedHead := refs.ZeroHash
+	if len(parents) > 0 && parents[0] != mergeHead {
+		expectedHead = parents[0]
+	}
+	if err := refsMan.UpdateHead(commitHash, expectedHead, commitReason(parents, message)); err != nil {
+		return fmt.Errorf("failed to update HEAD: %w", err)
 	}
 
-	if strings.HasPrefix(headRef, "refs/heads/") {
-		// Update branch
-		branchName := strings.TrimPrefix(headRef, "refs/heads/")
-		if err := refsMan.SetBranch(branchName, commitHash); err != nil {
-			return fmt.Errorf("failed to update branch: %w", err)
-		}
-	} else {
-		// Update HEAD directly
-		if err := refsMan.SetHead(commitHash); err != nil {
-			return fmt.Errorf("failed to update HEAD: %w", err)
-		}
+	if err := clearMergeState(repo); err != nil {
+		return err
 	}
 
-	// Clear the idx after successful commit
-	if err := index.Clear(); err != nil {
-		return fmt.Errorf("failed to clear index: %w", err)
+	// Summarise what this commit changed relative to its parent
+	lastCommitFiles, err := store.ReadTreeEntries(lastCommitTreeHash)
+	if err != nil {
+		return fmt.Errorf("failed to read previous tree: %w", err)
 	}
 
-	// Calculate diff statistics
-	stats, err := diff.CalculateDiffStats(repo, entries, lastCommitTreeHash)
+	stats, err := diff.CalculateDiffStats(store, lastCommitFiles, indexEntries)
 	if err != nil {
 		return fmt.Errorf("failed to calculate diff stats: %w", err)
 	}
@@ -157,7 +168,7 @@ func handleCommit(args []string) error {
 	fmt.Printf("[%s] %s\n", shortHash, message)
 
 	// Print diff statistics
-	filesChanged := len(entries)
+	filesChanged := stats.FilesChanged
 	if filesChanged == 1 {
 		fmt.Printf(" %d file changed", filesChanged)
 	} else {
@@ -165,20 +176,111 @@ func handleCommit(args []string) error {
 	}
 
 	if stats.Insertions > 0 {
-		fmt.Printf(", %d insertion(+)", stats.Insertions)
-		if stats.Insertions > 1 {
-			fmt.Print("s")
-		}
+		fmt.Printf(", %d insertion%s(+)", stats.Insertions, plural(stats.Insertions))
 	}
 
 	if stats.Deletions > 0 {
-		fmt.Printf(", %d deletion(-)", stats.Deletions)
-		if stats.Deletions > 1 {
-			fmt.Print("s")
-		}
+		fmt.Printf(", %d deletion%s(-)", stats.Deletions, plural(stats.Deletions))
 	}
 
 	fmt.Println()
 
 	return nil
 }
+
+// Writes a commit of tree with the author and committer taken from the
+// environment and configuration
+func createCommit(repo *repository.Repository, store *objects.Store, tree string, parents []string, message string) (string, error) {
+	author, err := signature(repo, "AUTHOR")
+	if err != nil {
+		return "", err
+	}
+	committer, err := signature(repo, "COMMITTER")
+	if err != nil {
+		return "", err
+	}
+
+	return store.WriteCommit(&objects.Commit{
+		Tree:      tree,
+		Parents:   parents,
+		Author:    author,
+		Committer: committer,
+		Message:   strings.TrimRight(message, "\n"),
+	})
+}
+
+// Describes a new commit in the reflog as Git does: "commit: <subject>",
+// with "(initial)" or "(merge)" for root and merge commits
+func commitReason(parents []string, message string) string {
+	subject, _, _ := strings.Cut(message, "\n")
+	switch {
+	case len(parents) == 0:
+		return "commit (initial): " + subject
+	case len(parents) > 1:
+		return "commit (merge): " + subject
+	}
+	return "commit: " + subject
+}
+
+// Returns the identity ref updates are logged for; unlike commits, reflogs
+// do without a configured one
+func reflogIdentity(repo *repository.Repository) objects.Signature {
+	committer, err := signature(repo, "COMMITTER")
+	if err != nil {
+		return objects.Signature{Name: "unknown", Email: "unknown", When: time.Now()}
+	}
+	return committer
+}
+
+// Resolves the author or committer signature. MINIGIT_<ROLE>_NAME, _EMAIL and
+// _DATE override user.name, user.email and the current time; without either,
+// the login name and host are used like Git does.
+func signature(repo *repository.Repository, role string) (objects.Signature, error) {
+	cfg, err := repo.GetConfig()
+	if err != nil {
+		return objects.Signature{}, err
+	}
+
+	name := os.Getenv("MINIGIT_" + role + "_NAME")
+	if name == "" {
+		name = cfg.GetString("user.name", "")
+	}
+	email := os.Getenv("MINIGIT_" + role + "_EMAIL")
+	if email == "" {
+		email = cfg.GetString("user.email", "")
+	}
+
+	if name == "" || email == "" {
+		if current, err := user.Current(); err == nil {
+			if name == "" {
+				name = current.Name
+			}
+			if name == "" {
+				name = current.Username
+			}
+			if email == "" {
+				if host, err := os.Hostname(); err == nil && current.Username != "" && host != "" {
+					email = current.Username + "@" + host
+				}
+			}
+		}
+	}
+
+	if name == "" || email == "" {
+		kind := strings.ToUpper(role[:1]) + strings.ToLower(role[1:])
+		return objects.Signature{}, fmt.Errorf("%s identity unknown\n\n*** Please tell me who you are.\n\nRun\n\n"+
+			"  ./mygit config --global user.email \"you@example.com\"\n"+
+			"  ./mygit config --global user.name \"Your Name\"\n\n"+
+			"to set your account's default identity.", kind)
+	}
+
+	when := time.Now()
+	if date := os.Getenv("MINIGIT_" + role + "_DATE"); date != "" {
+		when, err = objects.ParseDate(date)
+		if err != nil {
+			return objects.Signature{}, fmt.Errorf("fatal: %w", err)
+		}
+	}
+
+	return objects.Signature{Name: name, Email: email, When: when}, nil
+}
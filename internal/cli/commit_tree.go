@@ -0,0 +1,108 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"minigit/internal/objects"
+)
+
+func handleCommitTree(args []string) error {
+	var tree string
+	var parents, messages []string
+	haveMessage := false
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch arg {
+		case "-p", "-m", "-F":
+			if i+1 >= len(args) {
+				return fmt.Errorf("switch `%s' requires a value", strings.TrimPrefix(arg, "-"))
+			}
+			i++
+			switch arg {
+			case "-p":
+				parents = append(parents, args[i])
+			case "-m":
+				messages = append(messages, args[i])
+			case "-F":
+				content, err := readMessageFile(args[i])
+				if err != nil {
+					return err
+				}
+				messages = append(messages, content)
+			}
+			haveMessage = haveMessage || arg != "-p"
+		default:
+			if strings.HasPrefix(arg, "-") {
+				return fmt.Errorf("unknown option: %s", arg)
+			}
+			if tree != "" {
+				return fmt.Errorf("usage: commit-tree <tree> [-p <parent>]... [-m <message>]...")
+			}
+			tree = arg
+		}
+	}
+
+	if tree == "" {
+		return fmt.Errorf("usage: commit-tree <tree> [-p <parent>]... [-m <message>]...")
+	}
+
+	repo, err := findRepository()
+	if err != nil {
+		return err
+	}
+	store, err := repo.GetObjectStore()
+	if err != nil {
+		return err
+	}
+
+	treeHash, err := resolveObjectOfType(repo, store, tree, objects.TreeObject)
+	if err != nil {
+		return err
+	}
+
+	var parentHashes []string
+	for _, parent := range parents {
+		hash, err := resolveObjectOfType(repo, store, parent, objects.CommitObject)
+		if err != nil {
+			return err
+		}
+		parentHashes = append(parentHashes, hash)
+	}
+
+	// Without -m or -F the message comes from stdin
+	message := strings.Join(messages, "\n\n")
+	if !haveMessage {
+		content, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return fmt.Errorf("failed to read message: %w", err)
+		}
+		message = string(content)
+	}
+
+	hash, err := createCommit(repo, store, treeHash, parentHashes, message)
+	if err != nil {
+		return fmt.Errorf("failed to create commit: %w", err)
+	}
+
+	fmt.Println(hash)
+	return nil
+}
+
+// Reads a message from a file, or from stdin for "-"
+func readMessageFile(path string) (string, error) {
+	var content []byte
+	var err error
+	if path == "-" {
+		content, err = io.ReadAll(os.Stdin)
+	} else {
+		content, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return "", fmt.Errorf("fatal: could not read log file '%s': %w", path, err)
+	}
+	return string(content), nil
+}
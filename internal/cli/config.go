@@ -0,0 +1,127 @@
+package cli
+
+import (
+	"fmt"
+	"minigit/internal/config"
+	"strings"
+)
+
+type configAction int
+
+const (
+	configGet configAction = iota
+	configSet
+	configUnset
+	configList
+)
+
+func handleConfig(args []string) error {
+	action := configGet
+	actionSet := false
+	scope, scopeSet := config.ScopeLocal, false
+	var positional []string
+
+	setAction := func(next configAction, flag string) error {
+		if actionSet && action != next {
+			return fmt.Errorf("error: only one action at a time (%s)", flag)
+		}
+		action, actionSet = next, true
+		return nil
+	}
+
+	for _, arg := range args {
+		var err error
+		switch arg {
+		case "--global":
+			scope, scopeSet = config.ScopeGlobal, true
+		case "--local":
+			scope, scopeSet = config.ScopeLocal, true
+		case "--get":
+			err = setAction(configGet, arg)
+		case "--set":
+			err = setAction(configSet, arg)
+		case "--unset":
+			err = setAction(configUnset, arg)
+		case "--list", "-l":
+			err = setAction(configList, arg)
+		default:
+			if strings.HasPrefix(arg, "-") {
+				return fmt.Errorf("unknown option: %s", arg)
+			}
+			positional = append(positional, arg)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	// Without an explicit action, a value argument means set
+	if !actionSet && len(positional) == 2 {
+		action = configSet
+	}
+
+	wantArgs := map[configAction]int{configGet: 1, configSet: 2, configUnset: 1, configList: 0}[action]
+	if len(positional) != wantArgs {
+		return fmt.Errorf("usage: config [--global | --local] [--get] <name>\n" +
+			"   or: config [--global | --local] [--set] <name> <value>\n" +
+			"   or: config [--global | --local] --unset <name>\n" +
+			"   or: config [--global | --local] --list")
+	}
+
+	// User-level settings can be read and written outside a repository
+	writes := action == configSet || action == configUnset
+	cfg, err := loadConfig(scope == config.ScopeLocal && (scopeSet || writes))
+	if err != nil {
+		return err
+	}
+
+	switch action {
+	case configGet:
+		value, found := cfg.Get(positional[0])
+		if scopeSet {
+			value, found = cfg.GetScoped(scope, positional[0])
+		}
+		if !found {
+			return fmt.Errorf("error: key '%s' is not set", positional[0])
+		}
+		fmt.Println(value)
+
+	case configSet:
+		return cfg.Set(scope, positional[0], positional[1])
+
+	case configUnset:
+		removed, err := cfg.Unset(scope, positional[0])
+		if err != nil {
+			return err
+		}
+		if !removed {
+			return fmt.Errorf("error: key '%s' is not set", positional[0])
+		}
+
+	case configList:
+		var entries []config.Entry
+		if scopeSet {
+			entries = cfg.Entries(scope)
+		} else {
+			entries = cfg.Entries()
+		}
+		for _, entry := range entries {
+			fmt.Printf("%s=%s\n", entry.Key, entry.Value)
+		}
+	}
+
+	return nil
+}
+
+// Returns the repository's config, or just the user-level config when
+// outside a repository and the repository file is not required
+func loadConfig(needRepository bool) (*config.Config, error) {
+	repo, err := findRepository()
+	if err == nil {
+		return repo.GetConfig()
+	}
+	if needRepository {
+		return nil, err
+	}
+	return config.NewConfig("")
+}
@@ -0,0 +1,492 @@
+package cli
+
+import (
+	"fmt"
+	"minigit/internal/diff"
+	"minigit/internal/objects"
+	"minigit/internal/repository"
+	"minigit/internal/revparse"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+type diffFormat int
+
+const (
+	diffPatch diffFormat = iota
+	diffStat
+	diffNameOnly
+	diffNameStatus
+)
+
+type diffOptions struct {
+	staged    bool
+	format    diffFormat
+	context   int
+	revisions []string
+	pathspecs []string
+	dashDash  bool // pathspecs were separated from revisions by "--"
+}
+
+// One side of a comparison: a snapshot of files, read from the working tree when workDir is set
+type diffSide struct {
+	files   map[string]*objects.IndexEntry
+	workDir string
+}
+
+func handleDiff(args []string) error {
+	opts, err := parseDiffArgs(args)
+	if err != nil {
+		return err
+	}
+
+	repo, err := findRepository()
+	if err != nil {
+		return err
+	}
+
+	if !opts.dashDash {
+		separatePathspecs(repo, opts)
+	}
+	if len(opts.revisions) > 2 {
+		return fmt.Errorf("usage: diff [--staged] [<commit> [<commit>]] [-- <path>...]")
+	}
+	if opts.staged && len(opts.revisions) > 1 {
+		return fmt.Errorf("--staged takes at most one commit")
+	}
+
+	store, err := repo.GetObjectStore()
+	if err != nil {
+		return err
+	}
+
+	oldSide, newSide, err := diffSides(repo, opts)
+	if err != nil {
+		return err
+	}
+
+	var pathspecs []string
+	for _, arg := range opts.pathspecs {
+		pathspec, err := toRepoPath(repo, arg)
+		if err != nil {
+			return err
+		}
+		pathspecs = append(pathspecs, pathspec)
+	}
+
+	var paths []string
+	for _, path := range changedPaths(oldSide.files, newSide.files) {
+		if len(pathspecs) == 0 || matchesAnyPathspec(path, pathspecs) {
+			paths = append(paths, path)
+		}
+	}
+
+	switch opts.format {
+	case diffNameOnly:
+		for _, path := range paths {
+			fmt.Println(path)
+		}
+		return nil
+	case diffNameStatus:
+		for _, path := range paths {
+			fmt.Printf("%s\t%s\n", changeStatus(oldSide.files[path], newSide.files[path]), path)
+		}
+		return nil
+	case diffStat:
+		return printDiffStat(store, oldSide, newSide, paths)
+	}
+
+	for _, path := range paths {
+		if err := printFilePatch(store, oldSide, newSide, path, opts.context); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func parseDiffArgs(args []string) (*diffOptions, error) {
+	opts := &diffOptions{context: 3}
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "--staged" || arg == "--cached":
+			opts.staged = true
+		case arg == "--stat":
+			opts.format = diffStat
+		case arg == "--name-only":
+			opts.format = diffNameOnly
+		case arg == "--name-status":
+			opts.format = diffNameStatus
+		case strings.HasPrefix(arg, "--unified="):
+			context, err := strconv.Atoi(strings.TrimPrefix(arg, "--unified="))
+			if err != nil || context < 0 {
+				return nil, fmt.Errorf("invalid context length: %s", arg)
+			}
+			opts.context = context
+		case strings.HasPrefix(arg, "-U"):
+			context, err := strconv.Atoi(strings.TrimPrefix(arg, "-U"))
+			if err != nil || context < 0 {
+				return nil, fmt.Errorf("invalid context length: %s", arg)
+			}
+			opts.context = context
+		case arg == "--":
+			opts.pathspecs = append(opts.pathspecs, args[i+1:]...)
+			opts.dashDash = true
+			i = len(args)
+		case strings.HasPrefix(arg, "-"):
+			return nil, fmt.Errorf("unknown option: %s", arg)
+		default:
+			opts.revisions = append(opts.revisions, arg)
+		}
+	}
+
+	return opts, nil
+}
+
+// Without "--", arguments are revisions up to the first one that names no
+// revision but a file in the working tree; like Git, that one and those
+// after it are pathspecs
+func separatePathspecs(repo *repository.Repository, opts *diffOptions) {
+	for i, arg := range opts.revisions {
+		if strings.Contains(arg, "..") {
+			continue
+		}
+		if _, err := resolveRevision(repo, arg); err == nil {
+			continue
+		}
+		if _, err := os.Lstat(arg); err != nil {
+			continue
+		}
+
+		opts.revisions, opts.pathspecs = opts.revisions[:i], opts.revisions[i:]
+		return
+	}
+}
+
+// Picks the two snapshots to compare based on the options
+func diffSides(repo *repository.Repository, opts *diffOptions) (*diffSide, *diffSide, error) {
+	store, err := repo.GetObjectStore()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	commitFiles := func(rev string) (map[string]*objects.IndexEntry, error) {
+		hash, err := resolveRevision(repo, rev)
+		if err != nil {
+			return nil, err
+		}
+		return readCommitFiles(store, hash)
+	}
+
+	indexFiles, err := indexSnapshot(repo)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read index: %w", err)
+	}
+
+	switch {
+	case len(opts.revisions) == 1 && strings.Contains(opts.revisions[0], ".."):
+		// "A..B" compares A with B, "A...B" their merge base with B
+		from, to, symmetric, _ := revparse.SplitRange(opts.revisions[0])
+		if opts.staged {
+			return nil, nil, fmt.Errorf("--staged does not take a range")
+		}
+		fromHash, err := resolveRevision(repo, from)
+		if err != nil {
+			return nil, nil, err
+		}
+		toHash, err := resolveRevision(repo, to)
+		if err != nil {
+			return nil, nil, err
+		}
+		if symmetric && fromHash != "" && toHash != "" {
+			if fromHash, err = mergeBase(store, fromHash, toHash); err != nil {
+				return nil, nil, err
+			}
+			if fromHash == "" {
+				return nil, nil, fmt.Errorf("fatal: %s: no merge base", opts.revisions[0])
+			}
+		}
+		oldFiles, err := readCommitFiles(store, fromHash)
+		if err != nil {
+			return nil, nil, err
+		}
+		newFiles, err := readCommitFiles(store, toHash)
+		if err != nil {
+			return nil, nil, err
+		}
+		return &diffSide{files: oldFiles}, &diffSide{files: newFiles}, nil
+
+	case len(opts.revisions) == 2:
+		oldFiles, err := commitFiles(opts.revisions[0])
+		if err != nil {
+			return nil, nil, err
+		}
+		newFiles, err := commitFiles(opts.revisions[1])
+		if err != nil {
+			return nil, nil, err
+		}
+		return &diffSide{files: oldFiles}, &diffSide{files: newFiles}, nil
+
+	case opts.staged:
+		// Index against HEAD, or against the given commit
+		rev := ""
+		if len(opts.revisions) == 1 {
+			rev = opts.revisions[0]
+		}
+		oldFiles, err := commitFiles(rev)
+		if err != nil {
+			return nil, nil, err
+		}
+		return &diffSide{files: oldFiles}, &diffSide{files: indexFiles}, nil
+
+	case len(opts.revisions) == 1:
+		// A commit against the working tree
+		oldFiles, err := commitFiles(opts.revisions[0])
+		if err != nil {
+			return nil, nil, err
+		}
+		tracked := make(map[string]bool)
+		for path := range oldFiles {
+			tracked[path] = true
+		}
+		for path := range indexFiles {
+			tracked[path] = true
+		}
+		workFiles, err := workingTreeFiles(repo, tracked)
+		if err != nil {
+			return nil, nil, err
+		}
+		return &diffSide{files: oldFiles}, &diffSide{files: workFiles, workDir: repo.GetWorkingDirectory()}, nil
+
+	default:
+		// The index against the working tree
+		tracked := make(map[string]bool)
+		for path := range indexFiles {
+			tracked[path] = true
+		}
+		workFiles, err := workingTreeFiles(repo, tracked)
+		if err != nil {
+			return nil, nil, err
+		}
+		return &diffSide{files: indexFiles}, &diffSide{files: workFiles, workDir: repo.GetWorkingDirectory()}, nil
+	}
+}
+
+// Hashes the given tracked paths as they currently are on disk, skipping
+// missing files and trusting the index's stat data for unchanged ones
+func workingTreeFiles(repo *repository.Repository, paths map[string]bool) (map[string]*objects.IndexEntry, error) {
+	idx, err := repo.GetIndex()
+	if err != nil {
+		return nil, err
+	}
+
+	workDir := repo.GetWorkingDirectory()
+	var present []workingFile
+	for path := range paths {
+		absPath := filepath.Join(workDir, filepath.FromSlash(path))
+		info, err := os.Lstat(absPath)
+		if err != nil || info.IsDir() {
+			continue
+		}
+		present = append(present, workingFile{absPath: absPath, relPath: filepath.FromSlash(path), info: info})
+	}
+
+	hashes, err := hashWorkingFiles(idx, present)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read working tree: %w", err)
+	}
+
+	files := make(map[string]*objects.IndexEntry)
+	for i, file := range present {
+		path := filepath.ToSlash(file.relPath)
+		entry, _ := idx.GetEntry(file.relPath)
+		files[path] = &objects.IndexEntry{
+			Path: path,
+			Hash: hashes[i],
+			Mode: idx.FileMode(entry, file.info),
+		}
+	}
+
+	return files, nil
+}
+
+func (side *diffSide) content(store *objects.Store, path string) ([]byte, error) {
+	entry, exists := side.files[path]
+	if !exists {
+		return nil, nil
+	}
+
+	if side.workDir != "" {
+		return readWorkingFile(filepath.Join(side.workDir, filepath.FromSlash(path)))
+	}
+
+	blob, err := store.LoadObject(entry.Hash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load blob for %s: %w", path, err)
+	}
+	return blob.Content, nil
+}
+
+func printFilePatch(store *objects.Store, oldSide, newSide *diffSide, path string, context int) error {
+	oldEntry, inOld := oldSide.files[path]
+	newEntry, inNew := newSide.files[path]
+
+	oldContent, err := oldSide.content(store, path)
+	if err != nil {
+		return err
+	}
+	newContent, err := newSide.content(store, path)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("diff --git a/%s b/%s\n", path, path)
+
+	zeroHash := strings.Repeat("0", 7)
+	switch {
+	case !inOld:
+		fmt.Printf("new file mode %s\n", gitFileMode(newEntry.Mode))
+		fmt.Printf("index %s..%s\n", zeroHash, shortenHash(newEntry.Hash))
+	case !inNew:
+		fmt.Printf("deleted file mode %s\n", gitFileMode(oldEntry.Mode))
+		fmt.Printf("index %s..%s\n", shortenHash(oldEntry.Hash), zeroHash)
+	case oldEntry.Mode != newEntry.Mode:
+		fmt.Printf("old mode %s\n", gitFileMode(oldEntry.Mode))
+		fmt.Printf("new mode %s\n", gitFileMode(newEntry.Mode))
+		if oldEntry.Hash != newEntry.Hash {
+			fmt.Printf("index %s..%s\n", shortenHash(oldEntry.Hash), shortenHash(newEntry.Hash))
+		}
+	default:
+		fmt.Printf("index %s..%s %s\n", shortenHash(oldEntry.Hash), shortenHash(newEntry.Hash), gitFileMode(newEntry.Mode))
+	}
+
+	if inOld && inNew && oldEntry.Hash == newEntry.Hash {
+		return nil // mode change only
+	}
+
+	oldName, newName := "a/"+path, "b/"+path
+	if !inOld {
+		oldName = "/dev/null"
+	}
+	if !inNew {
+		newName = "/dev/null"
+	}
+
+	if diff.IsBinary(oldContent) || diff.IsBinary(newContent) {
+		fmt.Printf("Binary files %s and %s differ\n", oldName, newName)
+		return nil
+	}
+
+	fmt.Printf("--- %s\n", oldName)
+	fmt.Printf("+++ %s\n", newName)
+	fmt.Print(diff.Unified(oldContent, newContent, context))
+
+	return nil
+}
+
+func printDiffStat(store *objects.Store, oldSide, newSide *diffSide, paths []string) error {
+	type fileStat struct {
+		path       string
+		insertions int
+		deletions  int
+		binary     bool
+	}
+
+	var stats []fileStat
+	totalInsertions, totalDeletions := 0, 0
+	nameWidth, maxChanges := 0, 0
+
+	for _, path := range paths {
+		oldContent, err := oldSide.content(store, path)
+		if err != nil {
+			return err
+		}
+		newContent, err := newSide.content(store, path)
+		if err != nil {
+			return err
+		}
+
+		stat := fileStat{path: path}
+		if diff.IsBinary(oldContent) || diff.IsBinary(newContent) {
+			stat.binary = true
+		} else {
+			stat.insertions, stat.deletions = diff.CountChanges(oldContent, newContent)
+		}
+
+		totalInsertions += stat.insertions
+		totalDeletions += stat.deletions
+		nameWidth = max(nameWidth, len(path))
+		maxChanges = max(maxChanges, stat.insertions+stat.deletions)
+		stats = append(stats, stat)
+	}
+
+	if len(stats) == 0 {
+		return nil
+	}
+
+	// Scale the +/- graph down when the largest change would not fit
+	const graphWidth = 50
+	countWidth := len(strconv.Itoa(maxChanges))
+	scale := func(n int) int {
+		if maxChanges <= graphWidth || n == 0 {
+			return n
+		}
+		return max(n*graphWidth/maxChanges, 1)
+	}
+
+	for _, stat := range stats {
+		if stat.binary {
+			fmt.Printf(" %-*s | %*s\n", nameWidth, stat.path, countWidth, "Bin")
+			continue
+		}
+		graph := strings.Repeat("+", scale(stat.insertions)) + strings.Repeat("-", scale(stat.deletions))
+		fmt.Printf(" %-*s | %*d %s\n", nameWidth, stat.path, countWidth, stat.insertions+stat.deletions, graph)
+	}
+
+	summary := fmt.Sprintf(" %d file%s changed", len(stats), plural(len(stats)))
+	if totalInsertions > 0 {
+		summary += fmt.Sprintf(", %d insertion%s(+)", totalInsertions, plural(totalInsertions))
+	}
+	if totalDeletions > 0 {
+		summary += fmt.Sprintf(", %d deletion%s(-)", totalDeletions, plural(totalDeletions))
+	}
+	fmt.Println(summary)
+
+	return nil
+}
+
+func changeStatus(oldEntry, newEntry *objects.IndexEntry) string {
+	switch {
+	case oldEntry == nil:
+		return "A"
+	case newEntry == nil:
+		return "D"
+	default:
+		return "M"
+	}
+}
+
+// Formats a file mode the way Git writes it in diffs and trees
+func gitFileMode(mode os.FileMode) string {
+	return objects.FormatMode(mode)
+}
+
+func matchesAnyPathspec(path string, pathspecs []string) bool {
+	for _, pathspec := range pathspecs {
+		if matchesPathspec(path, pathspec) {
+			return true
+		}
+	}
+	return false
+}
+
+func plural(n int) string {
+	if n == 1 {
+		return ""
+	}
+	return "s"
+}
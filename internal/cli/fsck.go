@@ -0,0 +1,361 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"minigit/internal/objects"
+	"minigit/internal/refs"
+	"minigit/internal/repository"
+)
+
+type fsckOptions struct {
+	full        bool // also verify packs and every packed object
+	unreachable bool // list all unreachable objects, not only dangling ones
+	lostFound   bool // save dangling objects in .minigit/lost-found
+}
+
+// An object named by another object, a ref or the index
+type fsckLink struct {
+	hash    string
+	objType objects.ObjectType
+}
+
+type fsckChecker struct {
+	store  *objects.Store
+	types  map[string]objects.ObjectType // objects read so far
+	links  map[string][]fsckLink         // what each object read so far points to
+	failed bool
+}
+
+func handleFsck(args []string) error {
+	opts := &fsckOptions{}
+
+	for _, arg := range args {
+		switch arg {
+		case "--full":
+			opts.full = true
+		case "--unreachable":
+			opts.unreachable = true
+		case "--lost-found":
+			opts.lostFound = true
+		default:
+			return fmt.Errorf("unknown option: %s", arg)
+		}
+	}
+
+	repo, err := findRepository()
+	if err != nil {
+		return err
+	}
+	store, err := repo.GetObjectStore()
+	if err != nil {
+		return err
+	}
+
+	checker := &fsckChecker{
+		store: store,
+		types: make(map[string]objects.ObjectType),
+		links: make(map[string][]fsckLink),
+	}
+
+	// Loose objects are always re-hashed; packed ones only with --full
+	toVerify, err := store.LooseObjects()
+	if err != nil {
+		return err
+	}
+	allObjects, err := store.ListObjects()
+	if err != nil {
+		return err
+	}
+	if opts.full {
+		packs, err := store.PackNames()
+		if err != nil {
+			return err
+		}
+		for _, pack := range packs {
+			if err := store.VerifyPack(pack); err != nil {
+				checker.errorf("error: %v", err)
+			}
+		}
+		toVerify = allObjects
+	}
+	for _, hash := range toVerify {
+		checker.verify(hash)
+	}
+
+	roots, err := fsckRoots(repo, checker)
+	if err != nil {
+		return err
+	}
+	reachable := checker.connect(roots)
+
+	// Unreachable objects that no other object points to are dangling
+	var unreachable []string
+	referenced := make(map[string]bool)
+	for _, hash := range allObjects {
+		if reachable[hash] {
+			continue
+		}
+		unreachable = append(unreachable, hash)
+		checker.read(hash)
+		for _, link := range checker.links[hash] {
+			referenced[link.hash] = true
+		}
+	}
+
+	for _, hash := range unreachable {
+		objType := checker.types[hash]
+		if objType == "" {
+			continue
+		}
+		dangling := !referenced[hash]
+
+		if opts.unreachable {
+			fmt.Printf("unreachable %s %s\n", objType, hash)
+		} else if dangling {
+			fmt.Printf("dangling %s %s\n", objType, hash)
+		}
+
+		if opts.lostFound && dangling {
+			if err := saveLostFound(repo, store, hash, objType); err != nil {
+				return err
+			}
+		}
+	}
+
+	if checker.failed {
+		return ExitCode(1)
+	}
+	return nil
+}
+
+// Collects what keeps objects alive: HEAD, refs, their reflogs, an
+// in-progress merge and the index. Broken refs are reported on the way.
+func fsckRoots(repo *repository.Repository, checker *fsckChecker) ([]fsckLink, error) {
+	refsMan, err := repo.GetRefsManager()
+	if err != nil {
+		return nil, err
+	}
+
+	var roots []fsckLink
+	addRef := func(name, hash string, wantCommit bool) {
+		if !checker.store.HasObject(hash) {
+			checker.errorf("error: %s: invalid sha1 pointer %s", name, hash)
+			return
+		}
+		if objType := checker.read(hash); wantCommit && objType != "" && objType != objects.CommitObject {
+			checker.errorf("error: %s: not a commit", name)
+		}
+		roots = append(roots, fsckLink{hash: hash, objType: checker.types[hash]})
+	}
+
+	names, err := refsMan.ListRefs()
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range names {
+		_, hash, err := refsMan.ResolveRef(name)
+		if err != nil {
+			return nil, err
+		}
+		addRef(name, hash, strings.HasPrefix(name, "refs/heads/"))
+	}
+
+	head, err := refsMan.GetHead()
+	if err != nil {
+		return nil, err
+	}
+	if branch, ok := strings.CutPrefix(head, "refs/heads/"); ok {
+		if !refsMan.BranchExists(branch) {
+			fmt.Printf("notice: HEAD points to an unborn branch (%s)\n", branch)
+		}
+	} else {
+		addRef("HEAD", head, true)
+	}
+
+	if mergeHead, err := readMergeHead(repo); err != nil {
+		return nil, err
+	} else if mergeHead != "" {
+		addRef(mergeHeadFile, mergeHead, true)
+	}
+
+	// Reflog entries keep the commits they mention
+	for _, name := range append([]string{"HEAD"}, names...) {
+		entries, err := refsMan.ReadReflog(name)
+		if err != nil {
+			checker.errorf("error: %v", err)
+			continue
+		}
+		for _, entry := range entries {
+			for _, hash := range []string{entry.Old, entry.New} {
+				if hash != refs.ZeroHash && checker.store.HasObject(hash) {
+					roots = append(roots, fsckLink{hash: hash, objType: objects.CommitObject})
+				}
+			}
+		}
+	}
+
+	idx, err := repo.GetIndex()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get index: %w", err)
+	}
+	for _, entry := range indexEntries(idx) {
+		if !checker.store.HasObject(entry.Hash) {
+			checker.errorf("error: %s: invalid sha1 pointer in index", entry.Path)
+			continue
+		}
+		roots = append(roots, fsckLink{hash: entry.Hash, objType: objects.BlobObject})
+	}
+
+	return roots, nil
+}
+
+// Re-hashes an object and checks that it parses as its type
+func (c *fsckChecker) verify(hash string) {
+	// A broken object has no type and is not looked at again
+	c.types[hash] = ""
+
+	obj, err := c.store.LoadObject(hash)
+	if err != nil {
+		c.errorf("error: %s: object corrupt or missing: %v", hash, err)
+		return
+	}
+
+	if actual := c.store.HashContent(obj.Type, obj.Content); actual != hash {
+		c.errorf("error: hash mismatch for %s (found %s)", hash, actual)
+		return
+	}
+	if obj.Size != int64(len(obj.Content)) {
+		c.errorf("error: %s: object size %d does not match its header (%d)", hash, len(obj.Content), obj.Size)
+		return
+	}
+
+	c.record(obj)
+}
+
+// Returns an object's type, reading and parsing it the first time it is seen.
+// Unreadable objects are reported and have no type.
+func (c *fsckChecker) read(hash string) objects.ObjectType {
+	if objType, ok := c.types[hash]; ok {
+		return objType
+	}
+
+	obj, err := c.store.LoadObject(hash)
+	if err != nil {
+		c.errorf("error: %s: object corrupt or missing: %v", hash, err)
+		c.types[hash] = ""
+		return ""
+	}
+	c.record(obj)
+	return c.types[hash]
+}
+
+// Parses an object and remembers its type and the objects it points to
+func (c *fsckChecker) record(obj *objects.Object) {
+	var links []fsckLink
+
+	switch obj.Type {
+	case objects.BlobObject:
+	case objects.TreeObject:
+		tree, err := c.store.ParseTree(obj.Content)
+		if err != nil {
+			c.errorf("error: in tree %s: %v", obj.Hash, err)
+			break
+		}
+		for _, entry := range tree.Entries {
+			links = append(links, fsckLink{hash: entry.Hash, objType: entry.Type})
+		}
+	case objects.CommitObject:
+		commit, err := c.store.ParseCommit(obj.Content)
+		if err != nil {
+			c.errorf("error: in commit %s: %v", obj.Hash, err)
+			break
+		}
+		links = append(links, fsckLink{hash: commit.Tree, objType: objects.TreeObject})
+		for _, parent := range commit.Parents {
+			links = append(links, fsckLink{hash: parent, objType: objects.CommitObject})
+		}
+	case objects.TagObject:
+		tag, err := c.store.ParseTag(obj.Content)
+		if err != nil {
+			c.errorf("error: in tag %s: %v", obj.Hash, err)
+			break
+		}
+		links = append(links, fsckLink{hash: tag.Object, objType: tag.Type})
+	default:
+		c.errorf("error: %s: unknown object type %s", obj.Hash, obj.Type)
+	}
+
+	c.types[obj.Hash] = obj.Type
+	c.links[obj.Hash] = links
+}
+
+// Marks everything reachable from the roots, reporting missing objects
+func (c *fsckChecker) connect(roots []fsckLink) map[string]bool {
+	reachable := make(map[string]bool)
+	missing := make(map[string]objects.ObjectType)
+	queue := roots
+
+	for len(queue) > 0 {
+		link := queue[0]
+		queue = queue[1:]
+		if reachable[link.hash] {
+			continue
+		}
+		reachable[link.hash] = true
+
+		if !c.store.HasObject(link.hash) {
+			missing[link.hash] = link.objType
+			continue
+		}
+		if objType := c.read(link.hash); objType != "" && link.objType != "" && objType != link.objType {
+			c.errorf("error: object %s is a %s, not a %s", link.hash, objType, link.objType)
+		}
+		queue = append(queue, c.links[link.hash]...)
+	}
+
+	hashes := make([]string, 0, len(missing))
+	for hash := range missing {
+		hashes = append(hashes, hash)
+	}
+	sort.Strings(hashes)
+	for _, hash := range hashes {
+		fmt.Printf("missing %s %s\n", missing[hash], hash)
+		c.failed = true
+	}
+
+	return reachable
+}
+
+func (c *fsckChecker) errorf(format string, args ...any) {
+	fmt.Printf(format+"\n", args...)
+	c.failed = true
+}
+
+// Writes a dangling object to lost-found/commit or lost-found/other: blobs
+// by content, anything else by name
+func saveLostFound(repo *repository.Repository, store *objects.Store, hash string, objType objects.ObjectType) error {
+	kind := "other"
+	if objType == objects.CommitObject {
+		kind = "commit"
+	}
+	dir := filepath.Join(repo.GetMinigitDirectory(), "lost-found", kind)
+	// 0755 ~~ rwxr-xr-x
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	content := []byte(hash + "\n")
+	if objType == objects.BlobObject {
+		obj, err := store.LoadObject(hash)
+		if err != nil {
+			return err
+		}
+		content = obj.Content
+	}
+	return os.WriteFile(filepath.Join(dir, hash), content, 0644)
+}
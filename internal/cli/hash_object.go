@@ -0,0 +1,131 @@
+package cli
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"minigit/internal/objects"
+)
+
+func handleHashObject(args []string) error {
+	write := false
+	fromStdin := false
+	stdinPaths := false
+	objType := objects.BlobObject
+	var paths []string
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "-w":
+			write = true
+		case arg == "--stdin":
+			fromStdin = true
+		case arg == "--stdin-paths":
+			stdinPaths = true
+		case arg == "-t":
+			if i+1 >= len(args) {
+				return fmt.Errorf("switch `t' requires a value")
+			}
+			i++
+			objType = objects.ObjectType(args[i])
+		case strings.HasPrefix(arg, "-") && arg != "-":
+			return fmt.Errorf("unknown option: %s", arg)
+		default:
+			paths = append(paths, arg)
+		}
+	}
+
+	switch objType {
+	case objects.BlobObject, objects.TreeObject, objects.CommitObject, objects.TagObject:
+	default:
+		return fmt.Errorf("fatal: invalid object type \"%s\"", objType)
+	}
+	if stdinPaths && (fromStdin || len(paths) > 0) {
+		return fmt.Errorf("fatal: Can't use --stdin-paths with --stdin or paths")
+	}
+
+	// Hashing alone works outside a repository
+	store, err := objects.NewStore("")
+	if err != nil {
+		return err
+	}
+	if write {
+		repo, err := findRepository()
+		if err != nil {
+			return err
+		}
+		if store, err = repo.GetObjectStore(); err != nil {
+			return err
+		}
+	}
+
+	hash := func(content []byte) error {
+		if err := validateObject(store, objType, content); err != nil {
+			return err
+		}
+
+		if !write {
+			fmt.Println(store.HashContent(objType, content))
+			return nil
+		}
+		hash, err := store.StoreObject(objType, content)
+		if err != nil {
+			return fmt.Errorf("failed to store object: %w", err)
+		}
+		fmt.Println(hash)
+		return nil
+	}
+
+	if fromStdin {
+		content, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return fmt.Errorf("failed to read stdin: %w", err)
+		}
+		if err := hash(content); err != nil {
+			return err
+		}
+	}
+
+	if stdinPaths {
+		scanner := bufio.NewScanner(os.Stdin)
+		for scanner.Scan() {
+			paths = append(paths, scanner.Text())
+		}
+		if err := scanner.Err(); err != nil {
+			return fmt.Errorf("failed to read stdin: %w", err)
+		}
+	}
+
+	for _, path := range paths {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("fatal: could not open '%s' for reading: %w", path, err)
+		}
+		if err := hash(content); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Refuses trees, commits and tags that would not parse back
+func validateObject(store *objects.Store, objType objects.ObjectType, content []byte) error {
+	var err error
+	switch objType {
+	case objects.TreeObject:
+		_, err = store.ParseTree(content)
+	case objects.CommitObject:
+		_, err = store.ParseCommit(content)
+	case objects.TagObject:
+		_, err = store.ParseTag(content)
+	}
+	if err != nil {
+		return fmt.Errorf("fatal: corrupt %s: %w", objType, err)
+	}
+	return nil
+}
@@ -1,8 +1,317 @@
 package cli
 
-import "fmt"
+import (
+	"container/heap"
+	"fmt"
+	"minigit/internal/objects"
+	"minigit/internal/repository"
+	"minigit/internal/revparse"
+	"strconv"
+	"strings"
+)
+
+type logOptions struct {
+	oneline     bool
+	firstParent bool
+	maxCount    int // -1 means unlimited
+	revisions   []string
+}
 
 func handleLog(args []string) error {
-	fmt.Println("Not Implemented")
+	opts, err := parseLogArgs(args)
+	if err != nil {
+		return err
+	}
+
+	repo, err := findRepository()
+	if err != nil {
+		return err
+	}
+
+	store, err := repo.GetObjectStore()
+	if err != nil {
+		return err
+	}
+
+	refsMan, err := repo.GetRefsManager()
+	if err != nil {
+		return fmt.Errorf("failed to get refs manager: %w", err)
+	}
+
+	include, exclude, err := logRevisions(repo, opts.revisions)
+	if err != nil {
+		return err
+	}
+
+	if len(include) == 0 {
+		if len(opts.revisions) > 0 {
+			return nil
+		}
+		branchName, _ := refsMan.CurrentBranch()
+		return fmt.Errorf("fatal: your current branch '%s' does not have any commits yet", branchName)
+	}
+
+	// Commits reachable from an excluded revision are hidden
+	hidden := make(map[string]bool)
+	if len(exclude) > 0 {
+		err = walkCommits(store, exclude, false, func(hash string, _ *objects.Commit) bool {
+			hidden[hash] = true
+			return true
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	shown := 0
+	return walkCommits(store, include, opts.firstParent, func(hash string, commit *objects.Commit) bool {
+		if opts.maxCount >= 0 && shown >= opts.maxCount {
+			return false
+		}
+		if hidden[hash] {
+			return true
+		}
+
+		printLogEntry(hash, commit, opts.oneline)
+		shown++
+		return true
+	})
+}
+
+// Turns log arguments into the commits to start from and the commits whose
+// history is left out: "A..B" shows B without A, "A...B" shows what either
+// has that their merge bases lack, and "^A" leaves out A. Without arguments
+// the log starts at HEAD, which is empty on an unborn branch.
+func logRevisions(repo *repository.Repository, revisions []string) ([]string, []string, error) {
+	if len(revisions) == 0 {
+		head, err := resolveRevision(repo, "")
+		if err != nil || head == "" {
+			return nil, nil, err
+		}
+		return []string{head}, nil, nil
+	}
+
+	store, err := repo.GetObjectStore()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Unlike the default, a HEAD named explicitly has to exist
+	resolve := func(rev string) (string, error) {
+		hash, err := resolveRevision(repo, rev)
+		if err == nil && hash == "" {
+			err = &revparse.UnknownRevisionError{Rev: rev}
+		}
+		return hash, err
+	}
+
+	var include, exclude []string
+	for _, rev := range revisions {
+		if from, to, symmetric, ok := revparse.SplitRange(rev); ok {
+			fromHash, err := resolve(from)
+			if err != nil {
+				return nil, nil, err
+			}
+			toHash, err := resolve(to)
+			if err != nil {
+				return nil, nil, err
+			}
+
+			if !symmetric {
+				include = append(include, toHash)
+				exclude = append(exclude, fromHash)
+				continue
+			}
+
+			// Excluding every merge base excludes all commits reachable from both
+			bases, err := mergeBases(store, fromHash, toHash)
+			if err != nil {
+				return nil, nil, err
+			}
+			include = append(include, fromHash, toHash)
+			exclude = append(exclude, bases...)
+			continue
+		}
+
+		if name, ok := strings.CutPrefix(rev, "^"); ok {
+			hash, err := resolve(name)
+			if err != nil {
+				return nil, nil, err
+			}
+			exclude = append(exclude, hash)
+			continue
+		}
+
+		hash, err := resolve(rev)
+		if err != nil {
+			return nil, nil, err
+		}
+		include = append(include, hash)
+	}
+
+	return include, exclude, nil
+}
+
+func parseLogArgs(args []string) (*logOptions, error) {
+	opts := &logOptions{maxCount: -1}
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+
+		switch {
+		case arg == "--oneline":
+			opts.oneline = true
+		case arg == "--first-parent":
+			opts.firstParent = true
+		case arg == "-n":
+			if i+1 >= len(args) {
+				return nil, fmt.Errorf("switch `n' requires a value")
+			}
+			i++
+			count, err := parseMaxCount(args[i])
+			if err != nil {
+				return nil, err
+			}
+			opts.maxCount = count
+		case strings.HasPrefix(arg, "--max-count="):
+			count, err := parseMaxCount(strings.TrimPrefix(arg, "--max-count="))
+			if err != nil {
+				return nil, err
+			}
+			opts.maxCount = count
+		case strings.HasPrefix(arg, "-n"):
+			count, err := parseMaxCount(strings.TrimPrefix(arg, "-n"))
+			if err != nil {
+				return nil, err
+			}
+			opts.maxCount = count
+		case strings.HasPrefix(arg, "-"):
+			return nil, fmt.Errorf("unknown option: %s", arg)
+		default:
+			opts.revisions = append(opts.revisions, arg)
+		}
+	}
+
+	return opts, nil
+}
+
+func parseMaxCount(value string) (int, error) {
+	count, err := strconv.Atoi(value)
+	if err != nil || count < 0 {
+		return 0, fmt.Errorf("invalid count: '%s'", value)
+	}
+	return count, nil
+}
+
+func printLogEntry(hash string, commit *objects.Commit, oneline bool) {
+	if oneline {
+		subject, _, _ := strings.Cut(commit.Message, "\n")
+		fmt.Printf("%s %s\n", shortenHash(hash), subject)
+		return
+	}
+
+	fmt.Printf("commit %s\n", hash)
+	if len(commit.Parents) > 1 {
+		shortParents := make([]string, len(commit.Parents))
+		for i, parent := range commit.Parents {
+			shortParents[i] = shortenHash(parent)
+		}
+		fmt.Printf("Merge: %s\n", strings.Join(shortParents, " "))
+	}
+	fmt.Printf("Author: %s\n", commit.Author)
+	fmt.Printf("Date:   %s\n", commit.Author.When.Format("Mon Jan 2 15:04:05 2006 -0700"))
+	fmt.Println()
+	for line := range strings.SplitSeq(commit.Message, "\n") {
+		fmt.Printf("    %s\n", line)
+	}
+	fmt.Println()
+}
+
+func shortenHash(hash string) string {
+	if len(hash) > 7 {
+		return hash[:7]
+	}
+	return hash
+}
+
+// Visits commits reachable from starts, newest first, each commit at most once.
+// With firstParent only the first parent of every commit is followed.
+// Walking stops as soon as visit returns false.
+func walkCommits(store *objects.Store, starts []string, firstParent bool, visit func(hash string, commit *objects.Commit) bool) error {
+	queue := &commitQueue{}
+	seen := make(map[string]bool)
+
+	push := func(hash string) error {
+		if seen[hash] {
+			return nil
+		}
+		seen[hash] = true
+
+		commit, err := loadCommit(store, hash)
+		if err != nil {
+			return fmt.Errorf("failed to load commit %s: %w", hash, err)
+		}
+
+		heap.Push(queue, &queuedCommit{hash: hash, commit: commit, order: queue.pushed})
+		queue.pushed++
+		return nil
+	}
+
+	for _, start := range starts {
+		if err := push(start); err != nil {
+			return err
+		}
+	}
+
+	for queue.Len() > 0 {
+		next := heap.Pop(queue).(*queuedCommit)
+		if !visit(next.hash, next.commit) {
+			return nil
+		}
+
+		parents := next.commit.Parents
+		if firstParent && len(parents) > 1 {
+			parents = parents[:1]
+		}
+
+		for _, parent := range parents {
+			if err := push(parent); err != nil {
+				return err
+			}
+		}
+	}
+
 	return nil
 }
+
+type queuedCommit struct {
+	hash   string
+	commit *objects.Commit
+	order  int
+}
+
+// Max-heap of commits ordered by commit date, ties broken by insertion order
+type commitQueue struct {
+	items  []*queuedCommit
+	pushed int
+}
+
+func (q *commitQueue) Len() int { return len(q.items) }
+
+func (q *commitQueue) Less(i, j int) bool {
+	a, b := q.items[i], q.items[j]
+	if !a.commit.Committer.When.Equal(b.commit.Committer.When) {
+		return a.commit.Committer.When.After(b.commit.Committer.When)
+	}
+	return a.order < b.order
+}
+
+func (q *commitQueue) Swap(i, j int) { q.items[i], q.items[j] = q.items[j], q.items[i] }
+
+func (q *commitQueue) Push(x any) { q.items = append(q.items, x.(*queuedCommit)) }
+
+func (q *commitQueue) Pop() any {
+	last := q.items[len(q.items)-1]
+	q.items = q.items[:len(q.items)-1]
+	return last
+}
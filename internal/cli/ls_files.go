@@ -0,0 +1,145 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"minigit/internal/index"
+	"minigit/internal/repository"
+)
+
+type lsFilesOptions struct {
+	cached          bool
+	stage           bool
+	modified        bool
+	deleted         bool
+	others          bool
+	excludeStandard bool // hide ignored files from --others
+}
+
+func handleLsFiles(args []string) error {
+	opts := &lsFilesOptions{}
+
+	for _, arg := range args {
+		switch arg {
+		case "-c", "--cached":
+			opts.cached = true
+		case "-s", "--stage":
+			opts.stage = true
+		case "-m", "--modified":
+			opts.modified = true
+		case "-d", "--deleted":
+			opts.deleted = true
+		case "-o", "--others":
+			opts.others = true
+		case "--exclude-standard":
+			opts.excludeStandard = true
+		default:
+			return fmt.Errorf("unknown option: %s", arg)
+		}
+	}
+
+	// Like Git, the index is listed unless only other kinds were asked for
+	if !opts.stage && !opts.modified && !opts.deleted && !opts.others {
+		opts.cached = true
+	}
+
+	repo, err := findRepository()
+	if err != nil {
+		return err
+	}
+	idx, err := repo.GetIndex()
+	if err != nil {
+		return fmt.Errorf("failed to get index: %w", err)
+	}
+
+	if opts.others {
+		others, err := untrackedFiles(repo, !opts.excludeStandard)
+		if err != nil {
+			return err
+		}
+		for _, path := range others {
+			fmt.Println(path)
+		}
+	}
+
+	entries := indexEntries(idx)
+
+	if opts.cached || opts.stage {
+		lastPath := ""
+		for _, entry := range entries {
+			if opts.stage {
+				fmt.Printf("%s %s %d\t%s\n", gitFileMode(entry.Mode), entry.Hash, entry.Stage, entry.Path)
+			} else if entry.Path != lastPath {
+				// Unmerged paths have several stages but are listed once
+				fmt.Println(entry.Path)
+			}
+			lastPath = entry.Path
+		}
+	}
+
+	if opts.deleted || opts.modified {
+		workDir := repo.GetWorkingDirectory()
+		for _, entry := range entries {
+			content, err := readWorkingFile(filepath.Join(workDir, filepath.FromSlash(entry.Path)))
+			missing := err != nil
+
+			// Deleted files count as modified too
+			if opts.deleted && missing {
+				fmt.Println(entry.Path)
+			}
+			if opts.modified && (missing || entry.Stage != 0 || calculateFileHash(content) != entry.Hash) {
+				fmt.Println(entry.Path)
+			}
+		}
+	}
+
+	return nil
+}
+
+// Returns all index entries, conflict stages included, sorted by path and stage
+func indexEntries(idx *index.Index) []*index.Entry {
+	var entries []*index.Entry
+	for _, entry := range idx.GetEntries() {
+		entries = append(entries, entry)
+	}
+	for _, stages := range idx.GetConflicts() {
+		for _, entry := range stages {
+			if entry != nil {
+				entries = append(entries, entry)
+			}
+		}
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Path != entries[j].Path {
+			return entries[i].Path < entries[j].Path
+		}
+		return entries[i].Stage < entries[j].Stage
+	})
+	return entries
+}
+
+// Lists working tree files that are not in the index, sorted
+func untrackedFiles(repo *repository.Repository, includeIgnored bool) ([]string, error) {
+	tracked, err := trackedPaths(repo)
+	if err != nil {
+		return nil, err
+	}
+
+	var others []string
+	err = walkWorkingTree(repo, repo.GetWorkingDirectory(), includeIgnored, func(_, relPath string, _ os.FileInfo) error {
+		if !tracked[relPath] {
+			others = append(others, relPath)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Strings(others)
+	return others, nil
+}
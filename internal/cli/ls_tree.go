@@ -0,0 +1,160 @@
+package cli
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"minigit/internal/objects"
+)
+
+type lsTreeOptions struct {
+	recursive bool
+	showTrees bool // list the trees themselves when recursing
+	nameOnly  bool
+	paths     []string
+}
+
+func handleLsTree(args []string) error {
+	opts := &lsTreeOptions{}
+	var treeish string
+
+	for _, arg := range args {
+		switch {
+		case arg == "-r":
+			opts.recursive = true
+		case arg == "-t":
+			opts.showTrees = true
+		case arg == "--name-only" || arg == "--name-status":
+			opts.nameOnly = true
+		case strings.HasPrefix(arg, "-"):
+			return fmt.Errorf("unknown option: %s", arg)
+		case treeish == "":
+			treeish = arg
+		default:
+			// A trailing slash asks for what is inside a tree, so it is kept
+			opts.paths = append(opts.paths, arg)
+		}
+	}
+
+	if treeish == "" {
+		return fmt.Errorf("usage: ls-tree [-r] [-t] [--name-only] <tree-ish> [<path>...]")
+	}
+
+	repo, err := findRepository()
+	if err != nil {
+		return err
+	}
+	store, err := repo.GetObjectStore()
+	if err != nil {
+		return err
+	}
+
+	hash, err := resolveObjectName(repo, treeish)
+	if err != nil {
+		return fmt.Errorf("fatal: Not a valid object name %s", treeish)
+	}
+	treeHash, err := peelToTree(store, hash)
+	if err != nil {
+		return err
+	}
+
+	return listTree(store, treeHash, "", opts)
+}
+
+// Returns the tree of a commit or of what a tag points to, or the object
+// itself if it is a tree
+func peelToTree(store *objects.Store, hash string) (string, error) {
+	obj, err := store.LoadObject(hash)
+	if err != nil {
+		return "", fmt.Errorf("fatal: Not a valid object name %s", hash)
+	}
+
+	switch obj.Type {
+	case objects.TreeObject:
+		return hash, nil
+	case objects.CommitObject:
+		commit, err := store.ParseCommit(obj.Content)
+		if err != nil {
+			return "", err
+		}
+		return commit.Tree, nil
+	case objects.TagObject:
+		tag, err := store.ParseTag(obj.Content)
+		if err != nil {
+			return "", err
+		}
+		return peelToTree(store, tag.Object)
+	}
+	return "", fmt.Errorf("fatal: not a tree object")
+}
+
+func listTree(store *objects.Store, treeHash, prefix string, opts *lsTreeOptions) error {
+	obj, err := store.LoadObject(treeHash)
+	if err != nil {
+		return fmt.Errorf("failed to load tree %s: %w", treeHash, err)
+	}
+	tree, err := store.ParseTree(obj.Content)
+	if err != nil {
+		return fmt.Errorf("fatal: corrupt tree %s: %w", treeHash, err)
+	}
+
+	for _, entry := range tree.Entries {
+		fullPath := path.Join(prefix, entry.Name)
+		isTree := entry.Type == objects.TreeObject
+
+		if !lsTreeSelects(fullPath, isTree, opts) {
+			continue
+		}
+
+		// Recursing into a tree replaces it by its contents unless -t asks for both
+		descend := isTree && (opts.recursive || lsTreeLeadsTo(fullPath, opts.paths))
+		if !descend || opts.showTrees {
+			if opts.nameOnly {
+				fmt.Println(fullPath)
+			} else {
+				fmt.Printf("%s %s %s\t%s\n", treeEntryMode(entry), entry.Type, entry.Hash, fullPath)
+			}
+		}
+
+		if descend {
+			if err := listTree(store, entry.Hash, fullPath, opts); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// Reports whether a path is listed: without paths everything is, otherwise
+// the named paths, what lies under them, and the trees leading to them
+func lsTreeSelects(entryPath string, isTree bool, opts *lsTreeOptions) bool {
+	if len(opts.paths) == 0 {
+		return true
+	}
+	if isTree && lsTreeLeadsTo(entryPath, opts.paths) {
+		return true
+	}
+	for _, p := range opts.paths {
+		// "dir/" names the entries in dir rather than dir itself
+		if dir, ok := strings.CutSuffix(p, "/"); ok {
+			if strings.HasPrefix(entryPath, dir+"/") {
+				return true
+			}
+		} else if matchesPathspec(entryPath, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// Reports whether a tree has to be entered to reach one of the paths
+func lsTreeLeadsTo(treePath string, paths []string) bool {
+	for _, p := range paths {
+		if strings.HasPrefix(p, treePath+"/") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,598 @@
+package cli
+
+import (
+	"fmt"
+	"minigit/internal/diff"
+	"minigit/internal/index"
+	"minigit/internal/objects"
+	"minigit/internal/refs"
+	"minigit/internal/repository"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	mergeHeadFile = "MERGE_HEAD"
+	mergeMsgFile  = "MERGE_MSG"
+)
+
+// How a single path comes out of a three-way merge
+type mergedPath struct {
+	path               string
+	base, ours, theirs *objects.IndexEntry
+	// The clean result, nil when the path is deleted or conflicted
+	result *objects.IndexEntry
+	// Conflict kind ("content", "add/add", "modify/delete"), empty when clean
+	conflict string
+	// Working tree content for a conflicted path
+	content []byte
+	// Whether both sides' contents were merged line by line
+	autoMerged bool
+}
+
+func handleMerge(args []string) error {
+	var target, message string
+	noFastForward := false
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "--continue" || arg == "--abort":
+			if len(args) != 1 {
+				return fmt.Errorf("fatal: %s expects no arguments", arg)
+			}
+			if arg == "--continue" {
+				return continueMerge()
+			}
+			return abortMerge()
+		case arg == "--no-ff":
+			noFastForward = true
+		case arg == "-m":
+			if i+1 >= len(args) {
+				return fmt.Errorf("switch `m' requires a value")
+			}
+			message = args[i+1]
+			i++
+		case strings.HasPrefix(arg, "-"):
+			return fmt.Errorf("unknown option: %s", arg)
+		case target != "":
+			return fmt.Errorf("fatal: merging more than one commit at a time is not supported")
+		default:
+			target = arg
+		}
+	}
+
+	if target == "" {
+		return fmt.Errorf("usage: merge [--no-ff] [-m <message>] <branch>\n   or: merge --continue\n   or: merge --abort")
+	}
+
+	repo, err := findRepository()
+	if err != nil {
+		return err
+	}
+
+	return mergeRevision(repo, target, message, noFastForward)
+}
+
+func mergeRevision(repo *repository.Repository, target, message string, noFastForward bool) error {
+	refsMan, err := repo.GetRefsManager()
+	if err != nil {
+		return fmt.Errorf("failed to get refs manager: %w", err)
+	}
+
+	store, err := repo.GetObjectStore()
+	if err != nil {
+		return err
+	}
+
+	idx, err := repo.GetIndex()
+	if err != nil {
+		return fmt.Errorf("failed to get index: %w", err)
+	}
+
+	if mergeHead, err := readMergeHead(repo); err != nil {
+		return err
+	} else if mergeHead != "" {
+		return fmt.Errorf("fatal: You have not concluded your merge (MERGE_HEAD exists).\nPlease, commit your changes before you merge.")
+	}
+	if idx.HasConflicts() {
+		return fmt.Errorf("error: Merging is not possible because you have unmerged files.\nfatal: Exiting because of an unresolved conflict.")
+	}
+
+	theirs, err := resolveRevision(repo, target)
+	if err != nil || theirs == "" {
+		return fmt.Errorf("merge: %s - not something we can merge", target)
+	}
+
+	head, err := refsMan.ResolveHead()
+	if err != nil {
+		return fmt.Errorf("failed to get HEAD: %w", err)
+	}
+
+	// Nothing to merge into yet: just take the other history
+	if head == "" {
+		if err := switchWorkingTree(repo, "", theirs); err != nil {
+			return err
+		}
+		return refsMan.UpdateHead(theirs, refs.ZeroHash, "merge "+target+": Fast-forward")
+	}
+
+	base, err := mergeBase(store, head, theirs)
+	if err != nil {
+		return fmt.Errorf("failed to find merge base: %w", err)
+	}
+	if base == "" {
+		return fmt.Errorf("fatal: refusing to merge unrelated histories")
+	}
+
+	if base == theirs {
+		fmt.Println("Already up to date.")
+		return nil
+	}
+
+	oursFiles, err := readCommitFiles(store, head)
+	if err != nil {
+		return fmt.Errorf("failed to read current tree: %w", err)
+	}
+	theirsFiles, err := readCommitFiles(store, theirs)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", target, err)
+	}
+
+	if base == head && !noFastForward {
+		if err := switchWorkingTree(repo, head, theirs); err != nil {
+			return err
+		}
+		if err := refsMan.UpdateHead(theirs, head, "merge "+target+": Fast-forward"); err != nil {
+			return fmt.Errorf("failed to update HEAD: %w", err)
+		}
+
+		fmt.Printf("Updating %s..%s\n", shortenHash(head), shortenHash(theirs))
+		fmt.Println("Fast-forward")
+		return printDiffStat(store, &diffSide{files: oursFiles}, &diffSide{files: theirsFiles}, changedPaths(oursFiles, theirsFiles))
+	}
+
+	baseFiles, err := readCommitFiles(store, base)
+	if err != nil {
+		return fmt.Errorf("failed to read merge base: %w", err)
+	}
+
+	if message == "" {
+		message = defaultMergeMessage(repo, target, theirs)
+	}
+
+	plan, err := planMerge(store, baseFiles, oursFiles, theirsFiles, target)
+	if err != nil {
+		return err
+	}
+
+	if err := checkMergeOverwrites(repo, oursFiles, plan); err != nil {
+		return err
+	}
+
+	conflicts, err := applyMerge(repo, store, idx, plan, target)
+	if err != nil {
+		return err
+	}
+
+	if conflicts > 0 {
+		if err := writeMergeState(repo, theirs, message); err != nil {
+			return err
+		}
+		return fmt.Errorf("Automatic merge failed; fix conflicts and then commit the result.")
+	}
+
+	resultFiles, err := indexSnapshot(repo)
+	if err != nil {
+		return fmt.Errorf("failed to read index: %w", err)
+	}
+
+	treeHash, err := store.CreateTreeFromIndex(resultFiles)
+	if err != nil {
+		return fmt.Errorf("failed to create tree: %w", err)
+	}
+
+	commitHash, err := createCommit(repo, store, treeHash, []string{head, theirs}, message)
+	if err != nil {
+		return fmt.Errorf("failed to create commit: %w", err)
+	}
+
+	if err := refsMan.UpdateHead(commitHash, head, "merge "+target+": Merge made by the three-way strategy."); err != nil {
+		return fmt.Errorf("failed to update HEAD: %w", err)
+	}
+
+	fmt.Println("Merge made by the 'three-way' strategy.")
+	return printDiffStat(store, &diffSide{files: oursFiles}, &diffSide{files: resultFiles}, changedPaths(oursFiles, resultFiles))
+}
+
+// Decides the outcome of every path touched by either side
+func planMerge(store *objects.Store, baseFiles, oursFiles, theirsFiles map[string]*objects.IndexEntry, theirsLabel string) ([]*mergedPath, error) {
+	paths := make(map[string]bool)
+	for _, files := range []map[string]*objects.IndexEntry{baseFiles, oursFiles, theirsFiles} {
+		for path := range files {
+			paths[path] = true
+		}
+	}
+
+	sorted := make([]string, 0, len(paths))
+	for path := range paths {
+		sorted = append(sorted, path)
+	}
+	sort.Strings(sorted)
+
+	var plan []*mergedPath
+	for _, path := range sorted {
+		merged, err := mergePath(store, path, baseFiles[path], oursFiles[path], theirsFiles[path], theirsLabel)
+		if err != nil {
+			return nil, err
+		}
+		plan = append(plan, merged)
+	}
+
+	return plan, nil
+}
+
+func mergePath(store *objects.Store, path string, base, ours, theirs *objects.IndexEntry, theirsLabel string) (*mergedPath, error) {
+	merged := &mergedPath{path: path, base: base, ours: ours, theirs: theirs}
+
+	switch {
+	case sameEntry(ours, theirs) || sameEntry(base, theirs):
+		merged.result = ours
+		return merged, nil
+	case sameEntry(base, ours):
+		merged.result = theirs
+		return merged, nil
+	case ours == nil || theirs == nil:
+		// Changed on one side, deleted on the other: keep the surviving version around
+		survivor := ours
+		if survivor == nil {
+			survivor = theirs
+		}
+		blob, err := store.LoadObject(survivor.Hash)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load blob for %s: %w", path, err)
+		}
+		merged.conflict = "modify/delete"
+		merged.content = blob.Content
+		return merged, nil
+	}
+
+	// Both sides changed the file; take whichever mode change was made, and
+	// conflict when each made a different one
+	mode := ours.Mode
+	if base != nil && ours.Mode == base.Mode {
+		mode = theirs.Mode
+	}
+	modeConflict := ours.Mode != theirs.Mode && (base == nil || (ours.Mode != base.Mode && theirs.Mode != base.Mode))
+
+	conflictKind := "content"
+	if base == nil {
+		conflictKind = "add/add"
+	}
+
+	if ours.Hash == theirs.Hash {
+		if modeConflict {
+			blob, err := store.LoadObject(ours.Hash)
+			if err != nil {
+				return nil, fmt.Errorf("failed to load blob for %s: %w", path, err)
+			}
+			merged.conflict = conflictKind
+			merged.content = blob.Content
+			return merged, nil
+		}
+		merged.result = &objects.IndexEntry{Path: path, Hash: ours.Hash, Mode: mode}
+		return merged, nil
+	}
+
+	var baseContent []byte
+	if base != nil {
+		blob, err := store.LoadObject(base.Hash)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load blob for %s: %w", path, err)
+		}
+		baseContent = blob.Content
+	}
+	oursBlob, err := store.LoadObject(ours.Hash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load blob for %s: %w", path, err)
+	}
+	theirsBlob, err := store.LoadObject(theirs.Hash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load blob for %s: %w", path, err)
+	}
+
+	if diff.IsBinary(baseContent) || diff.IsBinary(oursBlob.Content) || diff.IsBinary(theirsBlob.Content) {
+		merged.conflict = conflictKind
+		merged.content = oursBlob.Content
+		return merged, nil
+	}
+
+	result := diff.Merge3(baseContent, oursBlob.Content, theirsBlob.Content, "HEAD", theirsLabel)
+	merged.autoMerged = true
+	if result.Conflicts > 0 || modeConflict {
+		merged.conflict = conflictKind
+		merged.content = result.Content
+		return merged, nil
+	}
+
+	hash, err := store.StoreObject(objects.BlobObject, result.Content)
+	if err != nil {
+		return nil, fmt.Errorf("failed to store merged %s: %w", path, err)
+	}
+	merged.result = &objects.IndexEntry{Path: path, Hash: hash, Mode: mode}
+
+	return merged, nil
+}
+
+// Refuses to merge over staged changes, or over working tree files the merge would rewrite
+func checkMergeOverwrites(repo *repository.Repository, oursFiles map[string]*objects.IndexEntry, plan []*mergedPath) error {
+	indexFiles, err := indexSnapshot(repo)
+	if err != nil {
+		return fmt.Errorf("failed to read index: %w", err)
+	}
+
+	dirty := changedPaths(oursFiles, indexFiles)
+	var untracked []string
+
+	if len(dirty) == 0 {
+		workDir := repo.GetWorkingDirectory()
+		for _, merged := range plan {
+			if merged.conflict == "" && sameEntry(merged.result, merged.ours) {
+				continue
+			}
+
+			content, err := readWorkingFile(filepath.Join(workDir, filepath.FromSlash(merged.path)))
+			if err != nil {
+				continue
+			}
+
+			workHash := calculateFileHash(content)
+			switch {
+			case merged.ours != nil && workHash == merged.ours.Hash:
+			case merged.result != nil && workHash == merged.result.Hash:
+			case merged.ours != nil:
+				dirty = append(dirty, merged.path)
+			default:
+				untracked = append(untracked, merged.path)
+			}
+		}
+	}
+
+	if len(dirty) == 0 && len(untracked) == 0 {
+		return nil
+	}
+
+	var msg strings.Builder
+	if len(dirty) > 0 {
+		msg.WriteString("error: Your local changes to the following files would be overwritten by merge:\n")
+		for _, path := range dirty {
+			fmt.Fprintf(&msg, "\t%s\n", path)
+		}
+		msg.WriteString("Please commit your changes or stash them before you merge.\n")
+	}
+	if len(untracked) > 0 {
+		msg.WriteString("error: The following untracked working tree files would be overwritten by merge:\n")
+		for _, path := range untracked {
+			fmt.Fprintf(&msg, "\t%s\n", path)
+		}
+		msg.WriteString("Please move or remove them before you merge.\n")
+	}
+	msg.WriteString("Aborting")
+	return fmt.Errorf("%s", msg.String())
+}
+
+// Writes the merge result into the working tree and index, returning the number of conflicted paths
+func applyMerge(repo *repository.Repository, store *objects.Store, idx *index.Index, plan []*mergedPath, theirsLabel string) (int, error) {
+	workDir := repo.GetWorkingDirectory()
+	conflicts := 0
+
+	for _, merged := range plan {
+		if merged.autoMerged {
+			fmt.Printf("Auto-merging %s\n", merged.path)
+		}
+
+		switch {
+		case merged.conflict != "":
+			conflicts++
+			if merged.conflict == "modify/delete" {
+				deletedIn, modifiedIn := theirsLabel, "HEAD"
+				if merged.ours == nil {
+					deletedIn, modifiedIn = "HEAD", theirsLabel
+				}
+				fmt.Printf("CONFLICT (modify/delete): %s deleted in %s and modified in %s. Version %s of %s left in tree.\n",
+					merged.path, deletedIn, modifiedIn, modifiedIn, merged.path)
+			} else {
+				fmt.Printf("CONFLICT (%s): Merge conflict in %s\n", merged.conflict, merged.path)
+			}
+
+			if err := writeConflictFile(workDir, merged); err != nil {
+				return conflicts, err
+			}
+			idx.AddConflict(merged.path, stageEntry(merged.base), stageEntry(merged.ours), stageEntry(merged.theirs))
+
+		case merged.result == nil:
+			if merged.ours == nil {
+				continue
+			}
+			if err := removeWorkingFile(workDir, merged.path); err != nil {
+				return conflicts, err
+			}
+			idx.RemoveEntry(merged.path)
+
+		case !sameEntry(merged.result, merged.ours):
+			result := &objects.IndexEntry{Path: merged.path, Hash: merged.result.Hash, Mode: merged.result.Mode}
+			if err := writeWorkingFile(store, workDir, result); err != nil {
+				return conflicts, err
+			}
+
+			info, err := os.Lstat(filepath.Join(workDir, filepath.FromSlash(merged.path)))
+			if err != nil {
+				return conflicts, fmt.Errorf("failed to stat %s: %w", merged.path, err)
+			}
+			idx.AddEntry(merged.path, result.Hash, info)
+		}
+	}
+
+	if err := idx.Write(); err != nil {
+		return conflicts, fmt.Errorf("failed to update index: %w", err)
+	}
+	return conflicts, nil
+}
+
+// Writes a conflicted path's content (with markers) into the working tree
+func writeConflictFile(workDir string, merged *mergedPath) error {
+	absPath := filepath.Join(workDir, filepath.FromSlash(merged.path))
+	// 0755 ~~ rwxr-xr-x
+	if err := os.MkdirAll(filepath.Dir(absPath), 0755); err != nil {
+		return fmt.Errorf("failed to create directory for %s: %w", merged.path, err)
+	}
+
+	perm := os.FileMode(0644)
+	for _, side := range []*objects.IndexEntry{merged.ours, merged.theirs} {
+		if side != nil && side.Mode.IsRegular() && side.Mode.Perm() != 0 {
+			perm = side.Mode.Perm()
+			break
+		}
+	}
+
+	if err := os.WriteFile(absPath, merged.content, perm); err != nil {
+		return fmt.Errorf("failed to write %s: %w", merged.path, err)
+	}
+	return nil
+}
+
+// Creates the merge commit once every conflict has been resolved and staged
+func continueMerge() error {
+	repo, err := findRepository()
+	if err != nil {
+		return err
+	}
+
+	mergeHead, err := readMergeHead(repo)
+	if err != nil {
+		return err
+	}
+	if mergeHead == "" {
+		return fmt.Errorf("fatal: There is no merge in progress (MERGE_HEAD missing).")
+	}
+
+	message, err := os.ReadFile(filepath.Join(repo.GetMinigitDirectory(), mergeMsgFile))
+	if err != nil {
+		return fmt.Errorf("failed to read merge message: %w", err)
+	}
+
+	return handleCommit([]string{"-m", strings.TrimRight(string(message), "\n")})
+}
+
+// Throws away a conflicted merge, returning the index and working tree to HEAD
+func abortMerge() error {
+	repo, err := findRepository()
+	if err != nil {
+		return err
+	}
+
+	mergeHead, err := readMergeHead(repo)
+	if err != nil {
+		return err
+	}
+	if mergeHead == "" {
+		return fmt.Errorf("fatal: There is no merge to abort (MERGE_HEAD missing).")
+	}
+
+	refsMan, err := repo.GetRefsManager()
+	if err != nil {
+		return fmt.Errorf("failed to get refs manager: %w", err)
+	}
+
+	store, err := repo.GetObjectStore()
+	if err != nil {
+		return err
+	}
+
+	idx, err := repo.GetIndex()
+	if err != nil {
+		return fmt.Errorf("failed to get index: %w", err)
+	}
+
+	head, err := refsMan.ResolveHead()
+	if err != nil {
+		return fmt.Errorf("failed to get HEAD: %w", err)
+	}
+
+	headFiles, err := readCommitFiles(store, head)
+	if err != nil {
+		return fmt.Errorf("failed to read current tree: %w", err)
+	}
+
+	if err := resetWorkingTree(repo, store, idx, headFiles, headFiles); err != nil {
+		return err
+	}
+	if err := loadIndexFromTree(repo, headFiles); err != nil {
+		return fmt.Errorf("failed to reset index: %w", err)
+	}
+
+	return clearMergeState(repo)
+}
+
+// Returns the commit being merged in, empty when no merge is in progress
+func readMergeHead(repo *repository.Repository) (string, error) {
+	data, err := os.ReadFile(filepath.Join(repo.GetMinigitDirectory(), mergeHeadFile))
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read MERGE_HEAD: %w", err)
+	}
+
+	return strings.TrimSpace(string(data)), nil
+}
+
+func writeMergeState(repo *repository.Repository, mergeHead, message string) error {
+	minigitDir := repo.GetMinigitDirectory()
+	if err := os.WriteFile(filepath.Join(minigitDir, mergeHeadFile), []byte(mergeHead+"\n"), 0644); err != nil {
+		return fmt.Errorf("failed to write MERGE_HEAD: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(minigitDir, mergeMsgFile), []byte(message+"\n"), 0644); err != nil {
+		return fmt.Errorf("failed to write MERGE_MSG: %w", err)
+	}
+	return nil
+}
+
+func clearMergeState(repo *repository.Repository) error {
+	for _, name := range []string{mergeHeadFile, mergeMsgFile} {
+		if err := os.Remove(filepath.Join(repo.GetMinigitDirectory(), name)); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+func defaultMergeMessage(repo *repository.Repository, target, commitHash string) string {
+	message := fmt.Sprintf("Merge commit '%s'", commitHash)
+	if refsMan, err := repo.GetRefsManager(); err == nil {
+		if refsMan.BranchExists(target) {
+			message = fmt.Sprintf("Merge branch '%s'", target)
+		}
+		if current, err := refsMan.CurrentBranch(); err == nil && current != "" && current != "main" {
+			message += " into " + current
+		}
+	}
+	return message
+}
+
+// Reports whether two snapshot entries hold the same content and mode (nil meaning absent)
+func sameEntry(a, b *objects.IndexEntry) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return a.Hash == b.Hash && a.Mode == b.Mode
+}
+
+func stageEntry(entry *objects.IndexEntry) *index.Entry {
+	if entry == nil {
+		return nil
+	}
+	return &index.Entry{Path: entry.Path, Hash: entry.Hash, Mode: entry.Mode}
+}
@@ -0,0 +1,76 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+)
+
+func handleReflog(args []string) error {
+	if len(args) > 0 && args[0] == "show" {
+		args = args[1:]
+	}
+
+	maxCount := -1
+	ref := ""
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "-n":
+			if i+1 >= len(args) {
+				return fmt.Errorf("switch `n' requires a value")
+			}
+			i++
+			count, err := parseMaxCount(args[i])
+			if err != nil {
+				return err
+			}
+			maxCount = count
+		case strings.HasPrefix(arg, "-n"):
+			count, err := parseMaxCount(strings.TrimPrefix(arg, "-n"))
+			if err != nil {
+				return err
+			}
+			maxCount = count
+		case strings.HasPrefix(arg, "-"):
+			return fmt.Errorf("unknown option: %s", arg)
+		case ref != "":
+			return fmt.Errorf("usage: reflog [show] [-n <count>] [<ref>]")
+		default:
+			ref = arg
+		}
+	}
+
+	repo, err := findRepository()
+	if err != nil {
+		return err
+	}
+	refsMan, err := repo.GetRefsManager()
+	if err != nil {
+		return err
+	}
+
+	fullName := "HEAD"
+	if ref == "" || ref == "@" {
+		ref = "HEAD"
+	} else if ref != "HEAD" {
+		if fullName, _, err = refsMan.ResolveRef(ref); err != nil {
+			return fmt.Errorf("fatal: ambiguous argument '%s': unknown revision or path not in the working tree", ref)
+		}
+	}
+
+	entries, err := refsMan.ReadReflog(fullName)
+	if err != nil {
+		return err
+	}
+
+	// Newest first, numbered the way <ref>@{n} counts
+	for n := 0; n < len(entries); n++ {
+		if maxCount >= 0 && n >= maxCount {
+			break
+		}
+		entry := entries[len(entries)-1-n]
+		fmt.Printf("%s %s@{%d}: %s\n", shortenHash(entry.New), ref, n, entry.Message)
+	}
+
+	return nil
+}
@@ -0,0 +1,184 @@
+package cli
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"minigit/internal/objects"
+	"minigit/internal/repository"
+)
+
+type repackOptions struct {
+	all    bool // pack every object, not just loose ones
+	delete bool // remove the packs and loose objects made redundant
+	quiet  bool
+	window int
+	depth  int
+}
+
+func handleRepack(args []string) error {
+	repo, err := findRepository()
+	if err != nil {
+		return err
+	}
+
+	opts, err := defaultRepackOptions(repo)
+	if err != nil {
+		return err
+	}
+
+	for _, arg := range args {
+		switch {
+		case arg == "-a":
+			opts.all = true
+		case arg == "-d":
+			opts.delete = true
+		case arg == "-ad" || arg == "-da":
+			opts.all, opts.delete = true, true
+		case arg == "-q" || arg == "--quiet":
+			opts.quiet = true
+		case strings.HasPrefix(arg, "--window="):
+			if opts.window, err = parseRepackLimit(arg); err != nil {
+				return err
+			}
+		case strings.HasPrefix(arg, "--depth="):
+			if opts.depth, err = parseRepackLimit(arg); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("unknown option: %s", arg)
+		}
+	}
+
+	return repack(repo, opts)
+}
+
+// Packs all objects into a single pack and drops everything it replaces
+func handleGC(args []string) error {
+	repo, err := findRepository()
+	if err != nil {
+		return err
+	}
+
+	opts, err := defaultRepackOptions(repo)
+	if err != nil {
+		return err
+	}
+	opts.all, opts.delete = true, true
+
+	for _, arg := range args {
+		switch arg {
+		case "-q", "--quiet":
+			opts.quiet = true
+		default:
+			return fmt.Errorf("unknown option: %s", arg)
+		}
+	}
+
+	return repack(repo, opts)
+}
+
+// Reads the delta search limits from pack.window and pack.depth
+func defaultRepackOptions(repo *repository.Repository) (*repackOptions, error) {
+	cfg, err := repo.GetConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	window, err := cfg.GetInt("pack.window", objects.DefaultPackOptions.Window)
+	if err != nil {
+		return nil, err
+	}
+	depth, err := cfg.GetInt("pack.depth", objects.DefaultPackOptions.Depth)
+	if err != nil {
+		return nil, err
+	}
+
+	return &repackOptions{window: window, depth: depth}, nil
+}
+
+func parseRepackLimit(arg string) (int, error) {
+	name, value, _ := strings.Cut(arg, "=")
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("fatal: invalid value for '%s': '%s'", strings.TrimPrefix(name, "--"), value)
+	}
+	return n, nil
+}
+
+func repack(repo *repository.Repository, opts *repackOptions) error {
+	store, err := repo.GetObjectStore()
+	if err != nil {
+		return err
+	}
+	cfg, err := repo.GetConfig()
+	if err != nil {
+		return err
+	}
+
+	useOffsets, err := cfg.GetBool("repack.usedeltabaseoffset", true)
+	if err != nil {
+		return err
+	}
+
+	oldPacks, err := store.PackNames()
+	if err != nil {
+		return err
+	}
+
+	var hashes []string
+	if opts.all {
+		hashes, err = store.ListObjects()
+	} else {
+		hashes, err = store.LooseObjects()
+	}
+	if err != nil {
+		return fmt.Errorf("failed to list objects: %w", err)
+	}
+
+	// Repacking a single pack with nothing loose would only rewrite it
+	if len(hashes) == 0 || (opts.all && len(oldPacks) == 1 && !hasLooseObjects(store)) {
+		if !opts.quiet {
+			fmt.Println("Nothing new to pack.")
+		}
+		return nil
+	}
+
+	stats, err := store.WritePack(hashes, objects.PackOptions{
+		Window:   opts.window,
+		Depth:    opts.depth,
+		RefDelta: !useOffsets,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to write pack: %w", err)
+	}
+
+	if !opts.quiet {
+		fmt.Printf("Total %d (delta %d)\n", stats.Objects, stats.Deltas)
+	}
+
+	if !opts.delete {
+		return nil
+	}
+
+	// With -a the new pack holds everything the old ones did
+	if opts.all {
+		for _, name := range oldPacks {
+			if name == stats.Name {
+				continue
+			}
+			if err := store.RemovePack(name); err != nil {
+				return err
+			}
+		}
+	}
+
+	_, err = store.PrunePacked()
+	return err
+}
+
+func hasLooseObjects(store *objects.Store) bool {
+	loose, err := store.LooseObjects()
+	return err != nil || len(loose) > 0
+}
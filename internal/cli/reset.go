@@ -1,8 +1,353 @@
 package cli
 
-import "fmt"
+import (
+	"fmt"
+	"minigit/internal/index"
+	"minigit/internal/objects"
+	"minigit/internal/repository"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+type resetMode string
+
+const (
+	resetSoft  resetMode = "soft"
+	resetMixed resetMode = "mixed"
+	resetHard  resetMode = "hard"
+)
 
 func handleReset(args []string) error {
-	fmt.Println("Not Implemented")
+	mode := resetMixed
+	modeGiven := false
+	separator := -1
+	var positional []string
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "--soft" || arg == "--mixed" || arg == "--hard":
+			mode = resetMode(strings.TrimPrefix(arg, "--"))
+			modeGiven = true
+		case arg == "--":
+			separator = len(positional)
+			positional = append(positional, args[i+1:]...)
+			i = len(args)
+		case strings.HasPrefix(arg, "-"):
+			return fmt.Errorf("unknown option: %s", arg)
+		default:
+			positional = append(positional, arg)
+		}
+	}
+
+	repo, err := findRepository()
+	if err != nil {
+		return err
+	}
+
+	var revision string
+	var paths []string
+
+	switch {
+	case separator > 1:
+		return fmt.Errorf("usage: reset [<commit>] -- <paths>...")
+	case separator >= 0:
+		if separator == 1 {
+			revision = positional[0]
+		}
+		paths = positional[separator:]
+	case len(positional) > 0:
+		// The first argument is a commit if it resolves as one, otherwise everything is a path
+		if _, err := resolveRevision(repo, positional[0]); err == nil {
+			revision = positional[0]
+			paths = positional[1:]
+		} else {
+			paths = positional
+		}
+	}
+
+	if len(paths) > 0 {
+		if modeGiven && mode != resetMixed {
+			return fmt.Errorf("fatal: Cannot do --%s reset with paths.", mode)
+		}
+		return resetPaths(repo, revision, paths)
+	}
+
+	return resetToCommit(repo, revision, mode)
+}
+
+// Moves the current branch to a commit, updating the index and working tree as the mode requires
+func resetToCommit(repo *repository.Repository, revision string, mode resetMode) error {
+	refsMan, err := repo.GetRefsManager()
+	if err != nil {
+		return fmt.Errorf("failed to get refs manager: %w", err)
+	}
+
+	store, err := repo.GetObjectStore()
+	if err != nil {
+		return err
+	}
+
+	idx, err := repo.GetIndex()
+	if err != nil {
+		return fmt.Errorf("failed to get index: %w", err)
+	}
+
+	oldHead, err := refsMan.ResolveHead()
+	if err != nil {
+		return fmt.Errorf("failed to get HEAD: %w", err)
+	}
+
+	target, err := resolveRevision(repo, revision)
+	if err != nil {
+		return err
+	}
+	if target == "" {
+		return fmt.Errorf("fatal: ambiguous argument 'HEAD': unknown revision or path not in the working tree")
+	}
+
+	targetCommit, err := loadCommit(store, target)
+	if err != nil {
+		return fmt.Errorf("fatal: could not parse object '%s': %w", target, err)
+	}
+
+	targetFiles, err := store.ReadTreeEntries(targetCommit.Tree)
+	if err != nil {
+		return fmt.Errorf("failed to read target tree: %w", err)
+	}
+
+	oldFiles, err := readCommitFiles(store, oldHead)
+	if err != nil {
+		return fmt.Errorf("failed to read current tree: %w", err)
+	}
+
+	if mode == resetHard {
+		if err := resetWorkingTree(repo, store, idx, oldFiles, targetFiles); err != nil {
+			return err
+		}
+	}
+
+	// A soft reset leaves the index alone, so the undone commits show up as staged
+	if mode != resetSoft {
+		if err := loadIndexFromTree(repo, targetFiles); err != nil {
+			return fmt.Errorf("failed to reset index: %w", err)
+		}
+	}
+
+	if revision == "" {
+		revision = "HEAD"
+	}
+	if err := refsMan.UpdateHead(target, oldHead, "reset: moving to "+revision); err != nil {
+		return fmt.Errorf("failed to update HEAD: %w", err)
+	}
+
+	// Resetting abandons any merge in progress
+	if err := clearMergeState(repo); err != nil {
+		return err
+	}
+
+	switch mode {
+	case resetHard:
+		subject, _, _ := strings.Cut(targetCommit.Message, "\n")
+		fmt.Printf("HEAD is now at %s %s\n", shortenHash(target), subject)
+	case resetMixed:
+		printUnstagedAfterReset(repo.GetWorkingDirectory(), targetFiles)
+	}
+
+	return nil
+}
+
+// Makes every tracked file in the working tree match the target snapshot.
+// Files that were tracked (committed or staged) but are absent from the target are deleted.
+func resetWorkingTree(repo *repository.Repository, store *objects.Store, idx *index.Index, oldFiles, targetFiles map[string]*objects.IndexEntry) error {
+	workDir := repo.GetWorkingDirectory()
+
+	tracked := make(map[string]bool)
+	for path := range oldFiles {
+		tracked[path] = true
+	}
+	for path := range idx.GetEntries() {
+		tracked[filepath.ToSlash(path)] = true
+	}
+	for path := range idx.GetConflicts() {
+		tracked[filepath.ToSlash(path)] = true
+	}
+
+	for path := range tracked {
+		if _, exists := targetFiles[path]; !exists {
+			if err := removeWorkingFile(workDir, path); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, entry := range targetFiles {
+		if err := writeWorkingFile(store, workDir, entry); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
+
+// Replaces the index with a tree snapshot.
+// Stat data is taken from working tree files whose content already matches.
+func loadIndexFromTree(repo *repository.Repository, files map[string]*objects.IndexEntry) error {
+	idx, err := repo.GetIndex()
+	if err != nil {
+		return err
+	}
+
+	workDir := repo.GetWorkingDirectory()
+	entries := make([]*index.Entry, 0, len(files))
+
+	for path, file := range files {
+		entry := &index.Entry{Path: path, Hash: file.Hash, Mode: file.Mode}
+
+		absPath := filepath.Join(workDir, filepath.FromSlash(path))
+		if info, err := os.Lstat(absPath); err == nil && !info.IsDir() {
+			if content, err := readWorkingFile(absPath); err == nil && calculateFileHash(content) == file.Hash {
+				entry.SetStat(info)
+			}
+		}
+
+		entries = append(entries, entry)
+	}
+
+	idx.Reset(entries)
+	return idx.Write()
+}
+
+// Returns the snapshot of tracked files recorded in the index
+func indexSnapshot(repo *repository.Repository) (map[string]*objects.IndexEntry, error) {
+	idx, err := repo.GetIndex()
+	if err != nil {
+		return nil, err
+	}
+
+	files := make(map[string]*objects.IndexEntry)
+	for path, entry := range idx.GetEntries() {
+		path = filepath.ToSlash(path)
+		files[path] = &objects.IndexEntry{Path: path, Hash: entry.Hash, Mode: entry.Mode}
+	}
+
+	return files, nil
+}
+
+func printUnstagedAfterReset(workDir string, files map[string]*objects.IndexEntry) {
+	var lines []string
+	for path, entry := range files {
+		content, err := readWorkingFile(filepath.Join(workDir, filepath.FromSlash(path)))
+		if err != nil {
+			lines = append(lines, "D\t"+path)
+		} else if calculateFileHash(content) != entry.Hash {
+			lines = append(lines, "M\t"+path)
+		}
+	}
+
+	if len(lines) == 0 {
+		return
+	}
+
+	sort.Slice(lines, func(i, j int) bool { return lines[i][2:] < lines[j][2:] })
+	fmt.Println("Unstaged changes after reset:")
+	for _, line := range lines {
+		fmt.Println(line)
+	}
+}
+
+// Resets the index entries for the given paths to their version in a commit
+func resetPaths(repo *repository.Repository, revision string, pathspecs []string) error {
+	refsMan, err := repo.GetRefsManager()
+	if err != nil {
+		return fmt.Errorf("failed to get refs manager: %w", err)
+	}
+
+	store, err := repo.GetObjectStore()
+	if err != nil {
+		return err
+	}
+
+	idx, err := repo.GetIndex()
+	if err != nil {
+		return fmt.Errorf("failed to get index: %w", err)
+	}
+
+	head, err := refsMan.ResolveHead()
+	if err != nil {
+		return fmt.Errorf("failed to get HEAD: %w", err)
+	}
+
+	source := head
+	if revision != "" {
+		if source, err = resolveRevision(repo, revision); err != nil {
+			return err
+		}
+	}
+
+	sourceFiles, err := readCommitFiles(store, source)
+	if err != nil {
+		return fmt.Errorf("failed to read tree: %w", err)
+	}
+
+	matched, err := expandPathspecs(repo, pathspecs, idx.GetEntries(), sourceFiles)
+	if err != nil {
+		return err
+	}
+
+	for _, path := range matched {
+		if src, inSource := sourceFiles[path]; inSource {
+			idx.SetEntry(path, src.Hash, src.Mode)
+		} else {
+			idx.RemoveEntry(path)
+		}
+	}
+
+	if err := idx.Write(); err != nil {
+		return fmt.Errorf("failed to update index: %w", err)
+	}
+	return nil
+}
+
+// Resolves command line pathspecs against the index and a tree snapshot,
+// failing for any pathspec that matches nothing in either
+func expandPathspecs(repo *repository.Repository, pathspecs []string, indexEntries map[string]*index.Entry, files map[string]*objects.IndexEntry) ([]string, error) {
+	known := make(map[string]bool)
+	for path := range indexEntries {
+		known[filepath.ToSlash(path)] = true
+	}
+	for path := range files {
+		known[path] = true
+	}
+
+	seen := make(map[string]bool)
+	var matched []string
+
+	for _, arg := range pathspecs {
+		pathspec, err := toRepoPath(repo, arg)
+		if err != nil {
+			return nil, err
+		}
+
+		found := false
+		for path := range known {
+			if !matchesPathspec(path, pathspec) {
+				continue
+			}
+			found = true
+			if !seen[path] {
+				seen[path] = true
+				matched = append(matched, path)
+			}
+		}
+
+		if !found {
+			return nil, fmt.Errorf("error: pathspec '%s' did not match any file(s) known to minigit", arg)
+		}
+	}
+
+	sort.Strings(matched)
+	return matched, nil
+}
@@ -1,8 +1,124 @@
 package cli
 
-import "fmt"
+import (
+	"fmt"
+	"minigit/internal/objects"
+	"minigit/internal/repository"
+	"strings"
+)
 
 func handleRestore(args []string) error {
-	fmt.Println("Not Implemented")
+	var source string
+	var staged, worktree bool
+	var pathspecs []string
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "--staged" || arg == "-S":
+			staged = true
+		case arg == "--worktree" || arg == "-W":
+			worktree = true
+		case arg == "--source" || arg == "-s":
+			if i+1 >= len(args) {
+				return fmt.Errorf("switch `source' requires a value")
+			}
+			i++
+			source = args[i]
+		case strings.HasPrefix(arg, "--source="):
+			source = strings.TrimPrefix(arg, "--source=")
+		case arg == "--":
+			pathspecs = append(pathspecs, args[i+1:]...)
+			i = len(args)
+		case strings.HasPrefix(arg, "-"):
+			return fmt.Errorf("unknown option: %s", arg)
+		default:
+			pathspecs = append(pathspecs, arg)
+		}
+	}
+
+	if len(pathspecs) == 0 {
+		return fmt.Errorf("fatal: you must specify path(s) to restore")
+	}
+
+	// Without --staged the working tree is the default target
+	if !staged {
+		worktree = true
+	}
+
+	repo, err := findRepository()
+	if err != nil {
+		return err
+	}
+
+	if staged {
+		if err := resetPaths(repo, source, pathspecs); err != nil {
+			return err
+		}
+	}
+
+	if worktree {
+		return restoreWorkingTree(repo, source, staged, pathspecs)
+	}
+
+	return nil
+}
+
+// Rewrites working tree files from the index, or from a commit when source is given.
+// With both --staged and --worktree the working tree is restored from HEAD by default.
+func restoreWorkingTree(repo *repository.Repository, source string, fromHead bool, pathspecs []string) error {
+	store, err := repo.GetObjectStore()
+	if err != nil {
+		return err
+	}
+
+	tracked, err := indexSnapshot(repo)
+	if err != nil {
+		return fmt.Errorf("failed to read index: %w", err)
+	}
+
+	files := tracked
+	if source != "" || fromHead {
+		commitHash, err := resolveRevision(repo, source)
+		if err != nil {
+			return err
+		}
+		if commitHash == "" {
+			return fmt.Errorf("fatal: could not resolve HEAD")
+		}
+		if files, err = readCommitFiles(store, commitHash); err != nil {
+			return fmt.Errorf("failed to read tree: %w", err)
+		}
+	}
+
+	known := make(map[string]*objects.IndexEntry)
+	for path, entry := range tracked {
+		known[path] = entry
+	}
+	for path, entry := range files {
+		known[path] = entry
+	}
+
+	matched, err := expandPathspecs(repo, pathspecs, nil, known)
+	if err != nil {
+		return err
+	}
+
+	workDir := repo.GetWorkingDirectory()
+	for _, path := range matched {
+		entry, exists := files[path]
+		if !exists {
+			// Tracked now but absent from the source
+			if err := removeWorkingFile(workDir, path); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err := writeWorkingFile(store, workDir, entry); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
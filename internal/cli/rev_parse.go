@@ -0,0 +1,195 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"minigit/internal/refs"
+	"minigit/internal/revparse"
+)
+
+type revParseOptions struct {
+	verify     bool
+	short      int    // abbreviation length, 0 for full hashes
+	symbolic   string // "full" for --symbolic-full-name, "abbrev" for --abbrev-ref
+	revisions  []string
+	printPaths []string // --git-dir and --show-toplevel, in the order given
+}
+
+func handleRevParse(args []string) error {
+	opts := &revParseOptions{}
+
+	for _, arg := range args {
+		switch {
+		case arg == "--verify":
+			opts.verify = true
+		case arg == "--short":
+			opts.short = 7
+		case strings.HasPrefix(arg, "--short="):
+			length, err := strconv.Atoi(strings.TrimPrefix(arg, "--short="))
+			if err != nil || length < 0 {
+				return fmt.Errorf("invalid length: '%s'", strings.TrimPrefix(arg, "--short="))
+			}
+			opts.short = max(length, revparse.MinAbbrev)
+		case arg == "--abbrev-ref":
+			opts.symbolic = "abbrev"
+		case arg == "--symbolic-full-name":
+			opts.symbolic = "full"
+		case arg == "--git-dir" || arg == "--show-toplevel":
+			opts.printPaths = append(opts.printPaths, arg)
+		case strings.HasPrefix(arg, "-"):
+			return fmt.Errorf("unknown option: %s", arg)
+		default:
+			opts.revisions = append(opts.revisions, arg)
+		}
+	}
+
+	if opts.verify && len(opts.revisions) != 1 {
+		return fmt.Errorf("fatal: Needed a single revision")
+	}
+
+	repo, err := findRepository()
+	if err != nil {
+		return err
+	}
+	refsMan, err := repo.GetRefsManager()
+	if err != nil {
+		return err
+	}
+	resolver, err := newResolver(repo)
+	if err != nil {
+		return err
+	}
+
+	for _, option := range opts.printPaths {
+		if option == "--show-toplevel" {
+			fmt.Println(filepath.ToSlash(repo.GetWorkingDirectory()))
+			continue
+		}
+		// Like Git, the directory is relative when run from the top of the tree
+		cwd, _ := os.Getwd()
+		if cwd == repo.GetWorkingDirectory() {
+			fmt.Println(filepath.Base(repo.GetMinigitDirectory()))
+		} else {
+			fmt.Println(filepath.ToSlash(repo.GetMinigitDirectory()))
+		}
+	}
+
+	printHash := func(hash string, prefix string) error {
+		if opts.short > 0 {
+			short, err := resolver.Abbreviate(hash, opts.short)
+			if err != nil {
+				return err
+			}
+			hash = short
+		}
+		fmt.Println(prefix + hash)
+		return nil
+	}
+
+	for _, rev := range opts.revisions {
+		if opts.symbolic != "" {
+			if name, err := symbolicName(refsMan, resolver, rev, opts.symbolic == "abbrev"); err != nil {
+				return err
+			} else if name != "" {
+				fmt.Println(name)
+			}
+			continue
+		}
+
+		// A range lists its end first, then what it leaves out
+		if from, to, symmetric, ok := revparse.SplitRange(rev); ok && !opts.verify {
+			store, err := repo.GetObjectStore()
+			if err != nil {
+				return err
+			}
+			fromHash, err := resolver.ResolveCommit(from)
+			if err != nil {
+				return err
+			}
+			toHash, err := resolver.ResolveCommit(to)
+			if err != nil {
+				return err
+			}
+
+			if err := printHash(toHash, ""); err != nil {
+				return err
+			}
+			if symmetric {
+				if err := printHash(fromHash, ""); err != nil {
+					return err
+				}
+				bases, err := mergeBases(store, fromHash, toHash)
+				if err != nil {
+					return err
+				}
+				for _, base := range bases {
+					if err := printHash(base, "^"); err != nil {
+						return err
+					}
+				}
+				continue
+			}
+			if err := printHash(fromHash, "^"); err != nil {
+				return err
+			}
+			continue
+		}
+
+		prefix := ""
+		if name, ok := strings.CutPrefix(rev, "^"); ok && !opts.verify {
+			prefix, rev = "^", name
+		}
+
+		hash, err := resolver.Resolve(rev)
+		if err != nil {
+			if opts.verify {
+				return fmt.Errorf("fatal: Needed a single revision")
+			}
+			return err
+		}
+		if err := printHash(hash, prefix); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Returns the full ref name behind a revision, or with abbrev the shortest
+// unambiguous form. Revisions that are not plain ref names have none.
+func symbolicName(refsMan *refs.Manager, resolver *revparse.Resolver, rev string, abbrev bool) (string, error) {
+	rev, err := resolver.ExpandPrevious(rev)
+	if err != nil {
+		return "", err
+	}
+
+	if rev == "HEAD" || rev == "@" {
+		branch, err := refsMan.CurrentBranch()
+		if err != nil || branch == "" {
+			return "HEAD", err
+		}
+		if abbrev {
+			return branch, nil
+		}
+		return "refs/heads/" + branch, nil
+	}
+
+	fullName, _, err := refsMan.ResolveRef(rev)
+	if err != nil {
+		return "", nil
+	}
+	if !abbrev {
+		return fullName, nil
+	}
+
+	for _, prefix := range []string{"refs/heads/", "refs/tags/", "refs/remotes/", "refs/"} {
+		if short, ok := strings.CutPrefix(fullName, prefix); ok {
+			return short, nil
+		}
+	}
+	return fullName, nil
+}
@@ -0,0 +1,135 @@
+package cli
+
+import (
+	"fmt"
+	"minigit/internal/objects"
+	"minigit/internal/repository"
+	"minigit/internal/revparse"
+)
+
+// Builds a resolver for revision expressions in the repository
+func newResolver(repo *repository.Repository) (*revparse.Resolver, error) {
+	store, err := repo.GetObjectStore()
+	if err != nil {
+		return nil, err
+	}
+	refsMan, err := repo.GetRefsManager()
+	if err != nil {
+		return nil, err
+	}
+	return revparse.New(store, refsMan), nil
+}
+
+// Resolves a revision expression to a commit hash. HEAD (or no revision) on
+// an unborn branch resolves to "".
+func resolveRevision(repo *repository.Repository, rev string) (string, error) {
+	if rev == "" || rev == "HEAD" || rev == "@" {
+		refsMan, err := repo.GetRefsManager()
+		if err != nil {
+			return "", err
+		}
+		head, err := refsMan.ResolveHead()
+		if err != nil || head == "" {
+			return head, err
+		}
+		rev = "HEAD"
+	}
+
+	resolver, err := newResolver(repo)
+	if err != nil {
+		return "", err
+	}
+	return resolver.ResolveCommit(rev)
+}
+
+// Loads and parses the commit object with the given hash
+func loadCommit(store *objects.Store, hash string) (*objects.Commit, error) {
+	obj, err := store.LoadObject(hash)
+	if err != nil {
+		return nil, err
+	}
+	if obj.Type != objects.CommitObject {
+		return nil, fmt.Errorf("object %s is a %s, not a commit", hash, obj.Type)
+	}
+
+	return store.ParseCommit(obj.Content)
+}
+
+// Reports whether ancestor is reachable from descendant
+func isAncestor(store *objects.Store, ancestor, descendant string) (bool, error) {
+	found := false
+	err := walkCommits(store, []string{descendant}, false, func(hash string, _ *objects.Commit) bool {
+		found = hash == ancestor
+		return !found
+	})
+	return found, err
+}
+
+// Returns the best common ancestor of two commits: one that is not itself an
+// ancestor of another common ancestor. Empty when the histories are unrelated.
+func mergeBase(store *objects.Store, a, b string) (string, error) {
+	bases, err := mergeBases(store, a, b)
+	if err != nil || len(bases) == 0 {
+		return "", err
+	}
+	return bases[0], nil
+}
+
+// Returns all best common ancestors of two commits, of which criss-cross
+// merges leave several, in the order they are met walking back from b
+func mergeBases(store *objects.Store, a, b string) ([]string, error) {
+	reachable := make(map[string]bool)
+	err := walkCommits(store, []string{a}, false, func(hash string, _ *objects.Commit) bool {
+		reachable[hash] = true
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Common ancestors in the order they are met walking back from b
+	var common, parents []string
+	err = walkCommits(store, []string{b}, false, func(hash string, commit *objects.Commit) bool {
+		if reachable[hash] {
+			common = append(common, hash)
+			parents = append(parents, commit.Parents...)
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	redundant := make(map[string]bool)
+	if len(parents) > 0 {
+		err = walkCommits(store, parents, false, func(hash string, _ *objects.Commit) bool {
+			redundant[hash] = true
+			return true
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var bases []string
+	for _, hash := range common {
+		if !redundant[hash] {
+			bases = append(bases, hash)
+		}
+	}
+	return bases, nil
+}
+
+// Returns the files recorded in a commit's tree, empty for an unborn branch
+func readCommitFiles(store *objects.Store, commitHash string) (map[string]*objects.IndexEntry, error) {
+	if commitHash == "" {
+		return make(map[string]*objects.IndexEntry), nil
+	}
+
+	commit, err := loadCommit(store, commitHash)
+	if err != nil {
+		return nil, err
+	}
+
+	return store.ReadTreeEntries(commit.Tree)
+}
@@ -13,15 +13,38 @@ type Command struct {
 }
 
 var commands = map[string]Command{
-	"init":     {"init", "Initialize a new repository", handleInit},
-	"add":      {"add", "Add files to staging area", handleAdd},
-	"commit":   {"commit", "Create a new commit", handleCommit},
-	"status":   {"status", "Show repository status", handleStatus},
-	"log":      {"log", "Show commit history", handleLog},
-	"branch":   {"branch", "List or create branch", handleBranch},
-	"checkout": {"checkout", "Switch branches or restore files", handleCheckout},
-	"reset":    {"reset", "Reset current HEAD to the specified state", handleReset},
-	"restore":  {"restore", "Restore working tree files", handleRestore},
+	"init":         {"init", "Initialize a new repository", handleInit},
+	"add":          {"add", "Add files to staging area", handleAdd},
+	"commit":       {"commit", "Create a new commit", handleCommit},
+	"status":       {"status", "Show repository status", handleStatus},
+	"log":          {"log", "Show commit history", handleLog},
+	"branch":       {"branch", "List or create branch", handleBranch},
+	"checkout":     {"checkout", "Switch branches or restore files", handleCheckout},
+	"reset":        {"reset", "Reset current HEAD to the specified state", handleReset},
+	"restore":      {"restore", "Restore working tree files", handleRestore},
+	"diff":         {"diff", "Show changes between commits, index and working tree", handleDiff},
+	"merge":        {"merge", "Join two development histories together", handleMerge},
+	"config":       {"config", "Get and set repository or global options", handleConfig},
+	"check-ignore": {"check-ignore", "Debug .minigitignore files", handleCheckIgnore},
+	"repack":       {"repack", "Pack loose objects into a packfile", handleRepack},
+	"gc":           {"gc", "Pack all objects and remove redundant copies", handleGC},
+	"cat-file":     {"cat-file", "Show the type, size or content of objects", handleCatFile},
+	"hash-object":  {"hash-object", "Compute object IDs and optionally store objects", handleHashObject},
+	"ls-tree":      {"ls-tree", "List the contents of a tree object", handleLsTree},
+	"ls-files":     {"ls-files", "Show information about files in the index and working tree", handleLsFiles},
+	"write-tree":   {"write-tree", "Create a tree object from the index", handleWriteTree},
+	"commit-tree":  {"commit-tree", "Create a commit object from a tree", handleCommitTree},
+	"rev-parse":    {"rev-parse", "Resolve revision expressions to object names", handleRevParse},
+	"fsck":         {"fsck", "Verify the connectivity and validity of objects", handleFsck},
+	"reflog":       {"reflog", "Show the history of a ref's values", handleReflog},
+	"tag":          {"tag", "Create, list or delete tags", handleTag},
+}
+
+// Exit status to end with, without printing an error
+type ExitCode int
+
+func (code ExitCode) Error() string {
+	return fmt.Sprintf("exit status %d", int(code))
 }
 
 func Execute() error {
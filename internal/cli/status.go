@@ -2,10 +2,12 @@ package cli
 
 import (
 	"fmt"
+	"minigit/internal/index"
 	"minigit/internal/objects"
+	"minigit/internal/parallel"
 	"minigit/internal/repository"
 	"os"
-	"path/filepath"
+	"sort"
 	"strings"
 )
 
@@ -35,12 +37,32 @@ func handleStatus(args []string) error {
 
 	fmt.Printf("On branch %s\n", branchName)
 
-	stagedFiles := index.GetEntries()
+	mergeHead, err := readMergeHead(repo)
+	if err != nil {
+		return err
+	}
+
+	conflicts := index.GetConflicts()
+	if len(conflicts) > 0 {
+		fmt.Println("You have unmerged paths.")
+		fmt.Println("  (fix conflicts and run \"./mygit commit\")")
+		fmt.Println("  (use \"./mygit merge --abort\" to abort the merge)")
+		fmt.Println()
+	} else if mergeHead != "" {
+		fmt.Println("All conflicts fixed but you are still merging.")
+		fmt.Println("  (use \"./mygit commit\" to conclude merge)")
+		fmt.Println()
+	}
+
+	indexEntries := index.GetEntries()
 
-	workingFiles, err := getWorkdingDirectory(repo.GetWorkingDirectory())
+	workingFiles, err := getWorkdingDirectory(repo)
 	if err != nil {
 		return fmt.Errorf("failed to scan working directory: %w", err)
 	}
+	// Saving refreshed stat data only spares later commands some hashing, so
+	// a failure (another process holding the lock) is ignored like Git does
+	index.Write()
 
 	// get last commit files for comparision
 	lastCommitFiles, err := getLastCommitFiles(repo)
@@ -49,55 +71,81 @@ func handleStatus(args []string) error {
 	}
 
 	// categorize
-	var stagedForCommit,
-		modifiedFiles,
-		untrackedFiles []string
-
-	for path := range stagedFiles {
-		stagedForCommit = append(stagedForCommit, path)
+	var stagedChanges,
+		unstagedChanges []string
+	var untrackedFiles []string
+
+	// staged: index vs HEAD
+	for path, entry := range indexEntries {
+		if last, existsInLastCommit := lastCommitFiles[path]; !existsInLastCommit {
+			stagedChanges = append(stagedChanges, formatStatusLine("new file", path))
+		} else if entry.Hash != last.Hash || entry.Mode != last.Mode {
+			stagedChanges = append(stagedChanges, formatStatusLine("modified", path))
+		}
+	}
+	for path := range lastCommitFiles {
+		if _, isUnmerged := conflicts[path]; isUnmerged {
+			continue
+		}
+		if _, isTracked := indexEntries[path]; !isTracked {
+			stagedChanges = append(stagedChanges, formatStatusLine("deleted", path))
+		}
 	}
 
-	// check workDir files
-	for path, currHash := range workingFiles {
-		if _, isStaged := stagedFiles[path]; isStaged {
-			continue // already handled as staged
+	// unstaged: working directory vs index
+	for path, entry := range indexEntries {
+		if curr, exists := workingFiles[path]; !exists {
+			unstagedChanges = append(unstagedChanges, formatStatusLine("deleted", path))
+		} else if curr.Hash != entry.Hash || curr.Mode != entry.Mode {
+			unstagedChanges = append(unstagedChanges, formatStatusLine("modified", path))
 		}
+	}
 
-		if lastCommitHash, existsInLastCommit := lastCommitFiles[path]; existsInLastCommit {
-			if currHash != lastCommitHash {
-				modifiedFiles = append(modifiedFiles, path)
-			}
-		} else {
+	for path := range workingFiles {
+		if _, isUnmerged := conflicts[path]; isUnmerged {
+			continue
+		}
+		if _, isTracked := indexEntries[path]; !isTracked {
 			untrackedFiles = append(untrackedFiles, path)
 		}
 	}
 
-	if len(stagedForCommit) == 0 && len(modifiedFiles) == 0 && len(untrackedFiles) == 0 {
+	sort.Slice(stagedChanges, func(i, j int) bool { return stagedChanges[i][12:] < stagedChanges[j][12:] })
+	sort.Slice(unstagedChanges, func(i, j int) bool { return unstagedChanges[i][12:] < unstagedChanges[j][12:] })
+	sort.Strings(untrackedFiles)
+
+	if len(stagedChanges) == 0 && len(unstagedChanges) == 0 && len(untrackedFiles) == 0 && len(conflicts) == 0 {
 		fmt.Println("nothing to commit, working tree clean")
 		return nil
 	}
 
-	if len(stagedForCommit) > 0 {
+	if len(stagedChanges) > 0 {
 		fmt.Println("Changes to be committed:")
 		fmt.Println("  (use \"./mygit restore --staged <file>...\" to unstage)")
 
-		for _, file := range stagedForCommit {
-			if _, existsInLastCommit := lastCommitFiles[file]; existsInLastCommit {
-				fmt.Printf("\tmodified:   %s\n", file)
-			} else {
-				fmt.Printf("\tnew file:   %s\n", file)
-			}
+		for _, line := range stagedChanges {
+			fmt.Printf("\t%s\n", line)
+		}
+		fmt.Println()
+	}
+
+	if len(conflicts) > 0 {
+		fmt.Println("Unmerged paths:")
+		fmt.Println("  (use \"./mygit add <file>...\" to mark resolution)")
+
+		for _, path := range index.ConflictPaths() {
+			fmt.Printf("\t%s\n", fmt.Sprintf("%-17s%s", unmergedLabel(conflicts[path])+":", path))
 		}
 		fmt.Println()
 	}
 
-	if len(modifiedFiles) > 0 {
+	if len(unstagedChanges) > 0 {
 		fmt.Println("Changes not staged for commit:")
 		fmt.Println("  (use \"./mygit add <file>...\" to update what will be committed)")
-		fmt.Println("  (use \"./mygit checkout -- <file>...\" to discard changes in working directory)")
+		fmt.Println("  (use \"./mygit restore <file>...\" to discard changes in working directory)")
 
-		for _, file := range modifiedFiles {
-			fmt.Printf("\tmodified:   %s\n", file)
+		for _, line := range unstagedChanges {
+			fmt.Printf("\t%s\n", line)
 		}
 		fmt.Println()
 	}
@@ -115,36 +163,106 @@ func handleStatus(args []string) error {
 	return nil
 }
 
-func getWorkdingDirectory(workDir string) (map[string]string, error) {
-	files := make(map[string]string)
+// Formats a change as "label:   path" with the label padded like Git does
+func formatStatusLine(label, path string) string {
+	return fmt.Sprintf("%-12s%s", label+":", path)
+}
 
-	err := filepath.Walk(workDir, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
+// Describes an unmerged path by which of its base/ours/theirs stages exist
+func unmergedLabel(stages []*index.Entry) string {
+	var present [4]bool
+	for _, entry := range stages {
+		present[entry.Stage] = true
+	}
+
+	switch {
+	case !present[2]:
+		return "deleted by us"
+	case !present[3]:
+		return "deleted by them"
+	case !present[1]:
+		return "both added"
+	default:
+		return "both modified"
+	}
+}
+
+// Hashes every tracked file in the working tree, trusting the index's stat
+// data where it shows a file unchanged, and gives its mode as core.filemode
+// sees it. Untracked files, except ignored ones, are listed without a hash
+// as only their presence matters.
+func getWorkdingDirectory(repo *repository.Repository) (map[string]*objects.IndexEntry, error) {
+	idx, err := repo.GetIndex()
+	if err != nil {
+		return nil, err
+	}
+
+	files := make(map[string]*objects.IndexEntry)
+	var tracked []workingFile
+	err = walkWorkingTree(repo, repo.GetWorkingDirectory(), false, func(absPath, relPath string, info os.FileInfo) error {
+		if _, isTracked := idx.GetEntry(relPath); isTracked {
+			tracked = append(tracked, workingFile{absPath: absPath, relPath: relPath, info: info})
+		} else {
+			files[relPath] = &objects.IndexEntry{Path: relPath}
 		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
 
-		// skip .minigit
-		if info.IsDir() || strings.Contains(path, ".minigit") || strings.Contains(path, ".git") {
-			return nil
+	hashes, err := hashWorkingFiles(idx, tracked)
+	if err != nil {
+		return nil, err
+	}
+	for i, file := range tracked {
+		entry, _ := idx.GetEntry(file.relPath)
+		files[file.relPath] = &objects.IndexEntry{
+			Path: file.relPath,
+			Hash: hashes[i],
+			Mode: idx.FileMode(entry, file.info),
 		}
+	}
 
-		relPath, err := filepath.Rel(workDir, path)
-		if err != nil {
-			return err
+	return files, nil
+}
+
+// A file in the working tree, with its path relative to the top
+type workingFile struct {
+	absPath string
+	relPath string
+	info    os.FileInfo
+}
+
+// Returns the blob hashes of tracked files, computed on the worker pool. The
+// index entry's hash is reused when a file's stat data shows it unchanged;
+// entries whose files only turn out unchanged after rehashing get their stat
+// data refreshed.
+func hashWorkingFiles(idx *index.Index, files []workingFile) ([]string, error) {
+	hashes, err := parallel.Map(files, func(file workingFile) (string, error) {
+		if entry, tracked := idx.GetEntry(file.relPath); tracked && idx.IsUpToDate(entry, file.info) {
+			return entry.Hash, nil
 		}
 
-		content, err := os.ReadFile(path)
+		content, err := readWorkingFile(file.absPath)
 		if err != nil {
-			return err
+			return "", err
 		}
-
-		hash := calculateFileHash(content)
-		files[relPath] = hash
-
-		return nil
+		return calculateFileHash(content), nil
 	})
+	if err != nil {
+		return nil, err
+	}
+
+	// The index itself is only updated from this goroutine
+	for i, file := range files {
+		entry, tracked := idx.GetEntry(file.relPath)
+		if tracked && hashes[i] == entry.Hash && idx.FileMode(entry, file.info) == entry.Mode && !idx.IsUpToDate(entry, file.info) {
+			idx.RefreshEntry(file.relPath, file.info)
+		}
+	}
 
-	return files, err
+	return hashes, nil
 }
 
 func calculateFileHash(content []byte) string {
@@ -152,7 +270,8 @@ func calculateFileHash(content []byte) string {
 	return store.HashContent(objects.BlobObject, content)
 }
 
-func getLastCommitFiles(repo *repository.Repository) (map[string]string, error) {
+// Returns the files of the commit HEAD points to, empty on an unborn branch
+func getLastCommitFiles(repo *repository.Repository) (map[string]*objects.IndexEntry, error) {
 	refsMan, err := repo.GetRefsManager()
 	if err != nil {
 		return nil, err
@@ -169,14 +288,14 @@ func getLastCommitFiles(repo *repository.Repository) (map[string]string, error)
 		if commit, err := refsMan.GetBranch(branchName); err == nil {
 			lastCommitHash = commit
 		} else {
-			return make(map[string]string), nil // first commit
+			return make(map[string]*objects.IndexEntry), nil // first commit
 		}
 	} else {
 		lastCommitHash = headRef
 	}
 
 	if lastCommitHash == "" {
-		return make(map[string]string), nil
+		return make(map[string]*objects.IndexEntry), nil
 	}
 
 	store, err := repo.GetObjectStore()
@@ -194,43 +313,7 @@ func getLastCommitFiles(repo *repository.Repository) (map[string]string, error)
 		return nil, err
 	}
 
-	files := make(map[string]string)
-	err = getFilesFromTree(store, commit.Tree, "", files)
-	return files, err
-}
-
-func getFilesFromTree(store *objects.Store, treeHash, basePath string, files map[string]string) error {
-	if treeHash == "" {
-		return nil
-	}
-
-	treeObj, err := store.LoadObject(treeHash)
-	if err != nil {
-		return err
-	}
-
-	tree, err := store.ParseTree(treeObj.Content)
-	if err != nil {
-		return err
-	}
-
-	for _, entry := range tree.Entries {
-		fullPath := entry.Name
-		if basePath != "" {
-			fullPath = filepath.Join(basePath, entry.Name)
-		}
-
-		if entry.Type == objects.BlobObject {
-			files[fullPath] = entry.Hash
-		} else if entry.Type == objects.TreeObject {
-			// recurse
-			if err := getFilesFromTree(store, entry.Hash, fullPath, files); err != nil {
-				return err
-			}
-		}
-	}
-
-	return nil
+	return store.ReadTreeEntries(commit.Tree)
 }
 
 func isFirstCommit(err error) bool {
@@ -0,0 +1,264 @@
+package cli
+
+import (
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+
+	"minigit/internal/objects"
+	"minigit/internal/refs"
+	"minigit/internal/repository"
+)
+
+type tagOptions struct {
+	list     bool
+	delete   bool
+	annotate bool
+	messages []string
+	lines    int // annotation lines shown per tag when listing, 0 for names only
+}
+
+func handleTag(args []string) error {
+	opts := &tagOptions{}
+	var names []string
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "-l" || arg == "--list":
+			opts.list = true
+		case arg == "-d" || arg == "--delete":
+			opts.delete = true
+		case arg == "-a" || arg == "--annotate":
+			opts.annotate = true
+		case arg == "-m":
+			if i+1 >= len(args) {
+				return fmt.Errorf("switch `m' requires a value")
+			}
+			i++
+			opts.messages = append(opts.messages, args[i])
+		case strings.HasPrefix(arg, "--message="):
+			opts.messages = append(opts.messages, strings.TrimPrefix(arg, "--message="))
+		case strings.HasPrefix(arg, "-n"):
+			// -n alone shows the first line, -n<num> up to num lines
+			opts.lines = 1
+			if num := strings.TrimPrefix(arg, "-n"); num != "" {
+				lines, err := strconv.Atoi(num)
+				if err != nil || lines < 0 {
+					return fmt.Errorf("error: switch `n' expects a numerical value")
+				}
+				opts.lines = lines
+			}
+			opts.list = true
+		case strings.HasPrefix(arg, "-"):
+			return fmt.Errorf("unknown option: %s", arg)
+		default:
+			names = append(names, arg)
+		}
+	}
+
+	if opts.delete && (opts.list || opts.annotate || len(opts.messages) > 0) {
+		return fmt.Errorf("fatal: option '-d' cannot be used with other modes")
+	}
+	// A message makes the tag annotated
+	if len(opts.messages) > 0 {
+		opts.annotate = true
+	}
+	if opts.list && opts.annotate {
+		return fmt.Errorf("fatal: -a and -m options are only allowed with creating a tag")
+	}
+
+	repo, err := findRepository()
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case opts.delete:
+		return deleteTags(repo, names)
+	case opts.list || len(names) == 0:
+		if opts.annotate {
+			return fmt.Errorf("usage: tag [-a] [-m <msg>] <tagname> [<commit>]")
+		}
+		return listTags(repo, names, opts.lines)
+	case len(names) > 2:
+		return fmt.Errorf("too many arguments: usage: tag [-a] [-m <msg>] <tagname> [<commit>]")
+	}
+
+	target := "HEAD"
+	if len(names) == 2 {
+		target = names[1]
+	}
+	return createTag(repo, names[0], target, opts)
+}
+
+// Prints the tags matching any of patterns (all of them without patterns),
+// each followed by up to lines lines of its annotation
+func listTags(repo *repository.Repository, patterns []string, lines int) error {
+	refsMan, err := repo.GetRefsManager()
+	if err != nil {
+		return fmt.Errorf("failed to get refs manager: %w", err)
+	}
+	store, err := repo.GetObjectStore()
+	if err != nil {
+		return err
+	}
+
+	tags, err := refsMan.ListTags()
+	if err != nil {
+		return fmt.Errorf("failed to list tags: %w", err)
+	}
+
+	for _, tag := range tags {
+		if !matchesAnyPattern(tag, patterns) {
+			continue
+		}
+		if lines == 0 {
+			fmt.Println(tag)
+			continue
+		}
+
+		hash, err := refsMan.GetTag(tag)
+		if err != nil {
+			return fmt.Errorf("failed to read tag '%s': %w", tag, err)
+		}
+		annotation := strings.Split(tagAnnotation(store, hash), "\n")
+		if len(annotation) > lines {
+			annotation = annotation[:lines]
+		}
+
+		fmt.Printf("%-15s %s\n", tag, annotation[0])
+		for _, line := range annotation[1:] {
+			fmt.Printf("    %s\n", line)
+		}
+	}
+
+	return nil
+}
+
+func matchesAnyPattern(name string, patterns []string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, pattern := range patterns {
+		if matched, _ := path.Match(pattern, name); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns the message of an annotated tag or, for a lightweight tag, of the
+// commit it names; other objects have no annotation
+func tagAnnotation(store *objects.Store, hash string) string {
+	obj, err := store.LoadObject(hash)
+	if err != nil {
+		return ""
+	}
+
+	switch obj.Type {
+	case objects.TagObject:
+		if tag, err := store.ParseTag(obj.Content); err == nil {
+			return tag.Message
+		}
+	case objects.CommitObject:
+		if commit, err := store.ParseCommit(obj.Content); err == nil {
+			return commit.Message
+		}
+	}
+	return ""
+}
+
+// Points refs/tags/<name> at target, through a new tag object when annotating
+func createTag(repo *repository.Repository, name, target string, opts *tagOptions) error {
+	if !refs.ValidTagName(name) {
+		return fmt.Errorf("fatal: '%s' is not a valid tag name.", name)
+	}
+
+	refsMan, err := repo.GetRefsManager()
+	if err != nil {
+		return fmt.Errorf("failed to get refs manager: %w", err)
+	}
+	if refsMan.TagExists(name) {
+		return fmt.Errorf("fatal: tag '%s' already exists", name)
+	}
+
+	store, err := repo.GetObjectStore()
+	if err != nil {
+		return err
+	}
+
+	// Any object can be tagged, not only commits
+	hash, err := resolveObjectName(repo, target)
+	if err != nil {
+		return fmt.Errorf("fatal: Failed to resolve '%s' as a valid ref.", target)
+	}
+
+	if opts.annotate {
+		if len(opts.messages) == 0 {
+			return fmt.Errorf("interactive tag (opening editor) not implemented. please use `-m` to pass a message")
+		}
+
+		obj, err := store.LoadObject(hash)
+		if err != nil {
+			return fmt.Errorf("fatal: Failed to resolve '%s' as a valid ref.", target)
+		}
+		tagger, err := signature(repo, "COMMITTER")
+		if err != nil {
+			return err
+		}
+
+		// Each -m is a paragraph of its own
+		hash, err = store.WriteTag(&objects.Tag{
+			Object:  hash,
+			Type:    obj.Type,
+			Name:    name,
+			Tagger:  &tagger,
+			Message: strings.TrimRight(strings.Join(opts.messages, "\n\n"), "\n"),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to write tag: %w", err)
+		}
+	}
+
+	if err := refsMan.UpdateRef("refs/tags/"+name, hash, refs.ZeroHash, ""); err != nil {
+		return fmt.Errorf("failed to create tag: %w", err)
+	}
+	return nil
+}
+
+// Deletes the named tags, reporting those that don't exist and carrying on
+func deleteTags(repo *repository.Repository, names []string) error {
+	if len(names) == 0 {
+		return fmt.Errorf("fatal: tag name required")
+	}
+
+	refsMan, err := repo.GetRefsManager()
+	if err != nil {
+		return fmt.Errorf("failed to get refs manager: %w", err)
+	}
+
+	failed := false
+	for _, name := range names {
+		if !refsMan.TagExists(name) {
+			fmt.Printf("error: tag '%s' not found.\n", name)
+			failed = true
+			continue
+		}
+
+		hash, err := refsMan.GetTag(name)
+		if err != nil {
+			return fmt.Errorf("failed to read tag '%s': %w", name, err)
+		}
+		if err := refsMan.DeleteTag(name); err != nil {
+			return fmt.Errorf("failed to delete tag '%s': %w", name, err)
+		}
+		fmt.Printf("Deleted tag '%s' (was %s)\n", name, shortenHash(hash))
+	}
+
+	if failed {
+		return ExitCode(1)
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package cli
+
+import (
+	"fmt"
+
+	"minigit/internal/objects"
+	"minigit/internal/repository"
+)
+
+func handleWriteTree(args []string) error {
+	if len(args) > 0 {
+		return fmt.Errorf("usage: write-tree")
+	}
+
+	repo, err := findRepository()
+	if err != nil {
+		return err
+	}
+
+	hash, err := writeIndexTree(repo)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(hash)
+	return nil
+}
+
+// Stores the index as a tree and returns its hash. Unmerged paths can't be
+// written; an empty index gives the empty tree.
+func writeIndexTree(repo *repository.Repository) (string, error) {
+	idx, err := repo.GetIndex()
+	if err != nil {
+		return "", fmt.Errorf("failed to get index: %w", err)
+	}
+	store, err := repo.GetObjectStore()
+	if err != nil {
+		return "", err
+	}
+
+	if idx.HasConflicts() {
+		var msg string
+		for _, path := range idx.ConflictPaths() {
+			msg += fmt.Sprintf("%s: unmerged\n", path)
+		}
+		return "", fmt.Errorf("%sfatal: write-tree: error building trees", msg)
+	}
+
+	files, err := indexSnapshot(repo)
+	if err != nil {
+		return "", fmt.Errorf("failed to read index: %w", err)
+	}
+	if len(files) == 0 {
+		return store.StoreObject(objects.TreeObject, nil)
+	}
+
+	hash, err := store.CreateTreeFromIndex(files)
+	if err != nil {
+		return "", fmt.Errorf("failed to create tree: %w", err)
+	}
+	return hash, nil
+}
@@ -1,7 +1,188 @@
+// Repository and user configuration
 package config
 
-type Config struct{}
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
 
+// Which config file a setting is read from or written to
+type Scope int
+
+const (
+	ScopeLocal  Scope = iota // .minigit/config
+	ScopeGlobal              // ~/.minigitconfig
+)
+
+func (s Scope) String() string {
+	if s == ScopeGlobal {
+		return "global"
+	}
+	return "local"
+}
+
+// A key/value pair as listed by `config --list`
+type Entry struct {
+	Key   string
+	Value string
+	Scope Scope
+}
+
+// Merged view of the user-level and repository-level config files.
+// Repository settings take precedence over user settings.
+type Config struct {
+	local  *File // nil outside a repository
+	global *File
+}
+
+// Loads the config for the repository in minigitDir, or only the
+// user-level config when minigitDir is empty
 func NewConfig(minigitDir string) (*Config, error) {
-	return nil, nil
+	cfg := &Config{}
+
+	globalPath, err := GlobalPath()
+	if err != nil {
+		return nil, err
+	}
+	if cfg.global, err = LoadFile(globalPath); err != nil {
+		return nil, err
+	}
+
+	if minigitDir != "" {
+		if cfg.local, err = LoadFile(filepath.Join(minigitDir, "config")); err != nil {
+			return nil, err
+		}
+	}
+
+	return cfg, nil
+}
+
+// Location of the user-level config file
+func GlobalPath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to locate home directory: %w", err)
+	}
+	return filepath.Join(home, ".minigitconfig"), nil
+}
+
+// Returns the effective value of a key
+func (c *Config) Get(key string) (string, bool) {
+	if c.local != nil {
+		if value, found := c.local.Get(key); found {
+			return value, true
+		}
+	}
+	return c.global.Get(key)
+}
+
+// Returns the value of a key as set in one file only
+func (c *Config) GetScoped(scope Scope, key string) (string, bool) {
+	file, err := c.file(scope)
+	if err != nil {
+		return "", false
+	}
+	return file.Get(key)
+}
+
+// Returns the value of a key, or fallback when it is not set
+func (c *Config) GetString(key, fallback string) string {
+	if value, found := c.Get(key); found {
+		return value
+	}
+	return fallback
+}
+
+// Reads a boolean setting (true/yes/on/1 or false/no/off/0)
+func (c *Config) GetBool(key string, fallback bool) (bool, error) {
+	value, found := c.Get(key)
+	if !found {
+		return fallback, nil
+	}
+
+	switch strings.ToLower(value) {
+	case "true", "yes", "on", "1":
+		return true, nil
+	case "false", "no", "off", "0", "":
+		return false, nil
+	}
+	return false, fmt.Errorf("fatal: bad boolean config value '%s' for '%s'", value, key)
+}
+
+// Reads an integer setting, allowing a k, m or g suffix
+func (c *Config) GetInt(key string, fallback int) (int, error) {
+	value, found := c.Get(key)
+	if !found {
+		return fallback, nil
+	}
+
+	multiplier := 1
+	number := value
+	if n := len(value); n > 0 {
+		switch value[n-1] {
+		case 'k', 'K':
+			multiplier, number = 1<<10, value[:n-1]
+		case 'm', 'M':
+			multiplier, number = 1<<20, value[:n-1]
+		case 'g', 'G':
+			multiplier, number = 1<<30, value[:n-1]
+		}
+	}
+
+	n, err := strconv.Atoi(number)
+	if err != nil {
+		return 0, fmt.Errorf("fatal: bad numeric config value '%s' for '%s'", value, key)
+	}
+	return n * multiplier, nil
+}
+
+// Sets a key in the given file
+func (c *Config) Set(scope Scope, key, value string) error {
+	file, err := c.file(scope)
+	if err != nil {
+		return err
+	}
+	return file.Set(key, value)
+}
+
+// Removes a key from the given file, reporting whether it was set
+func (c *Config) Unset(scope Scope, key string) (bool, error) {
+	file, err := c.file(scope)
+	if err != nil {
+		return false, err
+	}
+	return file.Unset(key)
+}
+
+// Returns every setting in the given files, user-level ones first
+func (c *Config) Entries(scopes ...Scope) []Entry {
+	if len(scopes) == 0 {
+		scopes = []Scope{ScopeGlobal, ScopeLocal}
+	}
+
+	var entries []Entry
+	for _, scope := range scopes {
+		file, err := c.file(scope)
+		if err != nil {
+			continue
+		}
+		for _, entry := range file.Entries() {
+			entry.Scope = scope
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+func (c *Config) file(scope Scope) (*File, error) {
+	if scope == ScopeGlobal {
+		return c.global, nil
+	}
+	if c.local == nil {
+		return nil, fmt.Errorf("fatal: --local can only be used inside a minigit repository")
+	}
+	return c.local, nil
 }
@@ -0,0 +1,351 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"minigit/internal/lockfile"
+)
+
+// A single INI-style config file. Lines are kept as written so that
+// comments and layout survive when a value is changed.
+type File struct {
+	path  string
+	lines []*line
+}
+
+type line struct {
+	raw string
+	// Canonical section ("user", "branch.main") the line belongs to
+	section string
+	// Canonical variable name, empty for headers, comments and blank lines
+	name  string
+	value string
+}
+
+// Reads a config file; a missing file is treated as empty
+func LoadFile(path string) (*File, error) {
+	file := &File{path: path}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return file, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	section := ""
+	for i, raw := range strings.Split(strings.TrimSuffix(string(data), "\n"), "\n") {
+		parsed, err := parseLine(raw, section)
+		if err != nil {
+			return nil, fmt.Errorf("fatal: bad config line %d in file %s", i+1, path)
+		}
+		section = parsed.section
+		file.lines = append(file.lines, parsed)
+	}
+
+	return file, nil
+}
+
+// Returns the last value set for a key, which wins over earlier ones
+func (f *File) Get(key string) (string, bool) {
+	section, name, err := parseKey(key)
+	if err != nil {
+		return "", false
+	}
+
+	value, found := "", false
+	for _, line := range f.lines {
+		if line.name == name && line.section == section {
+			value, found = line.value, true
+		}
+	}
+	return value, found
+}
+
+// Sets a key, replacing its last occurrence or adding it to the end of its section
+func (f *File) Set(key, value string) error {
+	section, name, err := parseKey(key)
+	if err != nil {
+		return err
+	}
+
+	formatted := &line{
+		raw:     fmt.Sprintf("\t%s = %s", name, formatValue(value)),
+		section: section,
+		name:    name,
+		value:   value,
+	}
+
+	last, sectionEnd := -1, -1
+	for i, line := range f.lines {
+		if line.section != section {
+			continue
+		}
+		sectionEnd = i
+		if line.name == name {
+			last = i
+		}
+	}
+
+	switch {
+	case last >= 0:
+		f.lines[last] = formatted
+	case sectionEnd >= 0:
+		f.lines = append(f.lines[:sectionEnd+1], append([]*line{formatted}, f.lines[sectionEnd+1:]...)...)
+	default:
+		f.lines = append(f.lines, &line{raw: formatHeader(section), section: section}, formatted)
+	}
+
+	return f.save()
+}
+
+// Removes every occurrence of a key, reporting whether there was any
+func (f *File) Unset(key string) (bool, error) {
+	section, name, err := parseKey(key)
+	if err != nil {
+		return false, err
+	}
+
+	kept := f.lines[:0]
+	removed := false
+	for _, line := range f.lines {
+		if line.name == name && line.section == section {
+			removed = true
+			continue
+		}
+		kept = append(kept, line)
+	}
+	f.lines = kept
+
+	if !removed {
+		return false, nil
+	}
+	return true, f.save()
+}
+
+// Returns every variable in file order as canonical key/value pairs
+func (f *File) Entries() []Entry {
+	var entries []Entry
+	for _, line := range f.lines {
+		if line.name != "" {
+			entries = append(entries, Entry{Key: line.section + "." + line.name, Value: line.value})
+		}
+	}
+	return entries
+}
+
+func (f *File) save() error {
+	var content strings.Builder
+	for _, line := range f.lines {
+		content.WriteString(line.raw)
+		content.WriteString("\n")
+	}
+
+	// 0644 ~ Owner can read and write, group and others can only read.
+	return lockfile.WriteFile(f.path, []byte(content.String()), 0644)
+}
+
+// Parses one line of a config file, given the section it appears in
+func parseLine(raw, section string) (*line, error) {
+	parsed := &line{raw: raw, section: section}
+
+	text := strings.TrimSpace(raw)
+	if text == "" || text[0] == '#' || text[0] == ';' {
+		return parsed, nil
+	}
+
+	if text[0] == '[' {
+		header, err := parseHeader(text)
+		if err != nil {
+			return nil, err
+		}
+		parsed.section = header
+		return parsed, nil
+	}
+
+	if section == "" {
+		return nil, fmt.Errorf("variable outside of a section")
+	}
+
+	name, rawValue, hasValue := strings.Cut(text, "=")
+	name = strings.TrimSpace(name)
+	if !validName(name) {
+		return nil, fmt.Errorf("invalid variable name %q", name)
+	}
+	parsed.name = strings.ToLower(name)
+
+	// A bare variable name is shorthand for a true boolean
+	if !hasValue {
+		parsed.value = "true"
+		return parsed, nil
+	}
+
+	value, err := parseValue(rawValue)
+	if err != nil {
+		return nil, err
+	}
+	parsed.value = value
+
+	return parsed, nil
+}
+
+// Parses [section], [section "subsection"] or the older [section.subsection]
+func parseHeader(text string) (string, error) {
+	end := strings.LastIndexByte(text, ']')
+	if end < 0 {
+		return "", fmt.Errorf("unterminated section header")
+	}
+	if rest := strings.TrimSpace(text[end+1:]); rest != "" && rest[0] != '#' && rest[0] != ';' {
+		return "", fmt.Errorf("unexpected text after section header")
+	}
+
+	inner := text[1:end]
+	name, subsection, quoted := strings.Cut(inner, " ")
+	if !quoted {
+		name, subsection, _ = strings.Cut(inner, ".")
+		if !validSection(name) {
+			return "", fmt.Errorf("invalid section name %q", name)
+		}
+		if subsection == "" {
+			return strings.ToLower(name), nil
+		}
+		return strings.ToLower(name) + "." + strings.ToLower(subsection), nil
+	}
+
+	if !validSection(name) {
+		return "", fmt.Errorf("invalid section name %q", name)
+	}
+
+	subsection = strings.TrimSpace(subsection)
+	if len(subsection) < 2 || subsection[0] != '"' || subsection[len(subsection)-1] != '"' {
+		return "", fmt.Errorf("invalid subsection %q", subsection)
+	}
+	subsection = strings.NewReplacer(`\"`, `"`, `\\`, `\`).Replace(subsection[1 : len(subsection)-1])
+
+	return strings.ToLower(name) + "." + subsection, nil
+}
+
+// Unquotes a raw value, dropping comments and surrounding whitespace
+func parseValue(raw string) (string, error) {
+	var value, space strings.Builder
+	inQuote := false
+
+	write := func(c byte) {
+		if value.Len() > 0 {
+			value.WriteString(space.String())
+		}
+		space.Reset()
+		value.WriteByte(c)
+	}
+
+	for i := 0; i < len(raw); i++ {
+		c := raw[i]
+		switch {
+		case c == '\\':
+			i++
+			if i == len(raw) {
+				return "", fmt.Errorf("trailing backslash")
+			}
+			switch raw[i] {
+			case 'n':
+				write('\n')
+			case 't':
+				write('\t')
+			case 'b':
+				write('\b')
+			case '"', '\\':
+				write(raw[i])
+			default:
+				return "", fmt.Errorf("invalid escape sequence")
+			}
+		case c == '"':
+			inQuote = !inQuote
+		case inQuote:
+			write(c)
+		case c == '#' || c == ';':
+			i = len(raw)
+		case c == ' ' || c == '\t':
+			space.WriteByte(c)
+		default:
+			write(c)
+		}
+	}
+
+	if inQuote {
+		return "", fmt.Errorf("unterminated quote")
+	}
+	return value.String(), nil
+}
+
+// Quotes and escapes a value so that parseValue reads it back unchanged
+func formatValue(value string) string {
+	escaped := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\t", `\t`).Replace(value)
+
+	if value != strings.TrimSpace(value) || strings.ContainsAny(value, "#;") {
+		return `"` + escaped + `"`
+	}
+	return escaped
+}
+
+func formatHeader(section string) string {
+	name, subsection, found := strings.Cut(section, ".")
+	if !found {
+		return "[" + name + "]"
+	}
+	return fmt.Sprintf("[%s \"%s\"]", name, strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(subsection))
+}
+
+// Splits section[.subsection].name into its canonical section and variable name
+func parseKey(key string) (string, string, error) {
+	first := strings.IndexByte(key, '.')
+	last := strings.LastIndexByte(key, '.')
+	if first < 0 {
+		return "", "", fmt.Errorf("error: key does not contain a section: %s", key)
+	}
+
+	section, name := key[:first], key[last+1:]
+	if !validSection(section) {
+		return "", "", fmt.Errorf("error: invalid key: %s", key)
+	}
+	if !validName(name) {
+		return "", "", fmt.Errorf("error: invalid key: %s", key)
+	}
+
+	canonical := strings.ToLower(section)
+	if first != last {
+		canonical += "." + key[first+1:last]
+	}
+	return canonical, strings.ToLower(name), nil
+}
+
+// Section names are alphanumeric with '-'
+func validSection(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, c := range name {
+		if !isAlnum(c) && c != '-' {
+			return false
+		}
+	}
+	return true
+}
+
+// Variable names are alphanumeric with '-' and must start with a letter
+func validName(name string) bool {
+	if name == "" || !isAlpha(rune(name[0])) {
+		return false
+	}
+	return validSection(name)
+}
+
+func isAlpha(c rune) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isAlnum(c rune) bool {
+	return isAlpha(c) || (c >= '0' && c <= '9')
+}
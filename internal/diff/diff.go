@@ -2,14 +2,9 @@
 package diff
 
 import (
-	"bufio"
 	"bytes"
-	"fmt"
-	"minigit/internal/index"
 	"minigit/internal/objects"
-	"minigit/internal/repository"
-	"os"
-	"path/filepath"
+	"sort"
 	"strings"
 )
 
@@ -22,9 +17,8 @@ type LineChange struct {
 type ChangeType int
 
 const (
-	Added    ChangeType = iota // 0
-	Deleted                    // 1
-	Modified                   // 2
+	Added   ChangeType = iota // 0
+	Deleted                   // 1
 )
 
 // Represents the differences between 2 files
@@ -32,56 +26,35 @@ type FileDiff struct {
 	OldPath string
 	NewPath string
 	Changes []LineChange
+	Edits   []Edit
 }
 
-// Compares 2 files and returns the differences
+// Compares 2 files and returns the differences. A missing final newline
+// changes the last line, as it does in Unified.
 func CompareFiles(oldContent, newContent []byte) *FileDiff {
-	oldLines := splitLines(oldContent)
-	newLines := splitLines(newContent)
+	edits := Myers(markMissingNewline(oldContent, splitLines(oldContent)), markMissingNewline(newContent, splitLines(newContent)))
+	for i := range edits {
+		edits[i].Content = strings.TrimSuffix(edits[i].Content, noNewlineMarker)
+	}
 
 	diff := &FileDiff{
 		Changes: make([]LineChange, 0),
+		Edits:   edits,
 	}
 
-	// Simple line-by-line comparison
-	oldLen := len(oldLines)
-	newLen := len(newLines)
-	maxLen := max(oldLen, newLen)
-
-	for i := range maxLen {
-		var oldLine, newLine string
-		hasOld := i < oldLen
-		hasNew := i < newLen
-
-		if hasOld {
-			oldLine = oldLines[i]
-		}
-		if hasNew {
-			newLine = newLines[i]
-		}
-
-		if hasOld && hasNew {
-			if oldLine != newLine {
-				diff.Changes = append(diff.Changes, LineChange{
-					Type:    Modified,
-					LineNum: i + 1,
-					Content: fmt.Sprintf("-%s\n+%s", oldLine, newLine),
-				})
-			}
-			// If lines are equal, no change to record
-		} else if hasOld && !hasNew {
-			// Line was deleted
+	for _, edit := range edits {
+		switch edit.Type {
+		case Insert:
 			diff.Changes = append(diff.Changes, LineChange{
-				Type:    Deleted,
-				LineNum: i + 1,
-				Content: oldLine,
+				Type:    Added,
+				LineNum: edit.NewLine,
+				Content: edit.Content,
 			})
-		} else if !hasOld && hasNew {
-			// Line was added
+		case Delete:
 			diff.Changes = append(diff.Changes, LineChange{
-				Type:    Added,
-				LineNum: i + 1,
-				Content: newLine,
+				Type:    Deleted,
+				LineNum: edit.OldLine,
+				Content: edit.Content,
 			})
 		}
 	}
@@ -94,122 +67,106 @@ func splitLines(content []byte) []string {
 		return []string{}
 	}
 
-	scanner := bufio.NewScanner(bytes.NewReader(content))
-	lines := make([]string, 0)
-
-	for scanner.Scan() {
-		lines = append(lines, scanner.Text())
-	}
-
-	return lines
+	// A trailing newline terminates the last line rather than starting a new one
+	text := strings.TrimSuffix(string(content), "\n")
+	return strings.Split(text, "\n")
 }
 
 type DiffStats struct {
-	Insertions int
-	Deletions  int
+	FilesChanged int
+	Insertions   int
+	Deletions    int
 }
 
-func CalculateDiffStats(repo *repository.Repository, entries map[string]*index.Entry, lastTreeHash string) (*DiffStats, error) {
+// Counts changed files and inserted/deleted lines between two snapshots
+func CalculateDiffStats(store *objects.Store, oldFiles, newFiles map[string]*objects.IndexEntry) (*DiffStats, error) {
 	stats := &DiffStats{}
 
-	// If this is the first commit, count all lines as insertions
-	if lastTreeHash == "" {
-		for _, entry := range entries {
-			absPath := filepath.Join(repo.GetWorkingDirectory(), entry.Path)
-			if content, err := os.ReadFile(absPath); err == nil {
-				lines := strings.Count(string(content), "\n")
-				if len(content) > 0 && !strings.HasSuffix(string(content), "\n") {
-					lines++ // Count last line if file doesn't end with newline
-				}
-				stats.Insertions += lines
-			}
-		}
-		return stats, nil
+	paths := make(map[string]bool)
+	for path := range oldFiles {
+		paths[path] = true
 	}
-
-	// Get previous commit's file contents
-	store, err := repo.GetObjectStore()
-	if err != nil {
-		return stats, err
+	for path := range newFiles {
+		paths[path] = true
 	}
 
-	previousFiles := make(map[string][]byte)
-	if err := getFilesFromTree(store, lastTreeHash, "", previousFiles); err != nil {
-		return stats, err
+	sorted := make([]string, 0, len(paths))
+	for path := range paths {
+		sorted = append(sorted, path)
 	}
+	sort.Strings(sorted)
 
-	// Compare each file
-	for _, entry := range entries {
-		absPath := filepath.Join(repo.GetWorkingDirectory(), entry.Path)
-		newContent, err := os.ReadFile(absPath)
-		if err != nil {
+	for _, path := range sorted {
+		oldEntry, inOld := oldFiles[path]
+		newEntry, inNew := newFiles[path]
+
+		if inOld && inNew && oldEntry.Hash == newEntry.Hash {
+			if oldEntry.Mode != newEntry.Mode {
+				stats.FilesChanged++
+			}
 			continue
 		}
+		stats.FilesChanged++
 
-		oldContent, existed := previousFiles[entry.Path]
-		if !existed {
-			// New file - count all lines as insertions
-			lines := strings.Count(string(newContent), "\n")
-			if len(newContent) > 0 && !strings.HasSuffix(string(newContent), "\n") {
-				lines++
-			}
-			stats.Insertions += lines
-		} else {
-			// Compare files
-			fileDiff := CompareFiles(oldContent, newContent)
-			for _, change := range fileDiff.Changes {
-				switch change.Type {
-				case Added:
-					stats.Insertions++
-				case Deleted:
-					stats.Deletions++
-				case Modified:
-					// For modified lines, count as both deletion and insertion
-					stats.Deletions++
-					stats.Insertions++
-				}
-			}
+		oldContent, err := loadBlob(store, oldEntry, inOld)
+		if err != nil {
+			return stats, err
 		}
+		newContent, err := loadBlob(store, newEntry, inNew)
+		if err != nil {
+			return stats, err
+		}
+
+		insertions, deletions := CountChanges(oldContent, newContent)
+		stats.Insertions += insertions
+		stats.Deletions += deletions
 	}
 
 	return stats, nil
 }
 
-func getFilesFromTree(store *objects.Store, treeHash, basePath string, files map[string][]byte) error {
-	if treeHash == "" {
-		return nil
+// Counts the lines inserted and deleted between two versions of a file.
+// Like Git, binary files count as changed without any lines, and added or
+// removed files need no diff to count theirs.
+func CountChanges(oldContent, newContent []byte) (insertions, deletions int) {
+	switch {
+	case IsBinary(oldContent) || IsBinary(newContent):
+	case len(oldContent) == 0 || len(newContent) == 0:
+		deletions = countLines(oldContent)
+		insertions = countLines(newContent)
+	default:
+		for _, change := range CompareFiles(oldContent, newContent).Changes {
+			switch change.Type {
+			case Added:
+				insertions++
+			case Deleted:
+				deletions++
+			}
+		}
 	}
+	return insertions, deletions
+}
 
-	treeObj, err := store.LoadObject(treeHash)
-	if err != nil {
-		return err
+// Counts lines the way splitLines splits them, without splitting
+func countLines(content []byte) int {
+	if len(content) == 0 {
+		return 0
 	}
-
-	tree, err := store.ParseTree(treeObj.Content)
-	if err != nil {
-		return err
+	lines := bytes.Count(content, []byte("\n"))
+	if content[len(content)-1] != '\n' {
+		lines++
 	}
+	return lines
+}
 
-	for _, entry := range tree.Entries {
-		fullPath := entry.Name
-		if basePath != "" {
-			fullPath = filepath.Join(basePath, entry.Name)
-		}
-
-		if entry.Type == objects.BlobObject {
-			// It's a file
-			blobObj, err := store.LoadObject(entry.Hash)
-			if err != nil {
-				continue
-			}
-			files[fullPath] = blobObj.Content
-		} else if entry.Type == objects.TreeObject {
-			// It's a directory, recurse
-			if err := getFilesFromTree(store, entry.Hash, fullPath, files); err != nil {
-				return err
-			}
-		}
+func loadBlob(store *objects.Store, entry *objects.IndexEntry, exists bool) ([]byte, error) {
+	if !exists {
+		return nil, nil
 	}
 
-	return nil
+	blob, err := store.LoadObject(entry.Hash)
+	if err != nil {
+		return nil, err
+	}
+	return blob.Content, nil
 }
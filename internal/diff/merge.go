@@ -0,0 +1,161 @@
+// Three-way line merge (diff3)
+package diff
+
+import "strings"
+
+// Result of merging two descendants of a common base
+type MergeResult struct {
+	Content   []byte
+	Conflicts int
+}
+
+// Merges the changes made in ours and theirs relative to base.
+// Regions changed differently on both sides are written between
+// <<<<<<< / ======= / >>>>>>> markers labelled with the given names.
+func Merge3(base, ours, theirs []byte, oursLabel, theirsLabel string) *MergeResult {
+	// A last line without a newline differs from the same line with one
+	merger := &threeWayMerge{
+		base:   markMissingNewline(base, splitLines(base)),
+		ours:   markMissingNewline(ours, splitLines(ours)),
+		theirs: markMissingNewline(theirs, splitLines(theirs)),
+	}
+	merger.matchOurs = matchLines(merger.base, merger.ours)
+	merger.matchTheirs = matchLines(merger.base, merger.theirs)
+
+	var out []string
+	conflicts := 0
+
+	for _, chunk := range merger.chunks() {
+		switch {
+		case equalLines(chunk.ours, chunk.base) || equalLines(chunk.ours, chunk.theirs):
+			out = append(out, chunk.theirs...)
+		case equalLines(chunk.theirs, chunk.base):
+			out = append(out, chunk.ours...)
+		default:
+			conflicts++
+			out = append(out, "<<<<<<< "+oursLabel)
+			out = append(out, chunk.ours...)
+			out = append(out, "=======")
+			out = append(out, chunk.theirs...)
+			out = append(out, ">>>>>>> "+theirsLabel)
+		}
+	}
+
+	var content strings.Builder
+	for i, line := range out {
+		line, missing := strings.CutSuffix(line, noNewlineMarker)
+		content.WriteString(line)
+		// Only the merged file's last line keeps a missing newline; within
+		// a conflict the markers need a line of their own
+		if !missing || i < len(out)-1 {
+			content.WriteString("\n")
+		}
+	}
+
+	var merged []byte
+	if content.Len() > 0 {
+		merged = []byte(content.String())
+	}
+	return &MergeResult{Content: merged, Conflicts: conflicts}
+}
+
+type mergeChunk struct {
+	base, ours, theirs []string
+}
+
+type threeWayMerge struct {
+	base, ours, theirs []string
+	// Base line index -> matching line index on each side
+	matchOurs, matchTheirs         map[int]int
+	baseLine, oursLine, theirsLine int
+}
+
+// Splits the three inputs into alternating stable chunks (identical on all
+// sides) and unstable chunks (changed on at least one side)
+func (m *threeWayMerge) chunks() []mergeChunk {
+	var chunks []mergeChunk
+
+	for {
+		offset, found := m.nextMismatch()
+		if !found {
+			return append(chunks, m.chunkTo(len(m.base), len(m.ours), len(m.theirs)))
+		}
+
+		if offset > 0 {
+			chunks = append(chunks, m.chunkTo(m.baseLine+offset, m.oursLine+offset, m.theirsLine+offset))
+			continue
+		}
+
+		// Skip ahead to the next base line both sides still contain
+		baseLine := m.baseLine
+		for baseLine < len(m.base) && !m.matchedOnBothSides(baseLine) {
+			baseLine++
+		}
+		if baseLine == len(m.base) {
+			return append(chunks, m.chunkTo(len(m.base), len(m.ours), len(m.theirs)))
+		}
+
+		chunks = append(chunks, m.chunkTo(baseLine, m.matchOurs[baseLine], m.matchTheirs[baseLine]))
+	}
+}
+
+// Returns how many lines from the current position agree on all three sides,
+// and false when the inputs are exhausted before any disagreement
+func (m *threeWayMerge) nextMismatch() (int, bool) {
+	offset := 0
+	for m.inBounds(offset) {
+		oursLine, inOurs := m.matchOurs[m.baseLine+offset]
+		theirsLine, inTheirs := m.matchTheirs[m.baseLine+offset]
+		if !inOurs || !inTheirs || oursLine != m.oursLine+offset || theirsLine != m.theirsLine+offset {
+			return offset, true
+		}
+		offset++
+	}
+	return offset, false
+}
+
+func (m *threeWayMerge) inBounds(offset int) bool {
+	return m.baseLine+offset < len(m.base) ||
+		m.oursLine+offset < len(m.ours) ||
+		m.theirsLine+offset < len(m.theirs)
+}
+
+func (m *threeWayMerge) matchedOnBothSides(baseLine int) bool {
+	_, inOurs := m.matchOurs[baseLine]
+	_, inTheirs := m.matchTheirs[baseLine]
+	return inOurs && inTheirs
+}
+
+// Cuts a chunk from the current positions up to (excluding) the given ones
+func (m *threeWayMerge) chunkTo(baseLine, oursLine, theirsLine int) mergeChunk {
+	chunk := mergeChunk{
+		base:   m.base[m.baseLine:baseLine],
+		ours:   m.ours[m.oursLine:oursLine],
+		theirs: m.theirs[m.theirsLine:theirsLine],
+	}
+	m.baseLine, m.oursLine, m.theirsLine = baseLine, oursLine, theirsLine
+	return chunk
+}
+
+// Maps each base line to the line it is kept as in other, using the Myers edit script
+func matchLines(base, other []string) map[int]int {
+	matches := make(map[int]int)
+	for _, edit := range Myers(base, other) {
+		if edit.Type == Equal {
+			matches[edit.OldLine-1] = edit.NewLine - 1
+		}
+	}
+	return matches
+}
+
+func equalLines(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -1 +1,158 @@
+// Myers O(ND) line diff
 package diff
+
+type OpType int
+
+const (
+	Equal  OpType = iota // 0
+	Insert               // 1
+	Delete               // 2
+)
+
+// A single step of an edit script turning the old lines into the new ones.
+// Line numbers are 1-based; OldLine is 0 for inserts and NewLine is 0 for deletes.
+type Edit struct {
+	Type    OpType
+	OldLine int
+	NewLine int
+	Content string
+}
+
+// Computes a shortest edit script between two sequences of lines
+// using Myers' greedy algorithm ("An O(ND) Difference Algorithm and Its Variations")
+func Myers(oldLines, newLines []string) []Edit {
+	oldLen, newLen := len(oldLines), len(newLines)
+
+	// Common prefix and suffix never need to be searched
+	prefix := 0
+	for prefix < oldLen && prefix < newLen && oldLines[prefix] == newLines[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < oldLen-prefix && suffix < newLen-prefix &&
+		oldLines[oldLen-1-suffix] == newLines[newLen-1-suffix] {
+		suffix++
+	}
+
+	script := &editScript{a: oldLines, b: newLines, edits: make([]Edit, 0, max(oldLen, newLen))}
+	for i := range prefix {
+		script.equal(i, i)
+	}
+
+	script.diff(prefix, oldLen-suffix, prefix, newLen-suffix)
+
+	for i := suffix; i > 0; i-- {
+		script.equal(oldLen-i, newLen-i)
+	}
+
+	return script.edits
+}
+
+// Builds an edit script for a and b front to back. Only the search
+// frontiers of the current range are kept, so memory stays linear in the
+// input size rather than growing with the number of differences.
+type editScript struct {
+	a, b  []string
+	edits []Edit
+}
+
+func (s *editScript) equal(x, y int) {
+	s.edits = append(s.edits, Edit{Type: Equal, OldLine: x + 1, NewLine: y + 1, Content: s.a[x]})
+}
+
+// Appends the edits turning a[aLo:aHi] into b[bLo:bHi], splitting the
+// ranges at the middle snake of an optimal path until one side is empty
+// (Myers' linear space refinement, section 4b of the paper)
+func (s *editScript) diff(aLo, aHi, bLo, bHi int) {
+	for aLo < aHi && bLo < bHi && s.a[aLo] == s.b[bLo] {
+		s.equal(aLo, bLo)
+		aLo++
+		bLo++
+	}
+	suffix := 0
+	for aLo < aHi-suffix && bLo < bHi-suffix && s.a[aHi-1-suffix] == s.b[bHi-1-suffix] {
+		suffix++
+	}
+	aHi, bHi = aHi-suffix, bHi-suffix
+
+	switch {
+	case aLo == aHi:
+		for y := bLo; y < bHi; y++ {
+			s.edits = append(s.edits, Edit{Type: Insert, NewLine: y + 1, Content: s.b[y]})
+		}
+	case bLo == bHi:
+		for x := aLo; x < aHi; x++ {
+			s.edits = append(s.edits, Edit{Type: Delete, OldLine: x + 1, Content: s.a[x]})
+		}
+	default:
+		// Both ranges start and end with a difference, so the path costs at
+		// least two edits and each half makes progress
+		x, y := s.middleSnake(aLo, aHi, bLo, bHi)
+		s.diff(aLo, x, bLo, y)
+		s.diff(x, aHi, y, bHi)
+	}
+
+	for i := suffix; i > 0; i-- {
+		s.equal(aHi+suffix-i, bHi+suffix-i)
+	}
+}
+
+// Searches from both corners of the edit graph at once and returns a point
+// where the forward and reverse paths meet, which lies on a shortest path
+func (s *editScript) middleSnake(aLo, aHi, bLo, bHi int) (int, int) {
+	n, m := aHi-aLo, bHi-bLo
+	delta := n - m
+	limit := (n + m + 1) / 2
+
+	// forward[k+offset] holds the furthest x reached on diagonal k = x-y from
+	// the top left, reverse[k+offset] how far back from the bottom right the
+	// reverse search got on its diagonal k = (n-x)-(m-y)
+	offset := limit + 1
+	forward := make([]int, 2*limit+3)
+	reverse := make([]int, 2*limit+3)
+
+	for d := 0; d <= limit; d++ {
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && forward[k-1+offset] < forward[k+1+offset]) {
+				x = forward[k+1+offset] // step down: insertion
+			} else {
+				x = forward[k-1+offset] + 1 // step right: deletion
+			}
+			y := x - k
+			for x < n && y < m && s.a[aLo+x] == s.b[bLo+y] {
+				x++
+				y++
+			}
+			forward[k+offset] = x
+
+			// With an odd delta the paths meet after a forward step
+			if rk := delta - k; delta%2 != 0 && rk >= -(d-1) && rk <= d-1 && x+reverse[rk+offset] >= n {
+				return aLo + x, bLo + y
+			}
+		}
+
+		for k := -d; k <= d; k += 2 {
+			var u int
+			if k == -d || (k != d && reverse[k-1+offset] < reverse[k+1+offset]) {
+				u = reverse[k+1+offset]
+			} else {
+				u = reverse[k-1+offset] + 1
+			}
+			v := u - k
+			for u < n && v < m && s.a[aHi-1-u] == s.b[bHi-1-v] {
+				u++
+				v++
+			}
+			reverse[k+offset] = u
+
+			// With an even delta they meet after a reverse step
+			if fk := delta - k; delta%2 == 0 && fk >= -d && fk <= d && forward[fk+offset]+u >= n {
+				return aHi - u, bHi - v
+			}
+		}
+	}
+
+	// Unreachable: the searches meet by the time half the edits are spent
+	return aHi, bHi
+}
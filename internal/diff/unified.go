@@ -0,0 +1,134 @@
+// Unified diff formatting
+package diff
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+const noNewlineMarker = "\n\\ No newline at end of file"
+
+// A contiguous region of changes together with its surrounding context
+type Hunk struct {
+	OldStart int
+	OldLines int
+	NewStart int
+	NewLines int
+	Edits    []Edit
+}
+
+// Reports whether content looks binary (has a NUL byte near the start, like Git checks)
+func IsBinary(content []byte) bool {
+	return bytes.IndexByte(content[:min(len(content), 8000)], 0) != -1
+}
+
+// Renders the differences between two file contents as unified diff hunks
+// with the given number of context lines, without file headers
+func Unified(oldContent, newContent []byte, context int) string {
+	oldLines := markMissingNewline(oldContent, splitLines(oldContent))
+	newLines := markMissingNewline(newContent, splitLines(newContent))
+
+	var out strings.Builder
+	for _, hunk := range Hunks(Myers(oldLines, newLines), context) {
+		fmt.Fprintf(&out, "@@ -%s +%s @@\n", hunkRange(hunk.OldStart, hunk.OldLines), hunkRange(hunk.NewStart, hunk.NewLines))
+
+		for _, edit := range hunk.Edits {
+			switch edit.Type {
+			case Equal:
+				out.WriteString(" ")
+			case Delete:
+				out.WriteString("-")
+			case Insert:
+				out.WriteString("+")
+			}
+			out.WriteString(edit.Content)
+			out.WriteString("\n")
+		}
+	}
+
+	return out.String()
+}
+
+// Groups an edit script into hunks, merging changes whose context would overlap
+func Hunks(edits []Edit, context int) []Hunk {
+	context = max(context, 0)
+
+	var changes []int
+	for i, edit := range edits {
+		if edit.Type != Equal {
+			changes = append(changes, i)
+		}
+	}
+	if len(changes) == 0 {
+		return nil
+	}
+
+	// Line numbers each edit starts at on both sides
+	oldPos := make([]int, len(edits))
+	newPos := make([]int, len(edits))
+	oldLine, newLine := 1, 1
+	for i, edit := range edits {
+		oldPos[i], newPos[i] = oldLine, newLine
+		if edit.Type != Insert {
+			oldLine++
+		}
+		if edit.Type != Delete {
+			newLine++
+		}
+	}
+
+	var hunks []Hunk
+	for i := 0; i < len(changes); {
+		first, last := changes[i], changes[i]
+		i++
+		for i < len(changes) && changes[i]-last <= 2*context+1 {
+			last = changes[i]
+			i++
+		}
+
+		start := max(first-context, 0)
+		end := min(last+context+1, len(edits))
+
+		hunk := Hunk{
+			OldStart: oldPos[start],
+			NewStart: newPos[start],
+			Edits:    edits[start:end],
+		}
+		for _, edit := range hunk.Edits {
+			if edit.Type != Insert {
+				hunk.OldLines++
+			}
+			if edit.Type != Delete {
+				hunk.NewLines++
+			}
+		}
+
+		hunks = append(hunks, hunk)
+	}
+
+	return hunks
+}
+
+// Formats one side of a hunk header; empty ranges point at the line before them
+func hunkRange(start, count int) string {
+	switch count {
+	case 0:
+		return fmt.Sprintf("%d,0", start-1)
+	case 1:
+		return fmt.Sprintf("%d", start)
+	default:
+		return fmt.Sprintf("%d,%d", start, count)
+	}
+}
+
+// Tags the last line of content lacking a trailing newline, so it compares
+// unequal to the same text with a newline and prints Git's marker after it
+func markMissingNewline(content []byte, lines []string) []string {
+	if len(lines) == 0 || content[len(content)-1] == '\n' {
+		return lines
+	}
+
+	lines[len(lines)-1] += noNewlineMarker
+	return lines
+}
@@ -0,0 +1,269 @@
+// Gitignore-compatible path exclusion
+package ignore
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Name of the per-directory pattern files
+const FileName = ".minigitignore"
+
+// A single rule from an ignore file
+type Pattern struct {
+	Source string // file the rule was read from, relative to the work tree
+	Line   int
+	Text   string // the rule as written
+
+	base     string // directory the rule is relative to ("" for the root)
+	negate   bool
+	dirOnly  bool
+	anchored bool // matched against the path relative to base rather than the basename
+	regex    *regexp.Regexp
+}
+
+// Decides whether work tree paths are ignored, loading the ignore file of
+// each directory the first time a path inside it is checked
+type Matcher struct {
+	workDir string
+	exclude []*Pattern
+	dirs    map[string][]*Pattern
+}
+
+// Creates a matcher for the work tree, reading .minigit/info/exclude up front
+func NewMatcher(workDir, minigitDir string) (*Matcher, error) {
+	m := &Matcher{
+		workDir: workDir,
+		dirs:    make(map[string][]*Pattern),
+	}
+
+	excludePath := filepath.Join(minigitDir, "info", "exclude")
+	exclude, err := loadPatterns(excludePath, ".minigit/info/exclude", "")
+	if err != nil {
+		return nil, err
+	}
+	m.exclude = exclude
+
+	return m, nil
+}
+
+// Returns the rule that decides the given slash-separated path, or nil when
+// no rule matches. A negated result means the path is explicitly not ignored.
+// Paths inside an ignored directory are ignored by that directory's rule.
+func (m *Matcher) Match(relPath string, isDir bool) (*Pattern, error) {
+	parts := strings.Split(relPath, "/")
+
+	// Files can't be re-included once a parent directory is excluded
+	for i := 1; i < len(parts); i++ {
+		pattern, err := m.matchPath(strings.Join(parts[:i], "/"), true)
+		if err != nil {
+			return nil, err
+		}
+		if pattern != nil && !pattern.negate {
+			return pattern, nil
+		}
+	}
+
+	return m.matchPath(relPath, isDir)
+}
+
+// Reports whether the given path is ignored
+func (m *Matcher) IsIgnored(relPath string, isDir bool) (bool, error) {
+	pattern, err := m.Match(relPath, isDir)
+	return pattern != nil && !pattern.negate, err
+}
+
+// Finds the deciding rule for a path without looking at its parents. Rules in
+// deeper directories override shallower ones and the exclude file, and later
+// rules in a file override earlier ones.
+func (m *Matcher) matchPath(relPath string, isDir bool) (*Pattern, error) {
+	lists := [][]*Pattern{m.exclude}
+
+	// The ignore files of the root and of every directory above the path
+	parts := strings.Split(relPath, "/")
+	for i := 0; i < len(parts); i++ {
+		patterns, err := m.dirPatterns(strings.Join(parts[:i], "/"))
+		if err != nil {
+			return nil, err
+		}
+		lists = append(lists, patterns)
+	}
+
+	for i := len(lists) - 1; i >= 0; i-- {
+		patterns := lists[i]
+		for j := len(patterns) - 1; j >= 0; j-- {
+			if patterns[j].matches(relPath, isDir) {
+				return patterns[j], nil
+			}
+		}
+	}
+
+	return nil, nil
+}
+
+func (m *Matcher) dirPatterns(dir string) ([]*Pattern, error) {
+	if patterns, loaded := m.dirs[dir]; loaded {
+		return patterns, nil
+	}
+
+	source := path.Join(dir, FileName)
+	patterns, err := loadPatterns(filepath.Join(m.workDir, filepath.FromSlash(source)), source, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	m.dirs[dir] = patterns
+	return patterns, nil
+}
+
+// Reports whether the rule re-includes paths ("!pattern")
+func (p *Pattern) Negated() bool {
+	return p.negate
+}
+
+func (p *Pattern) matches(relPath string, isDir bool) bool {
+	if p.dirOnly && !isDir {
+		return false
+	}
+
+	if p.base != "" {
+		if !strings.HasPrefix(relPath, p.base+"/") {
+			return false
+		}
+		relPath = strings.TrimPrefix(relPath, p.base+"/")
+	}
+
+	if !p.anchored {
+		relPath = path.Base(relPath)
+	}
+
+	return p.regex.MatchString(relPath)
+}
+
+// Reads the rules from an ignore file; a missing file has no rules
+func loadPatterns(filePath, source, base string) ([]*Pattern, error) {
+	data, err := os.ReadFile(filePath)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", source, err)
+	}
+
+	var patterns []*Pattern
+	for i, line := range strings.Split(string(data), "\n") {
+		pattern, err := ParsePattern(line, base)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", source, i+1, err)
+		}
+		if pattern == nil {
+			continue
+		}
+		pattern.Source = source
+		pattern.Line = i + 1
+		patterns = append(patterns, pattern)
+	}
+
+	return patterns, nil
+}
+
+// Parses one line of an ignore file, returning nil for blank lines and comments
+func ParsePattern(line, base string) (*Pattern, error) {
+	line = strings.TrimSuffix(line, "\r")
+	text := trimTrailingSpace(line)
+	if text == "" || text[0] == '#' {
+		return nil, nil
+	}
+
+	p := &Pattern{Text: text, base: base}
+
+	if text[0] == '!' {
+		p.negate = true
+		text = text[1:]
+	} else if strings.HasPrefix(text, `\!`) || strings.HasPrefix(text, `\#`) {
+		text = text[1:]
+	}
+
+	if strings.HasSuffix(text, "/") {
+		p.dirOnly = true
+		text = strings.TrimSuffix(text, "/")
+	}
+
+	// A slash anywhere but the end ties the pattern to its base directory
+	if strings.Contains(text, "/") {
+		p.anchored = true
+		text = strings.TrimPrefix(text, "/")
+	}
+
+	if text == "" {
+		return nil, nil
+	}
+
+	regex, err := compileGlob(text)
+	if err != nil {
+		return nil, err
+	}
+	p.regex = regex
+
+	return p, nil
+}
+
+// Removes unescaped trailing spaces
+func trimTrailingSpace(line string) string {
+	end := len(line)
+	for end > 0 && line[end-1] == ' ' {
+		if end > 1 && line[end-2] == '\\' {
+			break
+		}
+		end--
+	}
+	return line[:end]
+}
+
+// Translates a gitignore glob into an anchored regular expression
+func compileGlob(glob string) (*regexp.Regexp, error) {
+	var expr strings.Builder
+	expr.WriteString("^")
+
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+		switch {
+		case strings.HasPrefix(glob[i:], "**/") && (i == 0 || glob[i-1] == '/'):
+			// Leading "**/" or "/**/": zero or more directories
+			expr.WriteString("(?:.*/)?")
+			i += 2
+		case glob[i:] == "**" && (i == 0 || glob[i-1] == '/'):
+			// Trailing "/**": everything inside
+			expr.WriteString(".*")
+			i++
+		case c == '*':
+			expr.WriteString("[^/]*")
+		case c == '?':
+			expr.WriteString("[^/]")
+		case c == '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end < 0 {
+				expr.WriteString(regexp.QuoteMeta("["))
+				continue
+			}
+			class := glob[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			expr.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			i += end + 1
+		case c == '\\' && i+1 < len(glob):
+			i++
+			expr.WriteString(regexp.QuoteMeta(string(glob[i])))
+		default:
+			expr.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+
+	expr.WriteString("$")
+	return regexp.Compile(expr.String())
+}
@@ -5,11 +5,54 @@ import (
 	"time"
 )
 
+// Hash of the empty blob, the only content a zero-size entry can trust its
+// stat data for
+const emptyBlobHash = "e69de29bb2d1d6434b8b29ae775ad8c2e48c5391"
+
 // Represents a file in the staging area
 type Entry struct {
-	Path    string      `json:"path"`
-	Hash    string      `json:"hash"`
-	Mode    os.FileMode `json:"mode"`
-	Size    int64       `json:"size"`
-	ModTime time.Time   `json:"mod_time"`
+	Path string      `json:"path"`
+	Hash string      `json:"hash"`
+	Mode os.FileMode `json:"mode"`
+	// Stat data of the file when it was staged, used to tell whether it
+	// changed without rehashing it
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mod_time"`
+	CTime   time.Time `json:"-"`
+	Dev     uint32    `json:"-"`
+	Ino     uint32    `json:"-"`
+	UID     uint32    `json:"-"`
+	GID     uint32    `json:"-"`
+	// 0 for a merged entry; 1 (base), 2 (ours) or 3 (theirs) for an unmerged path
+	Stage int `json:"stage,omitempty"`
+	// Set once the file was checked against the entry by this process
+	upToDate bool
+}
+
+// Records the stat data of the file the entry was staged from
+func (entry *Entry) SetStat(info os.FileInfo) {
+	entry.Size = info.Size()
+	entry.ModTime = info.ModTime()
+	fillStat(entry, info)
+}
+
+// Reports whether a file's stat data is what the entry recorded, in which
+// case its content is taken to be unchanged. The file's mode is passed in,
+// as the executable bit on disk may not be trusted (see Index.FileMode).
+func (entry *Entry) MatchesStat(info os.FileInfo, mode os.FileMode) bool {
+	current := Entry{Mode: mode}
+	current.SetStat(info)
+
+	// Smudged entries (see Index.Write) have their size zeroed
+	if entry.Size == 0 && entry.Hash != emptyBlobHash {
+		return false
+	}
+
+	return entry.Mode == current.Mode &&
+		entry.Size == current.Size &&
+		entry.ModTime.Equal(current.ModTime) &&
+		entry.CTime.Equal(current.CTime) &&
+		entry.Ino == current.Ino &&
+		entry.UID == current.UID &&
+		entry.GID == current.GID
 }
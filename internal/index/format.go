@@ -0,0 +1,216 @@
+package index
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"minigit/internal/objects"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// Git's index layout: a "DIRC" header, the entries sorted by path and stage,
+// optional extensions and a SHA-1 of everything before it
+const (
+	indexSignature = "DIRC"
+	indexVersion   = 2
+	headerSize     = 12
+	// ctime, mtime, dev, ino, mode, uid, gid and size, the hash and the flags
+	entryFixedSize = 62
+	checksumSize   = sha1.Size
+
+	flagExtended  = 0x4000
+	flagStageMask = 0x3000
+	flagNameMask  = 0x0fff
+)
+
+// Legacy JSON layout of the index file
+type jsonIndexFile struct {
+	Version   int                 `json:"version"`
+	Entries   map[string]*Entry   `json:"entries"`
+	Conflicts map[string][]*Entry `json:"conflicts,omitempty"`
+}
+
+// Serializes every entry, merged and unmerged, in Git's DIRC format
+func encodeIndex(entries map[string]*Entry, conflicts map[string][]*Entry) ([]byte, error) {
+	all := make([]*Entry, 0, len(entries)+len(conflicts))
+	for _, entry := range entries {
+		all = append(all, entry)
+	}
+	for _, stages := range conflicts {
+		all = append(all, stages...)
+	}
+	sort.Slice(all, func(i, j int) bool {
+		a, b := filepath.ToSlash(all[i].Path), filepath.ToSlash(all[j].Path)
+		if a != b {
+			return a < b
+		}
+		return all[i].Stage < all[j].Stage
+	})
+
+	var buf bytes.Buffer
+	buf.WriteString(indexSignature)
+	binary.Write(&buf, binary.BigEndian, uint32(indexVersion))
+	binary.Write(&buf, binary.BigEndian, uint32(len(all)))
+
+	for _, entry := range all {
+		hash, err := hex.DecodeString(entry.Hash)
+		if err != nil || len(hash) != sha1.Size {
+			return nil, fmt.Errorf("invalid object name %q for '%s'", entry.Hash, entry.Path)
+		}
+		mode, err := strconv.ParseUint(objects.FormatMode(entry.Mode), 8, 32)
+		if err != nil {
+			return nil, err
+		}
+
+		name := filepath.ToSlash(entry.Path)
+		flags := uint16(entry.Stage<<12) & flagStageMask
+		// Longer names are marked with the maximum and found by their NUL
+		flags |= uint16(min(len(name), flagNameMask))
+
+		fields := []uint32{
+			uint32(entry.CTime.Unix()), uint32(entry.CTime.Nanosecond()),
+			uint32(entry.ModTime.Unix()), uint32(entry.ModTime.Nanosecond()),
+			entry.Dev, entry.Ino, uint32(mode), entry.UID, entry.GID, uint32(entry.Size),
+		}
+		if entry.CTime.IsZero() {
+			fields[0], fields[1] = 0, 0
+		}
+		if entry.ModTime.IsZero() {
+			fields[2], fields[3] = 0, 0
+		}
+		binary.Write(&buf, binary.BigEndian, fields)
+		buf.Write(hash)
+		binary.Write(&buf, binary.BigEndian, flags)
+		buf.WriteString(name)
+
+		// NUL-padded to a multiple of eight bytes, with at least one NUL
+		size := entryFixedSize + len(name)
+		buf.Write(make([]byte, 8-size%8))
+	}
+
+	sum := sha1.Sum(buf.Bytes())
+	buf.Write(sum[:])
+	return buf.Bytes(), nil
+}
+
+// Parses a DIRC index of version 2 or 3, splitting out unmerged entries
+func decodeIndex(data []byte) (map[string]*Entry, map[string][]*Entry, error) {
+	if len(data) < headerSize+checksumSize || string(data[:4]) != indexSignature {
+		return nil, nil, fmt.Errorf("index file corrupt: bad signature")
+	}
+
+	body := data[:len(data)-checksumSize]
+	if sum := sha1.Sum(body); !bytes.Equal(sum[:], data[len(body):]) {
+		return nil, nil, fmt.Errorf("index file corrupt: bad index file sha1 signature")
+	}
+
+	version := binary.BigEndian.Uint32(data[4:8])
+	if version != 2 && version != 3 {
+		return nil, nil, fmt.Errorf("index file version %d not supported", version)
+	}
+	count := binary.BigEndian.Uint32(data[8:12])
+
+	entries := make(map[string]*Entry)
+	conflicts := make(map[string][]*Entry)
+	offset := headerSize
+
+	for range count {
+		if offset+entryFixedSize > len(body) {
+			return nil, nil, fmt.Errorf("index file corrupt: truncated entry")
+		}
+		field := func(i int) uint32 {
+			return binary.BigEndian.Uint32(body[offset+4*i:])
+		}
+
+		mode, _, err := objects.ParseMode(strconv.FormatUint(uint64(field(6)), 8))
+		if err != nil {
+			return nil, nil, fmt.Errorf("index file corrupt: %w", err)
+		}
+		entry := &Entry{
+			CTime:   unixTime(field(0), field(1)),
+			ModTime: unixTime(field(2), field(3)),
+			Dev:     field(4),
+			Ino:     field(5),
+			Mode:    mode,
+			UID:     field(7),
+			GID:     field(8),
+			Size:    int64(field(9)),
+			Hash:    hex.EncodeToString(body[offset+40 : offset+60]),
+		}
+		flags := binary.BigEndian.Uint16(body[offset+60:])
+		entry.Stage = int(flags&flagStageMask) >> 12
+
+		nameStart := offset + entryFixedSize
+		if flags&flagExtended != 0 {
+			// Version 3 extended flags (skip-worktree, intent-to-add) are not used
+			nameStart += 2
+		}
+		nameLen := bytes.IndexByte(body[min(nameStart, len(body)):], 0)
+		if nameLen < 0 {
+			return nil, nil, fmt.Errorf("index file corrupt: unterminated path")
+		}
+		entry.Path = filepath.FromSlash(string(body[nameStart : nameStart+nameLen]))
+
+		size := nameStart - offset + nameLen
+		offset += size + 8 - size%8
+
+		if entry.Stage == 0 {
+			entries[entry.Path] = entry
+		} else {
+			conflicts[entry.Path] = append(conflicts[entry.Path], entry)
+		}
+	}
+
+	// Extensions (cached trees, resolve-undo, ...) only speed Git up and are
+	// skipped; unknown mandatory ones, lowercase by convention, are refused
+	for offset+8 <= len(body) {
+		signature := body[offset : offset+4]
+		size := int(binary.BigEndian.Uint32(body[offset+4:]))
+		if signature[0] < 'A' || signature[0] > 'Z' {
+			return nil, nil, fmt.Errorf("index uses %s extension, which we do not understand", signature)
+		}
+		offset += 8 + size
+	}
+	if offset != len(body) {
+		return nil, nil, fmt.Errorf("index file corrupt: trailing data")
+	}
+
+	return entries, conflicts, nil
+}
+
+// Reads an index written by older versions: a versioned JSON document, or
+// before that a bare path -> entry map, reported as version 0
+func decodeJSONIndex(data []byte) (map[string]*Entry, map[string][]*Entry, int, error) {
+	var file jsonIndexFile
+	if err := json.Unmarshal(data, &file); err != nil || file.Version == 0 {
+		file.Version, file.Entries, file.Conflicts = 0, nil, nil
+		if err := json.Unmarshal(data, &file.Entries); err != nil {
+			return nil, nil, 0, err
+		}
+	}
+	if file.Entries == nil {
+		file.Entries = make(map[string]*Entry)
+	}
+	if file.Conflicts == nil {
+		file.Conflicts = make(map[string][]*Entry)
+	}
+
+	// Older indexes recorded raw permission bits
+	for _, entry := range file.Entries {
+		entry.Mode = objects.NormalizeMode(entry.Mode)
+	}
+	return file.Entries, file.Conflicts, file.Version, nil
+}
+
+func unixTime(sec, nsec uint32) time.Time {
+	if sec == 0 && nsec == 0 {
+		return time.Time{}
+	}
+	return time.Unix(int64(sec), int64(nsec))
+}
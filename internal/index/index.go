@@ -2,17 +2,33 @@
 package index
 
 import (
-	"encoding/json"
+	"bytes"
 	"fmt"
 	"maps"
+	"minigit/internal/lockfile"
+	"minigit/internal/objects"
 	"os"
 	"path/filepath"
+	"sort"
+	"time"
 )
 
-// Manages the staging area
+// Manages the staging area. Changes are kept in memory until Write, so a
+// command rewrites the index file once however many paths it touches.
 type Index struct {
 	indexPath string
+	workDir   string
 	entries   map[string]*Entry
+	// Unmerged paths and their stage 1-3 entries, left behind by a conflicted merge
+	conflicts map[string][]*Entry
+	dirty     bool
+	// When the index file was last written, which racily clean entries are
+	// checked against
+	timestamp time.Time
+	// Read from the bare JSON map of versions that emptied the index on commit
+	legacy bool
+	// Whether executable bits in the working tree are meaningful (core.filemode)
+	trustExecutable bool
 }
 
 func NewIndex(minigitDir string) (*Index, error) {
@@ -20,7 +36,11 @@ func NewIndex(minigitDir string) (*Index, error) {
 
 	index := &Index{
 		indexPath: indexPath,
+		workDir:   filepath.Dir(minigitDir),
 		entries:   make(map[string]*Entry),
+		conflicts: make(map[string][]*Entry),
+
+		trustExecutable: true,
 	}
 
 	if err := index.load(); err != nil && !os.IsNotExist(err) {
@@ -30,23 +50,153 @@ func NewIndex(minigitDir string) (*Index, error) {
 	return index, nil
 }
 
+// Sets whether the executable bit of files is trusted, as core.filemode
+// does; file systems that don't keep it need it off
+func (idx *Index) SetTrustExecutableBit(trust bool) {
+	idx.trustExecutable = trust
+}
+
+// Returns the mode a file is staged with. When the executable bit is not
+// trusted, regular files keep the mode of their entry and new ones are not
+// executable.
+func (idx *Index) FileMode(entry *Entry, info os.FileInfo) os.FileMode {
+	mode := objects.NormalizeMode(info.Mode())
+	if idx.trustExecutable || !info.Mode().IsRegular() {
+		return mode
+	}
+	if entry != nil && entry.Mode.IsRegular() {
+		return entry.Mode
+	}
+	return 0644
+}
+
 // Adds or updates a file in the staging area
-func (idx *Index) AddEntry(path, hash string, info os.FileInfo) error {
-	idx.entries[path] = &Entry{
-		Path:    path,
-		Hash:    hash,
-		Mode:    info.Mode(),
-		Size:    info.Size(),
-		ModTime: info.ModTime(),
+func (idx *Index) AddEntry(path, hash string, info os.FileInfo) {
+	entry := &Entry{
+		Path: path,
+		Hash: hash,
+		Mode: idx.FileMode(idx.entries[path], info),
 	}
+	entry.SetStat(info)
+	entry.upToDate = true
 
-	return idx.save()
+	idx.entries[path] = entry
+	delete(idx.conflicts, path)
+	idx.dirty = true
+}
+
+// Adds or updates an entry whose content comes from the object store
+// rather than from a file in the working tree
+func (idx *Index) SetEntry(path, hash string, mode os.FileMode) {
+	idx.entries[path] = &Entry{
+		Path: path,
+		Hash: hash,
+		Mode: objects.NormalizeMode(mode),
+	}
+	delete(idx.conflicts, path)
+	idx.dirty = true
 }
 
 // Removes a file from the staging area
-func (idx *Index) RemoveEntry(path string) error {
+func (idx *Index) RemoveEntry(path string) {
+	delete(idx.entries, path)
+	delete(idx.conflicts, path)
+	idx.dirty = true
+}
+
+// Marks a path as unmerged, replacing its entry with the base, ours and
+// theirs versions (stages 1-3). Missing versions are passed as nil.
+func (idx *Index) AddConflict(path string, base, ours, theirs *Entry) {
+	var stages []*Entry
+	for i, entry := range []*Entry{base, ours, theirs} {
+		if entry == nil {
+			continue
+		}
+		staged := *entry
+		staged.Path = path
+		staged.Stage = i + 1
+		stages = append(stages, &staged)
+	}
+
 	delete(idx.entries, path)
-	return idx.save()
+	idx.conflicts[path] = stages
+	idx.dirty = true
+}
+
+// Returns the staged versions of every unmerged path
+func (idx *Index) GetConflicts() map[string][]*Entry {
+	result := make(map[string][]*Entry)
+	maps.Copy(result, idx.conflicts)
+	return result
+}
+
+// Returns the unmerged paths in sorted order
+func (idx *Index) ConflictPaths() []string {
+	paths := make([]string, 0, len(idx.conflicts))
+	for path := range idx.conflicts {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// Check if any path is unmerged
+func (idx *Index) HasConflicts() bool {
+	return len(idx.conflicts) > 0
+}
+
+// Returns the merged entry for a path
+func (idx *Index) GetEntry(path string) (*Entry, bool) {
+	entry, ok := idx.entries[path]
+	return entry, ok
+}
+
+// Reports whether a file can be assumed to match its entry without being
+// rehashed: its stat data is unchanged and the entry is not racily clean
+func (idx *Index) IsUpToDate(entry *Entry, info os.FileInfo) bool {
+	return entry.Stage == 0 && entry.MatchesStat(info, idx.FileMode(entry, info)) && !idx.isRacy(entry)
+}
+
+// Records fresh stat data for a file found to still match its entry
+func (idx *Index) RefreshEntry(path string, info os.FileInfo) {
+	if entry, ok := idx.entries[path]; ok {
+		entry.SetStat(info)
+		entry.upToDate = true
+		idx.dirty = true
+	}
+}
+
+// A file modified in the same second the index was written may have changed
+// again after it was staged without its mtime telling so
+func (idx *Index) isRacy(entry *Entry) bool {
+	return !idx.timestamp.IsZero() && entry.ModTime.Unix() >= idx.timestamp.Unix()
+}
+
+// Zeroes the size of a racily clean entry whose file changed after all, so
+// it is rehashed even once a rewritten index no longer makes it look racy
+func (idx *Index) smudgeRacilyClean(entry *Entry) {
+	absPath := filepath.Join(idx.workDir, entry.Path)
+	info, err := os.Lstat(absPath)
+	if err != nil || !entry.MatchesStat(info, idx.FileMode(entry, info)) {
+		// Changed files are caught by their stat data already
+		return
+	}
+
+	var content []byte
+	if info.Mode()&os.ModeSymlink != 0 {
+		target, err := os.Readlink(absPath)
+		if err != nil {
+			return
+		}
+		content = []byte(target)
+	} else if content, err = os.ReadFile(absPath); err != nil {
+		return
+	}
+
+	store := &objects.Store{}
+	if store.HashContent(objects.BlobObject, content) != entry.Hash {
+		entry.Size = 0
+	}
 }
 
 // Returns all staged entries
@@ -56,15 +206,32 @@ func (idx *Index) GetEntries() map[string]*Entry {
 	return result
 }
 
+// Replaces every entry in the staging area
+func (idx *Index) Reset(entries []*Entry) {
+	idx.entries = make(map[string]*Entry, len(entries))
+	for _, entry := range entries {
+		idx.entries[entry.Path] = entry
+	}
+	idx.conflicts = make(map[string][]*Entry)
+	idx.dirty = true
+}
+
 // Removes all entries from the staging area
-func (idx *Index) Clear() error {
+func (idx *Index) Clear() {
 	idx.entries = make(map[string]*Entry)
-	return idx.save()
+	idx.conflicts = make(map[string][]*Entry)
+	idx.dirty = true
 }
 
 // Check if index is empty
 func (idx *Index) IsEmpty() bool {
-	return len(idx.entries) == 0
+	return len(idx.entries) == 0 && len(idx.conflicts) == 0
+}
+
+// Reports whether the index was read from the unversioned JSON map written
+// by versions that emptied the index after every commit
+func (idx *Index) IsLegacy() bool {
+	return idx.legacy
 }
 
 // Get number of entries
@@ -72,21 +239,58 @@ func (idx *Index) Count() int {
 	return len(idx.entries)
 }
 
-// Utils functions
+// Writes pending changes to the index file, if there are any
+func (idx *Index) Write() error {
+	if !idx.dirty {
+		return nil
+	}
+
+	for _, entry := range idx.entries {
+		if !entry.upToDate && idx.isRacy(entry) {
+			idx.smudgeRacilyClean(entry)
+		}
+	}
+
+	data, err := encodeIndex(idx.entries, idx.conflicts)
+	if err != nil {
+		return err
+	}
+	// 0644 ~ Owner can read and write, group and others can only read.
+	if err := lockfile.WriteFile(idx.indexPath, data, 0644); err != nil {
+		return err
+	}
+	if info, err := os.Stat(idx.indexPath); err == nil {
+		idx.timestamp = info.ModTime()
+	}
+	idx.dirty = false
+	return nil
+}
+
+// Reads the index file. Indexes written as JSON by older versions are still
+// read, and are converted to the binary format by the next Write.
 func (idx *Index) load() error {
 	data, err := os.ReadFile(idx.indexPath)
 	if err != nil {
 		return err
 	}
+	info, err := os.Stat(idx.indexPath)
+	if err != nil {
+		return err
+	}
 
-	return json.Unmarshal(data, &idx.entries)
-}
-
-func (idx *Index) save() error {
-	data, err := json.MarshalIndent(idx.entries, "", "  ")
+	var entries map[string]*Entry
+	var conflicts map[string][]*Entry
+	if trimmed := bytes.TrimLeft(data, " \t\r\n"); len(trimmed) > 0 && trimmed[0] == '{' {
+		var version int
+		entries, conflicts, version, err = decodeJSONIndex(data)
+		idx.legacy = version == 0
+	} else {
+		entries, conflicts, err = decodeIndex(data)
+	}
 	if err != nil {
 		return err
 	}
-	// 0644 ~ Owner can read and write, group and others can only read.
-	return os.WriteFile(idx.indexPath, data, 0644)
+	idx.entries, idx.conflicts = entries, conflicts
+	idx.timestamp = info.ModTime()
+	return nil
 }
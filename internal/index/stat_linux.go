@@ -0,0 +1,21 @@
+package index
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+// Records the ctime, device, inode and owner Git keeps alongside the mtime
+func fillStat(entry *Entry, info os.FileInfo) {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		entry.CTime = info.ModTime()
+		return
+	}
+	entry.CTime = time.Unix(stat.Ctim.Sec, stat.Ctim.Nsec)
+	entry.Dev = uint32(stat.Dev)
+	entry.Ino = uint32(stat.Ino)
+	entry.UID = stat.Uid
+	entry.GID = stat.Gid
+}
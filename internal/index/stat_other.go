@@ -0,0 +1,11 @@
+//go:build !linux
+
+package index
+
+import "os"
+
+// Without a portable stat, the ctime falls back to the mtime and the
+// device, inode and owner stay zero as Git does on Windows
+func fillStat(entry *Entry, info os.FileInfo) {
+	entry.CTime = info.ModTime()
+}
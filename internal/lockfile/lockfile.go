@@ -0,0 +1,145 @@
+// Lock files guarding updates of repository files, as Git does: the new
+// content is written to "<file>.lock", created exclusively, and renamed
+// over the file once complete
+package lockfile
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// A lock older than this was left behind by a process that died and is removed
+var StaleAfter = 10 * time.Minute
+
+// How long Acquire keeps retrying while another process holds the lock
+var Timeout = time.Second
+
+// Returned (wrapped) when the lock is held by someone else
+var ErrLocked = errors.New("lock is held by another process")
+
+// A held lock on a file, through which its new content is written
+type Lock struct {
+	path string
+	file *os.File
+}
+
+// Creates path's lock file, waiting up to Timeout for another holder to
+// finish and clearing locks older than StaleAfter
+func Acquire(path string) (*Lock, error) {
+	lockPath := path + ".lock"
+	deadline := time.Now().Add(Timeout)
+	wait := 5 * time.Millisecond
+
+	for {
+		// 0644 ~ owners can read and write, others can only read
+		file, err := os.OpenFile(lockPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if err == nil {
+			return &Lock{path: path, file: file}, nil
+		}
+		if !os.IsExist(err) {
+			return nil, fmt.Errorf("fatal: Unable to create '%s': %w", lockPath, err)
+		}
+
+		if info, statErr := os.Lstat(lockPath); statErr == nil && time.Since(info.ModTime()) > StaleAfter {
+			// Whoever held it is long gone
+			if err := breakStaleLock(lockPath, info); err != nil {
+				return nil, fmt.Errorf("fatal: Unable to remove stale '%s': %w", lockPath, err)
+			}
+			continue
+		}
+
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("fatal: Unable to create '%s': File exists.\n\n"+
+				"Another minigit process seems to be running in this repository.\n"+
+				"If it is not, remove the file manually to continue: %w", lockPath, ErrLocked)
+		}
+		time.Sleep(wait)
+		wait = min(wait*2, 100*time.Millisecond)
+	}
+}
+
+// Removes a lock file found to be stale. Another process may have
+// broken it and taken the lock since, so the file is first moved aside
+// under a name of our own and only deleted if it is still the one seen;
+// a live lock moved by mistake is put back.
+func breakStaleLock(lockPath string, stale os.FileInfo) error {
+	aside := fmt.Sprintf("%s.stale-%d-%d", lockPath, os.Getpid(), time.Now().UnixNano())
+	if err := os.Rename(lockPath, aside); err != nil {
+		if os.IsNotExist(err) {
+			return nil // someone else broke it
+		}
+		return err
+	}
+
+	moved, err := os.Lstat(aside)
+	if err != nil {
+		return err
+	}
+	if os.SameFile(stale, moved) && moved.ModTime().Equal(stale.ModTime()) {
+		return os.Remove(aside)
+	}
+
+	// Linking fails rather than replacing a lock taken in the meantime
+	if err := os.Link(aside, lockPath); err != nil {
+		return err
+	}
+	return os.Remove(aside)
+}
+
+// Reports whether path is a lock file, or a stale one moved aside by
+// breakStaleLock and left behind when putting it back failed
+func IsLockFile(path string) bool {
+	return strings.HasSuffix(path, ".lock") || strings.Contains(filepath.Base(path), ".lock.stale-")
+}
+
+func (l *Lock) Write(data []byte) (int, error) {
+	return l.file.Write(data)
+}
+
+// Flushes the new content to disk and renames it over the locked file,
+// releasing the lock
+func (l *Lock) Commit() error {
+	if err := l.file.Sync(); err != nil {
+		l.Rollback()
+		return fmt.Errorf("failed to write %s: %w", l.file.Name(), err)
+	}
+	if err := l.file.Close(); err != nil {
+		os.Remove(l.file.Name())
+		return fmt.Errorf("failed to write %s: %w", l.file.Name(), err)
+	}
+	if err := os.Rename(l.file.Name(), l.path); err != nil {
+		os.Remove(l.file.Name())
+		return fmt.Errorf("failed to replace %s: %w", l.path, err)
+	}
+	return nil
+}
+
+// Releases the lock, leaving the file untouched
+func (l *Lock) Rollback() error {
+	l.file.Close()
+	if err := os.Remove(l.file.Name()); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// Replaces a file's content atomically under its lock
+func WriteFile(path string, data []byte, perm os.FileMode) error {
+	lock, err := Acquire(path)
+	if err != nil {
+		return err
+	}
+	if _, err := lock.Write(data); err != nil {
+		lock.Rollback()
+		return fmt.Errorf("failed to write %s: %w", lock.file.Name(), err)
+	}
+	if err := lock.file.Chmod(perm); err != nil {
+		lock.Rollback()
+		return err
+	}
+	return lock.Commit()
+}
@@ -1,10 +1,7 @@
 package objects
 
 import (
-	"bufio"
-	"bytes"
 	"fmt"
-	"strconv"
 	"strings"
 	"time"
 )
@@ -12,57 +9,80 @@ import (
 type Commit struct {
 	Tree      string    `json:"tree"`
 	Parents   []string  `json:"parents"`
-	Author    string    `json:"author"`
-	Committer string    `json:"committer"`
-	Message   string    `json:"message"`
-	Timestamp time.Time `json:"time"`
+	Author    Signature `json:"author"`
+	Committer Signature `json:"committer"`
+	// Headers other than the above (e.g. gpgsig), kept so the commit re-serializes exactly
+	ExtraHeaders []CommitHeader `json:"extraHeaders,omitempty"`
+	// Message without the newline that terminates it
+	Message string `json:"message"`
+
+	parsed     bool   // read from an object, so terminator is known
+	terminator string // what followed Message in the object ("\n" or "")
 }
 
-// Creates and stores commit objects
+// A header line, with continuation lines joined by "\n"
+type CommitHeader struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+// Creates and stores a commit authored and committed now by the given "Name <email>"
 func (store *Store) CreateCommit(treeHash string, parents []string, author, message string) (string, error) {
-	if treeHash == "" {
-		return "", fmt.Errorf("tree hash cannot be empty")
-	}
 	if message == "" {
 		return "", fmt.Errorf("aborting commit due to empty commit message")
 	}
 	if author == "" {
-		author = "MiniGit User <user@minigit.local>"
+		return "", fmt.Errorf("author identity cannot be empty")
 	}
 
-	commit := &Commit{
-		Tree:      treeHash,
-		Parents:   parents,
-		Author:    author,
-		Committer: author, // For simplicity
-		Message:   message,
-		Timestamp: time.Now(),
+	sig, err := ParseIdent(author)
+	if err != nil {
+		return "", err
 	}
+	sig.When = time.Now()
 
-	content := store.serializeCommit(commit)
+	return store.WriteCommit(&Commit{
+		Tree:      treeHash,
+		Parents:   parents,
+		Author:    sig,
+		Committer: sig,
+		Message:   strings.TrimRight(message, "\n"),
+	})
+}
 
-	return store.StoreObject(CommitObject, content)
+// Stores a fully specified commit, e.g. one with separate author and committer
+func (store *Store) WriteCommit(commit *Commit) (string, error) {
+	if commit.Tree == "" {
+		return "", fmt.Errorf("tree hash cannot be empty")
+	}
+	return store.StoreObject(CommitObject, store.serializeCommit(commit))
 }
 
 // Serialize to Git's commit format
 func (store *Store) serializeCommit(commit *Commit) []byte {
-	var content string
-
-	content += fmt.Sprintf("tree %s\n", commit.Tree)
+	var content strings.Builder
 
+	fmt.Fprintf(&content, "tree %s\n", commit.Tree)
 	for _, parent := range commit.Parents {
-		content += fmt.Sprintf("parent %s\n", parent)
+		fmt.Fprintf(&content, "parent %s\n", parent)
 	}
+	fmt.Fprintf(&content, "author %s\n", commit.Author.Format())
+	fmt.Fprintf(&content, "committer %s\n", commit.Committer.Format())
 
-	timestamp := commit.Timestamp.Unix()
-	timezone := commit.Timestamp.Format("-0700")
-
-	content += fmt.Sprintf("author %s %d %s\n", commit.Author, timestamp, timezone)
-	content += fmt.Sprintf("committer %s %d %s\n", commit.Committer, timestamp, timezone)
+	// Continuation lines start with a space
+	for _, header := range commit.ExtraHeaders {
+		fmt.Fprintf(&content, "%s %s\n", header.Key, strings.ReplaceAll(header.Value, "\n", "\n "))
+	}
 
-	content += "\n" + commit.Message + "\n"
+	// Git terminates non-empty messages with a newline
+	content.WriteString("\n" + commit.Message)
+	if commit.parsed {
+		content.WriteString(commit.terminator)
+	} else if commit.Message != "" {
+		content.WriteString("\n")
+	}
 
-	return []byte(content)
+	return []byte(content.String())
 }
 
 func (store *Store) ParseCommit(content []byte) (*Commit, error) {
@@ -70,80 +90,56 @@ func (store *Store) ParseCommit(content []byte) (*Commit, error) {
 		Parents: make([]string, 0),
 	}
 
-	scanner := bufio.NewScanner(bytes.NewReader(content))
-
-	// Parse header lines
-	for scanner.Scan() {
-		line := scanner.Text()
-
-		// Empty line indicates start of commit message
-		if line == "" {
-			break
-		}
+	// Headers end at the first empty line
+	headers, message, found := strings.Cut(string(content), "\n\n")
+	if !found {
+		headers = strings.TrimSuffix(headers, "\n")
+	}
 
-		parts := strings.SplitN(line, " ", 2)
-		if len(parts) < 2 {
+	var hasAuthor, hasCommitter bool
+	for _, line := range strings.Split(headers, "\n") {
+		if strings.HasPrefix(line, " ") && len(commit.ExtraHeaders) > 0 {
+			last := &commit.ExtraHeaders[len(commit.ExtraHeaders)-1]
+			last.Value += "\n" + line[1:]
 			continue
 		}
 
-		switch parts[0] {
+		key, value, _ := strings.Cut(line, " ")
+
+		var err error
+		switch key {
 		case "tree":
-			commit.Tree = parts[1]
+			commit.Tree = value
 		case "parent":
-			commit.Parents = append(commit.Parents, parts[1])
+			commit.Parents = append(commit.Parents, value)
 		case "author":
-			commit.Author = parseAuthorLine(parts[1])
+			commit.Author, err = ParseSignature(value)
+			hasAuthor = true
 		case "committer":
-			authorInfo := parseAuthorLine(parts[1])
-			commit.Committer = authorInfo
-			// Parse timestamp from committer line
-			if timestamp := parseTimestamp(parts[1]); !timestamp.IsZero() {
-				commit.Timestamp = timestamp
-			}
+			commit.Committer, err = ParseSignature(value)
+			hasCommitter = true
+		default:
+			commit.ExtraHeaders = append(commit.ExtraHeaders, CommitHeader{Key: key, Value: value})
+		}
+		if err != nil {
+			return nil, fmt.Errorf("bad %s line: %w", key, err)
 		}
 	}
 
-	// Parse commit message (everything after the empty line)
-	var messageLines []string
-	for scanner.Scan() {
-		messageLines = append(messageLines, scanner.Text())
+	if commit.Tree == "" {
+		return nil, fmt.Errorf("commit has no tree")
 	}
-
-	if len(messageLines) > 0 {
-		commit.Message = strings.Join(messageLines, "\n")
-		commit.Message = strings.TrimSpace(commit.Message)
+	if !hasAuthor || !hasCommitter {
+		return nil, fmt.Errorf("commit is missing its author or committer")
 	}
 
-	return commit, nil
-}
-
-func parseAuthorLine(line string) string {
-	// Format: "Name <email> timestamp timezone"
-	// Extract just "Name <email>"
-	parts := strings.Fields(line)
-	if len(parts) < 3 {
-		return line
+	commit.parsed = true
+	if strings.HasSuffix(message, "\n") {
+		commit.Message = strings.TrimSuffix(message, "\n")
+		commit.terminator = "\n"
+	} else {
+		commit.Message = message
 	}
 
-	// Find the last two parts (timestamp and timezone)
-	// Everything before that is the author name and email
-	authorParts := parts[:len(parts)-2]
-	return strings.Join(authorParts, " ")
-}
-
-func parseTimestamp(line string) time.Time {
-	// Format: "Name <email> timestamp timezone"
-	parts := strings.Fields(line)
-	if len(parts) < 2 {
-		return time.Time{}
-	}
-
-	// Get the timestamp (second to last field)
-	timestampStr := parts[len(parts)-2]
-	timestamp, err := strconv.ParseInt(timestampStr, 10, 64)
-	if err != nil {
-		return time.Time{}
-	}
-
-	return time.Unix(timestamp, 0)
+	return commit, nil
 }
@@ -0,0 +1,207 @@
+package objects
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// Length of the base blocks a delta is matched against
+const deltaBlockSize = 16
+
+// Largest copy a single delta instruction can express
+const maxCopySize = 0xffffff
+
+// Indexes a delta base by the content of its aligned blocks, so targets can
+// be encoded against it repeatedly
+type deltaIndex struct {
+	base   []byte
+	blocks map[string]int
+}
+
+func newDeltaIndex(base []byte) *deltaIndex {
+	idx := &deltaIndex{
+		base:   base,
+		blocks: make(map[string]int, len(base)/deltaBlockSize),
+	}
+
+	for offset := 0; offset+deltaBlockSize <= len(base); offset += deltaBlockSize {
+		block := string(base[offset : offset+deltaBlockSize])
+		if _, exists := idx.blocks[block]; !exists {
+			idx.blocks[block] = offset
+		}
+	}
+
+	return idx
+}
+
+// Encodes target as copies from the base and literal inserts. Returns nil
+// once the delta would grow beyond maxSize, as it is not worth storing then.
+func (idx *deltaIndex) encode(target []byte, maxSize int) []byte {
+	delta := appendDeltaSize(nil, len(idx.base))
+	delta = appendDeltaSize(delta, len(target))
+
+	var pending []byte
+	flush := func() {
+		for len(pending) > 0 {
+			n := min(len(pending), 127)
+			delta = append(delta, byte(n))
+			delta = append(delta, pending[:n]...)
+			pending = pending[n:]
+		}
+	}
+
+	for i := 0; i < len(target); {
+		if len(delta)+len(pending) > maxSize {
+			return nil
+		}
+
+		offset, found := -1, false
+		if i+deltaBlockSize <= len(target) {
+			offset, found = idx.blocks[string(target[i:i+deltaBlockSize])]
+		}
+		if !found {
+			pending = append(pending, target[i])
+			i++
+			continue
+		}
+
+		// Extend the match forwards, then backwards over pending literals
+		length := deltaBlockSize
+		for i+length < len(target) && offset+length < len(idx.base) && target[i+length] == idx.base[offset+length] {
+			length++
+		}
+		for len(pending) > 0 && offset > 0 && pending[len(pending)-1] == idx.base[offset-1] {
+			pending = pending[:len(pending)-1]
+			offset--
+			i--
+			length++
+		}
+
+		flush()
+		for copied := 0; copied < length; {
+			n := min(length-copied, maxCopySize)
+			delta = appendCopy(delta, offset+copied, n)
+			copied += n
+		}
+		i += length
+	}
+
+	flush()
+	if len(delta) > maxSize {
+		return nil
+	}
+	return delta
+}
+
+// Appends a delta header size: 7 bits at a time, least significant first
+func appendDeltaSize(buf []byte, size int) []byte {
+	for size >= 0x80 {
+		buf = append(buf, byte(size&0x7f)|0x80)
+		size >>= 7
+	}
+	return append(buf, byte(size))
+}
+
+// Appends a copy instruction, leaving out zero bytes of offset and size
+func appendCopy(buf []byte, offset, size int) []byte {
+	op := byte(0x80)
+	var args []byte
+
+	for i := 0; i < 4; i++ {
+		if b := byte(offset >> (8 * i)); b != 0 {
+			op |= 1 << i
+			args = append(args, b)
+		}
+	}
+	for i := 0; i < 3; i++ {
+		if b := byte(size >> (8 * i)); b != 0 {
+			op |= 1 << (4 + i)
+			args = append(args, b)
+		}
+	}
+
+	buf = append(buf, op)
+	return append(buf, args...)
+}
+
+// Rebuilds an object from its delta base and a delta
+func applyDelta(base, delta []byte) ([]byte, error) {
+	r := bytes.NewReader(delta)
+
+	baseSize, err := readDeltaSize(r)
+	if err != nil {
+		return nil, err
+	}
+	if baseSize != len(base) {
+		return nil, fmt.Errorf("delta base size mismatch: expected %d, got %d", baseSize, len(base))
+	}
+	targetSize, err := readDeltaSize(r)
+	if err != nil {
+		return nil, err
+	}
+
+	target := make([]byte, 0, targetSize)
+	for r.Len() > 0 {
+		op, _ := r.ReadByte()
+
+		if op&0x80 == 0 {
+			if op == 0 {
+				return nil, fmt.Errorf("invalid delta instruction")
+			}
+			literal := make([]byte, op)
+			if n, _ := r.Read(literal); n != int(op) {
+				return nil, fmt.Errorf("truncated delta")
+			}
+			target = append(target, literal...)
+			continue
+		}
+
+		var offset, size int
+		for i := 0; i < 4; i++ {
+			if op&(1<<i) != 0 {
+				b, err := r.ReadByte()
+				if err != nil {
+					return nil, fmt.Errorf("truncated delta")
+				}
+				offset |= int(b) << (8 * i)
+			}
+		}
+		for i := 0; i < 3; i++ {
+			if op&(1<<(4+i)) != 0 {
+				b, err := r.ReadByte()
+				if err != nil {
+					return nil, fmt.Errorf("truncated delta")
+				}
+				size |= int(b) << (8 * i)
+			}
+		}
+		if size == 0 {
+			size = 0x10000
+		}
+
+		if offset+size > len(base) {
+			return nil, fmt.Errorf("delta copies beyond its base")
+		}
+		target = append(target, base[offset:offset+size]...)
+	}
+
+	if len(target) != targetSize {
+		return nil, fmt.Errorf("delta result size mismatch: expected %d, got %d", targetSize, len(target))
+	}
+	return target, nil
+}
+
+func readDeltaSize(r *bytes.Reader) (int, error) {
+	size, shift := 0, 0
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			return 0, fmt.Errorf("truncated delta header")
+		}
+		size |= int(b&0x7f) << shift
+		if b&0x80 == 0 {
+			return size, nil
+		}
+		shift += 7
+	}
+}
@@ -0,0 +1,65 @@
+package objects
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+)
+
+// Tree entry modes as Git writes them
+const (
+	ModeRegular    = "100644"
+	ModeExecutable = "100755"
+	ModeSymlink    = "120000"
+	ModeTree       = "40000"
+)
+
+// Reduces a file mode to one of the few kinds Git records: regular (0644),
+// executable (0755) or symbolic link
+func NormalizeMode(mode os.FileMode) os.FileMode {
+	switch {
+	case mode&os.ModeSymlink != 0:
+		return os.ModeSymlink | 0777
+	case mode.IsDir():
+		return os.ModeDir | 0755
+	case mode&0111 != 0:
+		return 0755
+	default:
+		return 0644
+	}
+}
+
+// Formats a file mode as it appears in tree objects
+func FormatMode(mode os.FileMode) string {
+	switch {
+	case mode&os.ModeSymlink != 0:
+		return ModeSymlink
+	case mode.IsDir():
+		return ModeTree
+	case mode&0111 != 0:
+		return ModeExecutable
+	default:
+		return ModeRegular
+	}
+}
+
+// Parses a tree entry mode into a file mode and the type of object it points to.
+// Older minigit trees wrote bare permission bits ("644"), which are still accepted.
+func ParseMode(modeStr string) (os.FileMode, ObjectType, error) {
+	switch modeStr {
+	case ModeRegular:
+		return 0644, BlobObject, nil
+	case ModeExecutable:
+		return 0755, BlobObject, nil
+	case ModeSymlink:
+		return os.ModeSymlink | 0777, BlobObject, nil
+	case ModeTree, "040000":
+		return os.ModeDir | 0755, TreeObject, nil
+	}
+
+	perm, err := strconv.ParseUint(modeStr, 8, 32)
+	if err != nil || perm > 0777 {
+		return 0, "", fmt.Errorf("invalid mode: %s", modeStr)
+	}
+	return NormalizeMode(os.FileMode(perm)), BlobObject, nil
+}
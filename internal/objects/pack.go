@@ -0,0 +1,473 @@
+package objects
+
+import (
+	"bytes"
+	"compress/zlib"
+	"crypto/sha1"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Type codes of packed objects
+const (
+	packCommit   = 1
+	packTree     = 2
+	packBlob     = 3
+	packTag      = 4
+	packOfsDelta = 6
+	packRefDelta = 7
+)
+
+var packTypes = map[ObjectType]byte{
+	CommitObject: packCommit,
+	TreeObject:   packTree,
+	BlobObject:   packBlob,
+	TagObject:    packTag,
+}
+
+// Signature of version 2 pack indexes
+var idxMagic = []byte{0xff, 't', 'O', 'c'}
+
+// Longest delta chain followed before a pack is considered corrupt
+const maxDeltaChain = 4096
+
+// A .pack file along with its version 2 .idx
+type packFile struct {
+	packPath string
+	fanout   [256]uint32
+	hashes   []byte // sorted 20-byte object names
+	offsets  []uint64
+}
+
+// Loads the index of a pack; the pack itself is read on demand
+func openPack(idxPath string) (*packFile, error) {
+	data, err := os.ReadFile(idxPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) < 8+256*4+40 || !bytes.Equal(data[:4], idxMagic) || binary.BigEndian.Uint32(data[4:8]) != 2 {
+		return nil, fmt.Errorf("%s is not a version 2 pack index", filepath.Base(idxPath))
+	}
+
+	pack := &packFile{packPath: strings.TrimSuffix(idxPath, ".idx") + ".pack"}
+
+	pos := 8
+	for i := range pack.fanout {
+		pack.fanout[i] = binary.BigEndian.Uint32(data[pos:])
+		pos += 4
+	}
+
+	count := int(pack.fanout[255])
+	// names, CRCs, offsets and the two trailing checksums
+	if len(data) < pos+count*(20+4+4)+40 {
+		return nil, fmt.Errorf("%s is truncated", filepath.Base(idxPath))
+	}
+
+	pack.hashes = data[pos : pos+count*20]
+	pos += count * 20
+	pos += count * 4 // CRC32s are only needed when verifying
+
+	smallOffsets := data[pos : pos+count*4]
+	largeOffsets := data[pos+count*4:]
+
+	pack.offsets = make([]uint64, count)
+	for i := range pack.offsets {
+		offset := binary.BigEndian.Uint32(smallOffsets[i*4:])
+		if offset&0x80000000 == 0 {
+			pack.offsets[i] = uint64(offset)
+			continue
+		}
+
+		// Offsets past 2GiB live in a separate 8-byte table
+		large := int(offset&0x7fffffff) * 8
+		if large+8 > len(largeOffsets)-40 {
+			return nil, fmt.Errorf("%s has a bad large offset", filepath.Base(idxPath))
+		}
+		pack.offsets[i] = binary.BigEndian.Uint64(largeOffsets[large:])
+	}
+
+	return pack, nil
+}
+
+// Returns the number of objects in the pack
+func (pack *packFile) count() int {
+	return len(pack.offsets)
+}
+
+// Returns the hex name of the i-th object in index order
+func (pack *packFile) hashAt(i int) string {
+	return hex.EncodeToString(pack.hashes[i*20 : i*20+20])
+}
+
+// Finds an object's offset in the pack by binary search within its fanout bucket
+func (pack *packFile) find(hash []byte) (uint64, bool) {
+	lo := 0
+	if hash[0] > 0 {
+		lo = int(pack.fanout[hash[0]-1])
+	}
+	hi := int(pack.fanout[hash[0]])
+
+	i := lo + sort.Search(hi-lo, func(i int) bool {
+		return bytes.Compare(pack.hashes[(lo+i)*20:(lo+i)*20+20], hash) >= 0
+	})
+	if i < hi && bytes.Equal(pack.hashes[i*20:i*20+20], hash) {
+		return pack.offsets[i], true
+	}
+	return 0, false
+}
+
+// Returns the names in the pack that start with a hex prefix
+func (pack *packFile) findPrefix(prefix string) []string {
+	first, err := hex.DecodeString(prefix[:2])
+	if err != nil {
+		return nil
+	}
+
+	lo := 0
+	if first[0] > 0 {
+		lo = int(pack.fanout[first[0]-1])
+	}
+	hi := int(pack.fanout[first[0]])
+
+	var hashes []string
+	start := lo + sort.Search(hi-lo, func(i int) bool { return pack.hashAt(lo+i) >= prefix })
+	for i := start; i < hi; i++ {
+		hash := pack.hashAt(i)
+		if !strings.HasPrefix(hash, prefix) {
+			break
+		}
+		hashes = append(hashes, hash)
+	}
+	return hashes
+}
+
+// Reads and fully resolves the object stored at offset
+func (pack *packFile) readObject(store *Store, offset uint64) (ObjectType, []byte, error) {
+	file, err := os.Open(pack.packPath)
+	if err != nil {
+		return "", nil, err
+	}
+	defer file.Close()
+
+	// Collect the delta chain down to a full object
+	var deltas [][]byte
+	for {
+		if len(deltas) > maxDeltaChain {
+			return "", nil, fmt.Errorf("delta chain too long in %s", filepath.Base(pack.packPath))
+		}
+
+		typeCode, dataOffset, baseOffset, baseHash, err := readEntryHeader(file, offset)
+		if err != nil {
+			return "", nil, err
+		}
+
+		data, err := inflateAt(file, dataOffset)
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to inflate object at %d: %w", offset, err)
+		}
+
+		switch typeCode {
+		case packOfsDelta:
+			deltas = append(deltas, data)
+			offset = baseOffset
+			continue
+
+		case packRefDelta:
+			deltas = append(deltas, data)
+			base, err := store.LoadObject(baseHash)
+			if err != nil {
+				return "", nil, fmt.Errorf("missing delta base %s: %w", baseHash, err)
+			}
+			return resolveDeltas(base.Type, base.Content, deltas)
+		}
+
+		objType, err := objectTypeForCode(typeCode)
+		if err != nil {
+			return "", nil, err
+		}
+		return resolveDeltas(objType, data, deltas)
+	}
+}
+
+// Applies deltas, innermost (closest to the base) last in the slice
+func resolveDeltas(objType ObjectType, content []byte, deltas [][]byte) (ObjectType, []byte, error) {
+	for i := len(deltas) - 1; i >= 0; i-- {
+		var err error
+		if content, err = applyDelta(content, deltas[i]); err != nil {
+			return "", nil, err
+		}
+	}
+	return objType, content, nil
+}
+
+// Parses a packed entry header: the type and size, plus the base reference of deltas
+func readEntryHeader(file *os.File, offset uint64) (typeCode byte, dataOffset, baseOffset uint64, baseHash string, err error) {
+	// Large enough for the size varint followed by a base offset or name
+	buf := make([]byte, 64)
+	n, err := file.ReadAt(buf, int64(offset))
+	if err != nil && err != io.EOF {
+		return 0, 0, 0, "", err
+	}
+	buf = buf[:n]
+
+	pos := 0
+	next := func() (byte, error) {
+		if pos >= len(buf) {
+			return 0, fmt.Errorf("truncated pack entry at %d", offset)
+		}
+		b := buf[pos]
+		pos++
+		return b, nil
+	}
+
+	b, err := next()
+	if err != nil {
+		return 0, 0, 0, "", err
+	}
+	typeCode = (b >> 4) & 7
+	for b&0x80 != 0 {
+		if b, err = next(); err != nil {
+			return 0, 0, 0, "", err
+		}
+	}
+
+	switch typeCode {
+	case packOfsDelta:
+		if b, err = next(); err != nil {
+			return 0, 0, 0, "", err
+		}
+		distance := uint64(b & 0x7f)
+		for b&0x80 != 0 {
+			if b, err = next(); err != nil {
+				return 0, 0, 0, "", err
+			}
+			distance = ((distance + 1) << 7) | uint64(b&0x7f)
+		}
+		if distance > offset {
+			return 0, 0, 0, "", fmt.Errorf("bad delta base offset at %d", offset)
+		}
+		baseOffset = offset - distance
+
+	case packRefDelta:
+		if pos+20 > len(buf) {
+			return 0, 0, 0, "", fmt.Errorf("truncated pack entry at %d", offset)
+		}
+		baseHash = hex.EncodeToString(buf[pos : pos+20])
+		pos += 20
+	}
+
+	return typeCode, offset + uint64(pos), baseOffset, baseHash, nil
+}
+
+func inflateAt(file *os.File, offset uint64) ([]byte, error) {
+	reader, err := zlib.NewReader(io.NewSectionReader(file, int64(offset), math.MaxInt64-int64(offset)))
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	return io.ReadAll(reader)
+}
+
+func objectTypeForCode(code byte) (ObjectType, error) {
+	for objType, typeCode := range packTypes {
+		if typeCode == code {
+			return objType, nil
+		}
+	}
+	return "", fmt.Errorf("unsupported packed object type %d", code)
+}
+
+// Refreshes the list of packs in objects/pack, keeping the ones already loaded
+func (s *Store) loadPacks() error {
+	idxPaths, err := filepath.Glob(filepath.Join(s.objectsDir, "pack", "pack-*.idx"))
+	if err != nil {
+		return err
+	}
+	sort.Strings(idxPaths)
+
+	s.packsMu.Lock()
+	defer s.packsMu.Unlock()
+
+	loaded := make(map[string]*packFile)
+	for _, pack := range s.packs {
+		loaded[pack.packPath] = pack
+	}
+
+	var packs []*packFile
+	for _, idxPath := range idxPaths {
+		packPath := strings.TrimSuffix(idxPath, ".idx") + ".pack"
+		// A pack whose .idx exists but whose .pack is gone can't be used
+		if _, err := os.Stat(packPath); err != nil {
+			continue
+		}
+		if pack, ok := loaded[packPath]; ok {
+			packs = append(packs, pack)
+			continue
+		}
+
+		pack, err := openPack(idxPath)
+		if err != nil {
+			return err
+		}
+		packs = append(packs, pack)
+	}
+
+	s.packs = packs
+	return nil
+}
+
+// Looks an object up in the packs, rescanning the pack directory once when
+// it isn't found in the packs seen so far
+func (s *Store) loadPacked(objHash string) (*Object, error) {
+	raw, err := hex.DecodeString(objHash)
+	if err != nil || len(raw) != sha1.Size {
+		return nil, os.ErrNotExist
+	}
+
+	pack, offset, found := s.findPacked(raw)
+	if !found {
+		if err := s.loadPacks(); err != nil {
+			return nil, err
+		}
+		if pack, offset, found = s.findPacked(raw); !found {
+			return nil, os.ErrNotExist
+		}
+	}
+
+	objType, content, err := pack.readObject(s, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s from %s: %w", objHash, filepath.Base(pack.packPath), err)
+	}
+
+	return &Object{
+		Type:    objType,
+		Size:    int64(len(content)),
+		Content: content,
+		Hash:    objHash,
+	}, nil
+}
+
+// Returns the packs loaded so far; the slice is replaced, never modified, on
+// refresh so it can be read without holding the lock
+func (s *Store) loadedPacks() []*packFile {
+	s.packsMu.Lock()
+	defer s.packsMu.Unlock()
+	return s.packs
+}
+
+func (s *Store) findPacked(raw []byte) (*packFile, uint64, bool) {
+	for _, pack := range s.loadedPacks() {
+		if offset, found := pack.find(raw); found {
+			return pack, offset, true
+		}
+	}
+	return nil, 0, false
+}
+
+// Lists the names ("pack-<checksum>") of the packs in the store
+func (s *Store) PackNames() ([]string, error) {
+	if err := s.loadPacks(); err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, pack := range s.loadedPacks() {
+		names = append(names, strings.TrimSuffix(filepath.Base(pack.packPath), ".pack"))
+	}
+	return names, nil
+}
+
+// Deletes a pack and its index, e.g. once its objects were repacked elsewhere
+func (s *Store) RemovePack(name string) error {
+	base := filepath.Join(s.objectsDir, "pack", name)
+	// The index goes first so readers never see an index without its pack
+	for _, path := range []string{base + ".idx", base + ".pack"} {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove %s: %w", filepath.Base(path), err)
+		}
+	}
+
+	s.packsMu.Lock()
+	s.packs = slices.DeleteFunc(slices.Clone(s.packs), func(pack *packFile) bool { return pack.packPath == base+".pack" })
+	s.packsMu.Unlock()
+	return nil
+}
+
+// Deletes loose objects that are also in a pack and returns how many were removed
+func (s *Store) PrunePacked() (int, error) {
+	loose, err := s.LooseObjects()
+	if err != nil {
+		return 0, err
+	}
+	if err := s.loadPacks(); err != nil {
+		return 0, err
+	}
+
+	pruned := 0
+	for _, hash := range loose {
+		raw, _ := hex.DecodeString(hash)
+		if _, _, found := s.findPacked(raw); !found {
+			continue
+		}
+
+		dir := filepath.Join(s.objectsDir, hash[:2])
+		if err := os.Remove(filepath.Join(dir, hash[2:])); err != nil {
+			return pruned, fmt.Errorf("failed to remove loose object %s: %w", hash, err)
+		}
+		pruned++
+		// Fails harmlessly while the directory still holds other objects
+		os.Remove(dir)
+	}
+
+	return pruned, nil
+}
+
+// Checks a pack and its index against their trailing checksums and each other
+func (s *Store) VerifyPack(name string) error {
+	base := filepath.Join(s.objectsDir, "pack", name)
+
+	packData, err := os.ReadFile(base + ".pack")
+	if err != nil {
+		return err
+	}
+	if len(packData) < 12+20 || string(packData[:4]) != "PACK" {
+		return fmt.Errorf("%s.pack is not a packfile", name)
+	}
+	packSum := sha1.Sum(packData[:len(packData)-20])
+	if !bytes.Equal(packSum[:], packData[len(packData)-20:]) {
+		return fmt.Errorf("%s.pack SHA1 checksum mismatch", name)
+	}
+
+	idxData, err := os.ReadFile(base + ".idx")
+	if err != nil {
+		return err
+	}
+	if len(idxData) < 40 {
+		return fmt.Errorf("%s.idx is truncated", name)
+	}
+	idxSum := sha1.Sum(idxData[:len(idxData)-20])
+	if !bytes.Equal(idxSum[:], idxData[len(idxData)-20:]) {
+		return fmt.Errorf("%s.idx SHA1 checksum mismatch", name)
+	}
+	if !bytes.Equal(idxData[len(idxData)-40:len(idxData)-20], packSum[:]) {
+		return fmt.Errorf("%s.idx does not match %s.pack", name, name)
+	}
+
+	pack, err := openPack(base + ".idx")
+	if err != nil {
+		return err
+	}
+	if count := binary.BigEndian.Uint32(packData[8:12]); int(count) != pack.count() {
+		return fmt.Errorf("%s.pack holds %d objects but its index lists %d", name, count, pack.count())
+	}
+	return nil
+}
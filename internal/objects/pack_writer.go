@@ -0,0 +1,266 @@
+package objects
+
+import (
+	"bytes"
+	"compress/zlib"
+	"crypto/sha1"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"hash/crc32"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// Controls how WritePack searches for deltas
+type PackOptions struct {
+	Window   int  // how many preceding objects are tried as delta bases
+	Depth    int  // longest delta chain to create
+	RefDelta bool // name delta bases by hash (REF_DELTA) instead of by offset (OFS_DELTA)
+}
+
+// Git's defaults for pack.window and pack.depth
+var DefaultPackOptions = PackOptions{Window: 10, Depth: 50}
+
+// Describes a written pack
+type PackStats struct {
+	Name    string // "pack-<checksum>"
+	Objects int
+	Deltas  int
+}
+
+type packEntry struct {
+	hash    string
+	raw     []byte
+	objType ObjectType
+	content []byte
+
+	base   *packEntry // delta base, if stored as a delta
+	delta  []byte
+	depth  int
+	index  *deltaIndex
+	offset uint64
+	crc    uint32
+}
+
+// Writes the given objects into a new pack and index in objects/pack
+func (s *Store) WritePack(hashes []string, opts PackOptions) (*PackStats, error) {
+	if len(hashes) == 0 {
+		return nil, fmt.Errorf("no objects to pack")
+	}
+
+	seen := make(map[string]bool)
+	var entries []*packEntry
+	for _, hash := range hashes {
+		if seen[hash] {
+			continue
+		}
+		seen[hash] = true
+
+		obj, err := s.LoadObject(hash)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load %s: %w", hash, err)
+		}
+		if _, ok := packTypes[obj.Type]; !ok {
+			return nil, fmt.Errorf("cannot pack %s object %s", obj.Type, hash)
+		}
+		raw, _ := hex.DecodeString(hash)
+		entries = append(entries, &packEntry{hash: hash, raw: raw, objType: obj.Type, content: obj.Content})
+	}
+
+	// Similar objects end up next to each other: same type, then largest
+	// first so deltas mostly remove data
+	sort.Slice(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		if a.objType != b.objType {
+			return packTypes[a.objType] < packTypes[b.objType]
+		}
+		if len(a.content) != len(b.content) {
+			return len(a.content) > len(b.content)
+		}
+		return a.hash < b.hash
+	})
+
+	stats := &PackStats{Objects: len(entries)}
+	findDeltas(entries, opts)
+
+	var pack bytes.Buffer
+	pack.WriteString("PACK")
+	binary.Write(&pack, binary.BigEndian, uint32(2))
+	binary.Write(&pack, binary.BigEndian, uint32(len(entries)))
+
+	for _, entry := range entries {
+		entry.offset = uint64(pack.Len())
+
+		typeCode, data := packTypes[entry.objType], entry.content
+		if entry.base != nil {
+			stats.Deltas++
+			data = entry.delta
+			typeCode = packOfsDelta
+			if opts.RefDelta {
+				typeCode = packRefDelta
+			}
+		}
+
+		record := appendEntryHeader(nil, typeCode, len(data))
+		switch typeCode {
+		case packOfsDelta:
+			record = appendOffsetDistance(record, entry.offset-entry.base.offset)
+		case packRefDelta:
+			record = append(record, entry.base.raw...)
+		}
+
+		var compressed bytes.Buffer
+		writer := zlib.NewWriter(&compressed)
+		if _, err := writer.Write(data); err != nil {
+			return nil, fmt.Errorf("failed to compress object: %w", err)
+		}
+		writer.Close()
+		record = append(record, compressed.Bytes()...)
+
+		entry.crc = crc32.ChecksumIEEE(record)
+		pack.Write(record)
+	}
+
+	packSum := sha1.Sum(pack.Bytes())
+	pack.Write(packSum[:])
+
+	stats.Name = "pack-" + hex.EncodeToString(packSum[:])
+	packDir := filepath.Join(s.objectsDir, "pack")
+	// 0755 ~~ rwxr-xr-x
+	if err := os.MkdirAll(packDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create pack directory: %w", err)
+	}
+
+	// The index goes last: readers only look at packs that have one
+	if err := writeFileAtomic(filepath.Join(packDir, stats.Name+".pack"), pack.Bytes()); err != nil {
+		return nil, err
+	}
+	if err := writeFileAtomic(filepath.Join(packDir, stats.Name+".idx"), buildPackIndex(entries, packSum[:])); err != nil {
+		return nil, err
+	}
+
+	return stats, nil
+}
+
+// Picks a delta base for each entry among the preceding objects in its window
+func findDeltas(entries []*packEntry, opts PackOptions) {
+	for i, entry := range entries {
+		// Drop indexes that fell out of every window to bound memory
+		if i > opts.Window && entries[i-opts.Window-1].index != nil {
+			entries[i-opts.Window-1].index = nil
+		}
+
+		// A delta must save at least half of the object to be worth it
+		maxSize := len(entry.content)/2 - 20
+		for j := i - 1; j >= 0 && j >= i-opts.Window && maxSize > 0; j-- {
+			base := entries[j]
+			if base.objType != entry.objType || base.depth >= opts.Depth {
+				continue
+			}
+			if base.index == nil {
+				base.index = newDeltaIndex(base.content)
+			}
+
+			if delta := base.index.encode(entry.content, maxSize); delta != nil {
+				entry.base, entry.delta, entry.depth = base, delta, base.depth+1
+				maxSize = len(delta) - 1
+			}
+		}
+	}
+}
+
+// Builds a version 2 pack index for entries already written to a pack
+func buildPackIndex(entries []*packEntry, packSum []byte) []byte {
+	sorted := make([]*packEntry, len(entries))
+	copy(sorted, entries)
+	sort.Slice(sorted, func(i, j int) bool { return bytes.Compare(sorted[i].raw, sorted[j].raw) < 0 })
+
+	var idx bytes.Buffer
+	idx.Write(idxMagic)
+	binary.Write(&idx, binary.BigEndian, uint32(2))
+
+	var fanout [256]uint32
+	for _, entry := range sorted {
+		fanout[entry.raw[0]]++
+	}
+	for i := 1; i < 256; i++ {
+		fanout[i] += fanout[i-1]
+	}
+	binary.Write(&idx, binary.BigEndian, fanout)
+
+	for _, entry := range sorted {
+		idx.Write(entry.raw)
+	}
+	for _, entry := range sorted {
+		binary.Write(&idx, binary.BigEndian, entry.crc)
+	}
+
+	// Offsets that don't fit in 31 bits point into a table of 8-byte offsets
+	var large []uint64
+	for _, entry := range sorted {
+		if entry.offset < 0x80000000 {
+			binary.Write(&idx, binary.BigEndian, uint32(entry.offset))
+			continue
+		}
+		binary.Write(&idx, binary.BigEndian, uint32(len(large))|0x80000000)
+		large = append(large, entry.offset)
+	}
+	for _, offset := range large {
+		binary.Write(&idx, binary.BigEndian, offset)
+	}
+
+	idx.Write(packSum)
+	idxSum := sha1.Sum(idx.Bytes())
+	idx.Write(idxSum[:])
+
+	return idx.Bytes()
+}
+
+// Encodes an entry's type and uncompressed size: 4 bits of size in the first
+// byte, then 7 bits per byte
+func appendEntryHeader(buf []byte, typeCode byte, size int) []byte {
+	b := typeCode<<4 | byte(size&0x0f)
+	size >>= 4
+	for size > 0 {
+		buf = append(buf, b|0x80)
+		b = byte(size & 0x7f)
+		size >>= 7
+	}
+	return append(buf, b)
+}
+
+// Encodes the distance back to an OFS_DELTA base, most significant byte first.
+// Each continuation adds one so that no distance has two encodings.
+func appendOffsetDistance(buf []byte, distance uint64) []byte {
+	encoded := []byte{byte(distance & 0x7f)}
+	for distance >>= 7; distance > 0; distance >>= 7 {
+		distance--
+		encoded = append([]byte{byte(distance&0x7f) | 0x80}, encoded...)
+	}
+	return append(buf, encoded...)
+}
+
+// Writes data to a temporary file next to path and renames it into place
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "tmp_"+filepath.Base(path)+"_")
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", filepath.Base(path), err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write %s: %w", filepath.Base(path), err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", filepath.Base(path), err)
+	}
+	// 0444 ~ read-only permissions for everyone
+	if err := os.Chmod(tmp.Name(), 0444); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,129 @@
+package objects
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Identity and time of an author or committer, as recorded in commit headers
+type Signature struct {
+	Name  string    `json:"name"`
+	Email string    `json:"email"`
+	When  time.Time `json:"when"`
+}
+
+// Returns "Name <email>"
+func (sig Signature) String() string {
+	return fmt.Sprintf("%s <%s>", sig.Name, sig.Email)
+}
+
+// Formats the signature as it appears in a commit: "Name <email> 1243040974 -0700"
+func (sig Signature) Format() string {
+	return fmt.Sprintf("%s %d %s", sig.String(), sig.When.Unix(), sig.When.Format("-0700"))
+}
+
+// Parses "Name <email>" into a signature without a time
+func ParseIdent(ident string) (Signature, error) {
+	lt := strings.IndexByte(ident, '<')
+	gt := strings.LastIndexByte(ident, '>')
+	if lt < 0 || gt < lt {
+		return Signature{}, fmt.Errorf("malformed identity: %q", ident)
+	}
+
+	return Signature{
+		Name:  strings.TrimSpace(ident[:lt]),
+		Email: ident[lt+1 : gt],
+	}, nil
+}
+
+// Parses a commit signature line: "Name <email> timestamp timezone"
+func ParseSignature(line string) (Signature, error) {
+	gt := strings.LastIndexByte(line, '>')
+	if gt < 0 {
+		return Signature{}, fmt.Errorf("malformed signature: %q", line)
+	}
+
+	sig, err := ParseIdent(line[:gt+1])
+	if err != nil {
+		return Signature{}, err
+	}
+
+	fields := strings.Fields(line[gt+1:])
+	if len(fields) != 2 {
+		return Signature{}, fmt.Errorf("malformed signature: %q", line)
+	}
+
+	when, err := parseRawDate(fields[0], fields[1])
+	if err != nil {
+		return Signature{}, fmt.Errorf("malformed signature: %q", line)
+	}
+	sig.When = when
+
+	return sig, nil
+}
+
+// Parses a date in one of the forms Git accepts for GIT_AUTHOR_DATE: its
+// internal "<unix> <zone>" (optionally prefixed with "@"), RFC 2822, or ISO 8601
+func ParseDate(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+
+	fields := strings.Fields(strings.TrimPrefix(value, "@"))
+	switch {
+	case len(fields) == 2:
+		if when, err := parseRawDate(fields[0], fields[1]); err == nil {
+			return when, nil
+		}
+	case len(fields) == 1 && strings.HasPrefix(value, "@"):
+		if when, err := parseRawDate(fields[0], "+0000"); err == nil {
+			return when, nil
+		}
+	}
+
+	layouts := []string{
+		time.RFC1123Z,
+		"Mon, 2 Jan 2006 15:04:05 -0700",
+		"Mon Jan 2 15:04:05 2006 -0700",
+		time.RFC3339,
+		"2006-01-02T15:04:05-0700",
+		"2006-01-02 15:04:05 -0700",
+		"2006-01-02T15:04:05",
+		"2006-01-02 15:04:05",
+	}
+	for _, layout := range layouts {
+		if when, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return when, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("invalid date format: %s", value)
+}
+
+// Builds a time from seconds since the epoch and a "+hhmm" zone, keeping the
+// zone so the signature formats back to the same text
+func parseRawDate(seconds, zone string) (time.Time, error) {
+	unix, err := strconv.ParseInt(seconds, 10, 64)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	if len(zone) != 5 || (zone[0] != '+' && zone[0] != '-') {
+		return time.Time{}, fmt.Errorf("invalid time zone: %s", zone)
+	}
+	hours, err := strconv.Atoi(zone[1:3])
+	if err != nil {
+		return time.Time{}, err
+	}
+	minutes, err := strconv.Atoi(zone[3:5])
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	offset := hours*60*60 + minutes*60
+	if zone[0] == '-' {
+		offset = -offset
+	}
+
+	return time.Unix(unix, 0).In(time.FixedZone("", offset)), nil
+}
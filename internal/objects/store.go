@@ -5,10 +5,14 @@ import (
 	"bytes"
 	"compress/zlib"
 	"crypto/sha1"
+	"encoding/hex"
 	"fmt"
-	"io"
 	"os"
 	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
 )
 
 // Represents different types of objects
@@ -18,6 +22,7 @@ const (
 	BlobObject   ObjectType = "blob"
 	TreeObject   ObjectType = "tree"
 	CommitObject ObjectType = "commit"
+	TagObject    ObjectType = "tag"
 )
 
 // Represents a minigit object with its metadata
@@ -31,6 +36,9 @@ type Object struct {
 // Handles all object storage operations
 type Store struct {
 	objectsDir string
+	// Guards packs, which concurrent readers may refresh
+	packsMu sync.Mutex
+	packs   []*packFile
 }
 
 func NewStore(minigitDir string) (*Store, error) {
@@ -41,12 +49,10 @@ func NewStore(minigitDir string) (*Store, error) {
 // Computes the SHA-1 hash of content
 func (s *Store) HashContent(objType ObjectType, content []byte) string {
 	// Git's object format: "type size\0content"
-	contentSize := len(content)
-	header := fmt.Sprintf("%s %d\x00", objType, contentSize)
-	fullContent := append([]byte(header), content...)
-
-	hash := sha1.Sum(fullContent)
-	return fmt.Sprintf("%x", hash)
+	hasher := sha1.New()
+	hasher.Write(ObjectHeader{Type: objType, Size: int64(len(content))}.bytes())
+	hasher.Write(content)
+	return hex.EncodeToString(hasher.Sum(nil))
 }
 
 // Saves an object and returns its hash, uses zlib compression (like real Git)
@@ -62,28 +68,22 @@ func (s *Store) StoreObject(objType ObjectType, content []byte) (string, error)
 
 	objPath := filepath.Join(subDir, hash[2:])
 
-	// Check if object already exists
-	if _, err := os.Stat(objPath); err == nil {
+	// Check if object already exists, loose or packed
+	if s.HasObject(hash) {
 		return hash, nil
 	}
 
-	// Prepare content with header
-	contentSize := len(content)
-	header := fmt.Sprintf("%s %d\x00", objType, contentSize)
-	fullContent := append([]byte(header), content...)
-
-	// Compress with zlib
+	// Compress the header and content with zlib
 	var compressed bytes.Buffer
 	writer := zlib.NewWriter(&compressed)
-
-	if _, err := writer.Write(fullContent); err != nil {
+	writer.Write(ObjectHeader{Type: objType, Size: int64(len(content))}.bytes())
+	if _, err := writer.Write(content); err != nil {
 		return "", fmt.Errorf("failed to compress object: %w", err)
 	}
 	writer.Close()
 
-	// Write compressed content to file
-	// 0444 ~ read-only permissions for everyone
-	if err := os.WriteFile(objPath, compressed.Bytes(), 0444); err != nil {
+	// Written aside and renamed into place so readers never see half an object
+	if err := writeFileAtomic(objPath, compressed.Bytes()); err != nil {
 		return "", fmt.Errorf("failed to write object file: %w", err)
 	}
 
@@ -92,44 +92,134 @@ func (s *Store) StoreObject(objType ObjectType, content []byte) (string, error)
 
 // Retrieves an object by its hash
 func (s *Store) LoadObject(objHash string) (*Object, error) {
-	objPath := filepath.Join(s.objectsDir, objHash[:2], objHash[2:])
-
-	compressedData, err := os.ReadFile(objPath)
+	header, reader, err := s.OpenObject(objHash)
 	if err != nil {
-		return nil, fmt.Errorf("object not found: %w", err)
+		return nil, err
+	}
+	defer reader.Close()
+
+	// Sized up front, within reason for a header that may be corrupt
+	content := bytes.NewBuffer(make([]byte, 0, min(header.Size, 64<<20)))
+	if _, err := content.ReadFrom(reader); err != nil {
+		return nil, fmt.Errorf("failed to read object content: %w", err)
+	}
+
+	return &Object{
+		Type:    header.Type,
+		Size:    header.Size,
+		Content: content.Bytes(),
+		Hash:    objHash,
+	}, nil
+}
+
+// Reports whether an object exists, loose or packed
+func (s *Store) HasObject(objHash string) bool {
+	if len(objHash) != 2*sha1.Size || !isHex(objHash) {
+		return false
+	}
+	if _, err := os.Stat(filepath.Join(s.objectsDir, objHash[:2], objHash[2:])); err == nil {
+		return true
 	}
 
-	// Decompress content
-	reader, err := zlib.NewReader(bytes.NewReader(compressedData))
+	raw, err := hex.DecodeString(objHash)
+	if err != nil || len(raw) != sha1.Size {
+		return false
+	}
+	if _, _, found := s.findPacked(raw); found {
+		return true
+	}
+	if err := s.loadPacks(); err != nil {
+		return false
+	}
+	_, _, found := s.findPacked(raw)
+	return found
+}
+
+// Lists the hashes of all loose objects, sorted
+func (s *Store) LooseObjects() ([]string, error) {
+	dirs, err := os.ReadDir(s.objectsDir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
 	if err != nil {
-		return nil, fmt.Errorf("failed to decompress object: %w", err)
+		return nil, err
+	}
+
+	var hashes []string
+	for _, dir := range dirs {
+		if !dir.IsDir() || len(dir.Name()) != 2 || !isHex(dir.Name()) {
+			continue
+		}
+		files, err := os.ReadDir(filepath.Join(s.objectsDir, dir.Name()))
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range files {
+			if len(file.Name()) == 38 && isHex(file.Name()) {
+				hashes = append(hashes, dir.Name()+file.Name())
+			}
+		}
 	}
-	defer reader.Close()
 
-	content, err := io.ReadAll(reader)
+	sort.Strings(hashes)
+	return hashes, nil
+}
+
+// Lists the hashes of every object in the store, loose or packed, sorted
+func (s *Store) ListObjects() ([]string, error) {
+	hashes, err := s.LooseObjects()
 	if err != nil {
-		return nil, fmt.Errorf("failed to read object content: %w", err)
+		return nil, err
 	}
 
-	// Parse header
-	nullIdx := bytes.IndexByte(content, 0)
-	if nullIdx == -1 {
-		return nil, fmt.Errorf("invalid object format")
+	if err := s.loadPacks(); err != nil {
+		return nil, err
 	}
+	for _, pack := range s.loadedPacks() {
+		for i := 0; i < pack.count(); i++ {
+			hashes = append(hashes, pack.hashAt(i))
+		}
+	}
+
+	sort.Strings(hashes)
+	return slices.Compact(hashes), nil
+}
 
-	var objType ObjectType
-	var objSize int64
-	header := string(content[:nullIdx])
-	if _, err := fmt.Sscanf(header, "%s %d", &objType, &objSize); err != nil {
-		return nil, fmt.Errorf("failed to parse object header: %w", err)
+// Returns the sorted hashes of all objects, loose or packed, whose name
+// starts with the given lowercase hex prefix of at least two characters
+func (s *Store) FindByPrefix(prefix string) ([]string, error) {
+	if len(prefix) < 2 || !isHex(prefix) {
+		return nil, fmt.Errorf("invalid object name prefix: '%s'", prefix)
 	}
 
-	objContent := content[nullIdx+1:]
+	var hashes []string
 
-	return &Object{
-		Type:    objType,
-		Size:    objSize,
-		Content: objContent,
-		Hash:    objHash,
-	}, nil
+	files, err := os.ReadDir(filepath.Join(s.objectsDir, prefix[:2]))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	for _, file := range files {
+		if hash := prefix[:2] + file.Name(); len(hash) == 2*sha1.Size && strings.HasPrefix(hash, prefix) {
+			hashes = append(hashes, hash)
+		}
+	}
+
+	if err := s.loadPacks(); err != nil {
+		return nil, err
+	}
+	for _, pack := range s.loadedPacks() {
+		hashes = append(hashes, pack.findPrefix(prefix)...)
+	}
+
+	sort.Strings(hashes)
+	return slices.Compact(hashes), nil
+}
+
+func isHex(s string) bool {
+	for _, c := range s {
+		if !('0' <= c && c <= '9' || 'a' <= c && c <= 'f') {
+			return false
+		}
+	}
+	return true
 }
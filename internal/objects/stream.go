@@ -0,0 +1,167 @@
+package objects
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Type and size of an object, as recorded in front of its content
+type ObjectHeader struct {
+	Type ObjectType
+	Size int64
+}
+
+// Formats the "type size\0" prefix objects are hashed and stored with
+func (h ObjectHeader) bytes() []byte {
+	return fmt.Appendf(nil, "%s %d\x00", h.Type, h.Size)
+}
+
+// Saves an object of size bytes read from r, hashing and compressing it in
+// a single pass so its content is never held in memory as a whole
+func (s *Store) StoreObjectFromReader(objType ObjectType, size int64, r io.Reader) (string, error) {
+	// 0755 ~~ rwxr-xr-x
+	if err := os.MkdirAll(s.objectsDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create object directory: %w", err)
+	}
+	tmp, err := os.CreateTemp(s.objectsDir, "tmp_obj_")
+	if err != nil {
+		return "", fmt.Errorf("failed to create object file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	hasher := sha1.New()
+	compressed := bufio.NewWriter(tmp)
+	writer := zlib.NewWriter(compressed)
+	out := io.MultiWriter(hasher, writer)
+
+	out.Write(ObjectHeader{Type: objType, Size: size}.bytes())
+	// One byte past size tells content that grew while it was read
+	copied, err := io.Copy(out, io.LimitReader(r, size+1))
+	if err != nil {
+		return "", fmt.Errorf("failed to read object content: %w", err)
+	}
+	if copied != size {
+		return "", fmt.Errorf("object content is %d bytes, expected %d (file changed while being read?)", copied, size)
+	}
+
+	if err := writer.Close(); err != nil {
+		return "", fmt.Errorf("failed to compress object: %w", err)
+	}
+	if err := compressed.Flush(); err != nil {
+		return "", fmt.Errorf("failed to write object file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return "", fmt.Errorf("failed to write object file: %w", err)
+	}
+
+	hash := hex.EncodeToString(hasher.Sum(nil))
+	if s.HasObject(hash) {
+		return hash, nil
+	}
+
+	subDir := filepath.Join(s.objectsDir, hash[:2])
+	if err := os.MkdirAll(subDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create object directory: %w", err)
+	}
+	// 0444 ~ read-only permissions for everyone
+	if err := os.Chmod(tmp.Name(), 0444); err != nil {
+		return "", err
+	}
+	if err := os.Rename(tmp.Name(), filepath.Join(subDir, hash[2:])); err != nil {
+		return "", fmt.Errorf("failed to write object file: %w", err)
+	}
+
+	return hash, nil
+}
+
+// Opens an object for reading its content as a stream. Loose objects are
+// decompressed as they are read; packed ones, which may be deltas, are
+// still reconstructed in memory.
+func (s *Store) OpenObject(objHash string) (ObjectHeader, io.ReadCloser, error) {
+	if len(objHash) != 2*sha1.Size || !isHex(objHash) {
+		return ObjectHeader{}, nil, fmt.Errorf("invalid object name: '%s'", objHash)
+	}
+
+	file, err := os.Open(filepath.Join(s.objectsDir, objHash[:2], objHash[2:]))
+	if os.IsNotExist(err) {
+		obj, packErr := s.loadPacked(objHash)
+		if packErr == nil {
+			header := ObjectHeader{Type: obj.Type, Size: obj.Size}
+			return header, io.NopCloser(bytes.NewReader(obj.Content)), nil
+		}
+		if !os.IsNotExist(packErr) {
+			return ObjectHeader{}, nil, packErr
+		}
+	}
+	if err != nil {
+		return ObjectHeader{}, nil, fmt.Errorf("object not found: %w", err)
+	}
+
+	decompressed, err := zlib.NewReader(bufio.NewReader(file))
+	if err != nil {
+		file.Close()
+		return ObjectHeader{}, nil, fmt.Errorf("failed to decompress object: %w", err)
+	}
+	reader := bufio.NewReader(decompressed)
+
+	header, err := readObjectHeader(reader)
+	if err != nil {
+		decompressed.Close()
+		file.Close()
+		return ObjectHeader{}, nil, err
+	}
+
+	return header, &objectReader{
+		content:      io.LimitReader(reader, header.Size),
+		remaining:    header.Size,
+		decompressed: decompressed,
+		file:         file,
+	}, nil
+}
+
+// Parses the "type size\0" prefix of a loose object
+func readObjectHeader(r *bufio.Reader) (ObjectHeader, error) {
+	line, err := r.ReadString(0)
+	if err != nil {
+		return ObjectHeader{}, fmt.Errorf("invalid object format")
+	}
+
+	objType, sizeStr, ok := strings.Cut(strings.TrimSuffix(line, "\x00"), " ")
+	size, err := strconv.ParseInt(sizeStr, 10, 64)
+	if !ok || err != nil || size < 0 {
+		return ObjectHeader{}, fmt.Errorf("failed to parse object header: %q", line)
+	}
+	return ObjectHeader{Type: ObjectType(objType), Size: size}, nil
+}
+
+// The content of a loose object, which must be as long as its header says
+type objectReader struct {
+	content      io.Reader
+	remaining    int64
+	decompressed io.ReadCloser
+	file         *os.File
+}
+
+func (r *objectReader) Read(p []byte) (int, error) {
+	n, err := r.content.Read(p)
+	r.remaining -= int64(n)
+	if err == io.EOF && r.remaining > 0 {
+		return n, fmt.Errorf("object content is truncated: %w", io.ErrUnexpectedEOF)
+	}
+	return n, err
+}
+
+func (r *objectReader) Close() error {
+	r.decompressed.Close()
+	return r.file.Close()
+}
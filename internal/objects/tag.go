@@ -0,0 +1,98 @@
+package objects
+
+import (
+	"fmt"
+	"strings"
+)
+
+// An annotated tag: a named, signed pointer to another object
+type Tag struct {
+	Object string     `json:"object"`
+	Type   ObjectType `json:"type"`
+	Name   string     `json:"tag"`
+	// Old tags may lack a tagger
+	Tagger *Signature `json:"tagger,omitempty"`
+	// Message without the newline that terminates it
+	Message string `json:"message"`
+
+	parsed     bool   // read from an object, so terminator is known
+	terminator string // what followed Message in the object ("\n" or "")
+}
+
+// Stores an annotated tag
+func (store *Store) WriteTag(tag *Tag) (string, error) {
+	if tag.Object == "" || tag.Type == "" {
+		return "", fmt.Errorf("tag must point to an object")
+	}
+	if tag.Name == "" {
+		return "", fmt.Errorf("tag name cannot be empty")
+	}
+	return store.StoreObject(TagObject, store.serializeTag(tag))
+}
+
+// Serialize to Git's tag format
+func (store *Store) serializeTag(tag *Tag) []byte {
+	var content strings.Builder
+
+	fmt.Fprintf(&content, "object %s\n", tag.Object)
+	fmt.Fprintf(&content, "type %s\n", tag.Type)
+	fmt.Fprintf(&content, "tag %s\n", tag.Name)
+	if tag.Tagger != nil {
+		fmt.Fprintf(&content, "tagger %s\n", tag.Tagger.Format())
+	}
+
+	content.WriteString("\n" + tag.Message)
+	if tag.parsed {
+		content.WriteString(tag.terminator)
+	} else if tag.Message != "" {
+		content.WriteString("\n")
+	}
+
+	return []byte(content.String())
+}
+
+func (store *Store) ParseTag(content []byte) (*Tag, error) {
+	tag := &Tag{}
+
+	// Headers end at the first empty line
+	headers, message, found := strings.Cut(string(content), "\n\n")
+	if !found {
+		headers = strings.TrimSuffix(headers, "\n")
+	}
+
+	for _, line := range strings.Split(headers, "\n") {
+		key, value, _ := strings.Cut(line, " ")
+
+		switch key {
+		case "object":
+			tag.Object = value
+		case "type":
+			tag.Type = ObjectType(value)
+		case "tag":
+			tag.Name = value
+		case "tagger":
+			tagger, err := ParseSignature(value)
+			if err != nil {
+				return nil, fmt.Errorf("bad tagger line: %w", err)
+			}
+			tag.Tagger = &tagger
+		}
+	}
+
+	if tag.Object == "" || tag.Type == "" {
+		return nil, fmt.Errorf("tag is missing its object or type")
+	}
+	if tag.Name == "" {
+		return nil, fmt.Errorf("tag has no name")
+	}
+
+	tag.parsed = true
+	if strings.HasSuffix(message, "\n") {
+		tag.Message = strings.TrimSuffix(message, "\n")
+		tag.terminator = "\n"
+	} else {
+		tag.Message = message
+	}
+
+	return tag, nil
+}
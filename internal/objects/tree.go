@@ -21,11 +21,9 @@ type Tree struct {
 	Entries []TreeEntry `json:"entries"`
 }
 
+// Stores the trees for a set of index entries and returns the root's hash;
+// no entries make the empty tree
 func (store *Store) CreateTreeFromIndex(entries map[string]*IndexEntry) (string, error) {
-	if len(entries) == 0 {
-		return "", fmt.Errorf("no entries to create tree from")
-	}
-
 	// Build dir structure
 	root := &TreeNode{
 		name:     "",
@@ -84,7 +82,15 @@ func (store *Store) storeTreeNode(node *TreeNode) (string, error) {
 	for name := range node.children {
 		names = append(names, name)
 	}
-	sort.Strings(names)
+
+	// Git compares directory names as if they ended in "/"
+	sortKey := func(name string) string {
+		if node.children[name].isDir {
+			return name + "/"
+		}
+		return name
+	}
+	sort.Slice(names, func(i, j int) bool { return sortKey(names[i]) < sortKey(names[j]) })
 
 	// Process child
 	for _, name := range names {
@@ -97,7 +103,7 @@ func (store *Store) storeTreeNode(node *TreeNode) (string, error) {
 			}
 
 			entries = append(entries, TreeEntry{
-				Mode: 0755, // Dir mode
+				Mode: os.ModeDir | 0755,
 				Name: name,
 				Hash: childHash,
 				Type: TreeObject,
@@ -123,12 +129,9 @@ func (store *Store) serializeTree(entries []TreeEntry) []byte {
 
 	for _, entry := range entries {
 		// Git tree format: "mode name\0hash"
-		// Mode is octal without leading zeros for files, padded for dirs
-		var modeStr string
+		modeStr := FormatMode(entry.Mode)
 		if entry.Type == TreeObject {
-			modeStr = "40000" // Directory
-		} else {
-			modeStr = fmt.Sprintf("%o", entry.Mode)
+			modeStr = ModeTree
 		}
 
 		line := fmt.Sprintf("%s %s\x00", modeStr, entry.Name)
@@ -162,23 +165,13 @@ func (store *Store) ParseTree(content []byte) (*Tree, error) {
 			}
 		}
 		if spaceIdx == -1 {
-			break
+			return nil, fmt.Errorf("malformed tree entry at offset %d", i)
 		}
 
 		// Parse mode
-		modeStr := string(content[i:spaceIdx])
-		var mode os.FileMode
-		var objType ObjectType
-
-		if modeStr == "40000" {
-			mode = 0755
-			objType = TreeObject
-		} else {
-			// Convert octal string to FileMode
-			if modeInt, err := fmt.Sscanf(modeStr, "%o", &mode); err != nil || modeInt != 1 {
-				return nil, fmt.Errorf("invalid mode: %s", modeStr)
-			}
-			objType = BlobObject
+		mode, objType, err := ParseMode(string(content[i:spaceIdx]))
+		if err != nil {
+			return nil, err
 		}
 
 		// Find the null byte that separates name from hash
@@ -190,7 +183,7 @@ func (store *Store) ParseTree(content []byte) (*Tree, error) {
 			}
 		}
 		if nullIdx == -1 {
-			break
+			return nil, fmt.Errorf("malformed tree entry at offset %d", i)
 		}
 
 		// Parse name
@@ -198,7 +191,7 @@ func (store *Store) ParseTree(content []byte) (*Tree, error) {
 
 		// Parse hash (20 bytes after null)
 		if nullIdx+20 >= len(content) {
-			break
+			return nil, fmt.Errorf("truncated tree entry at offset %d", i)
 		}
 		hashBytes := content[nullIdx+1 : nullIdx+21]
 		hash := fmt.Sprintf("%x", hashBytes)
@@ -215,3 +208,53 @@ func (store *Store) ParseTree(content []byte) (*Tree, error) {
 
 	return tree, nil
 }
+
+// Recursively reads a tree and returns its files keyed by slash-separated path
+func (store *Store) ReadTreeEntries(treeHash string) (map[string]*IndexEntry, error) {
+	entries := make(map[string]*IndexEntry)
+	if treeHash == "" {
+		return entries, nil
+	}
+
+	if err := store.readTreeEntries(treeHash, "", entries); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+func (store *Store) readTreeEntries(treeHash, basePath string, entries map[string]*IndexEntry) error {
+	treeObj, err := store.LoadObject(treeHash)
+	if err != nil {
+		return err
+	}
+	if treeObj.Type != TreeObject {
+		return fmt.Errorf("object %s is a %s, not a tree", treeHash, treeObj.Type)
+	}
+
+	tree, err := store.ParseTree(treeObj.Content)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range tree.Entries {
+		fullPath := entry.Name
+		if basePath != "" {
+			fullPath = basePath + "/" + entry.Name
+		}
+
+		if entry.Type == TreeObject {
+			if err := store.readTreeEntries(entry.Hash, fullPath, entries); err != nil {
+				return err
+			}
+			continue
+		}
+
+		entries[fullPath] = &IndexEntry{
+			Path: fullPath,
+			Hash: entry.Hash,
+			Mode: entry.Mode,
+		}
+	}
+
+	return nil
+}
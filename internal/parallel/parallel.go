@@ -0,0 +1,48 @@
+// Bounded fan-out of independent work, such as hashing and compressing the
+// files of a large tree
+package parallel
+
+import (
+	"errors"
+	"runtime"
+	"sync"
+)
+
+// How many goroutines Map runs at most; 1 does the work on the calling goroutine
+var Workers = runtime.GOMAXPROCS(0)
+
+// Calls fn for every item on up to Workers goroutines and returns the results
+// in the order of items. Every item is processed even if some fail; their
+// errors are joined in the same order.
+func Map[T, R any](items []T, fn func(T) (R, error)) ([]R, error) {
+	results := make([]R, len(items))
+	errs := make([]error, len(items))
+
+	workers := min(Workers, len(items))
+	if workers <= 1 {
+		for i, item := range items {
+			results[i], errs[i] = fn(item)
+		}
+		return results, errors.Join(errs...)
+	}
+
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				results[i], errs[i] = fn(items[i])
+			}
+		}()
+	}
+
+	for i := range items {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+
+	return results, errors.Join(errs...)
+}
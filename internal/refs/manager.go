@@ -5,27 +5,63 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
+	"time"
+
+	"minigit/internal/lockfile"
+	"minigit/internal/objects"
 )
 
 type Manager struct {
 	refsDir    string
 	minigitDir string
+	identity   func() objects.Signature // who reflog entries are recorded for
 }
 
 func NewManager(minigitDir string) (*Manager, error) {
 	return &Manager{
 		refsDir:    filepath.Join(minigitDir, "refs"),
 		minigitDir: minigitDir,
+		identity: func() objects.Signature {
+			return objects.Signature{Name: "unknown", Email: "unknown", When: time.Now()}
+		},
 	}, nil
 }
 
-// Updates HEAD
-func (m *Manager) SetHead(ref string) error {
+// Sets who ref updates are logged for, asked each time an entry is written
+func (m *Manager) SetIdentity(identity func() objects.Signature) {
+	m.identity = identity
+}
+
+// Points HEAD at a ref such as "refs/heads/main", or detaches it at a commit
+// hash, logging the move in HEAD's reflog with the given reason
+func (m *Manager) SetHead(ref, reason string) error {
+	oldCommit, err := m.ResolveHead()
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
 	headPath := filepath.Join(m.minigitDir, "HEAD")
 	content := fmt.Sprintf("ref: %s\n", ref)
+	if !strings.HasPrefix(ref, "refs/") {
+		// Git stores a detached HEAD as the bare hash
+		content = ref + "\n"
+	}
 	// 0644 ~ owners can read and write, others can only read
-	return os.WriteFile(headPath, []byte(content), 0644)
+	if err := lockfile.WriteFile(headPath, []byte(content), 0644); err != nil {
+		return err
+	}
+
+	newCommit, err := m.ResolveHead()
+	if err != nil {
+		return err
+	}
+	// Moving between unborn branches changes no commit worth recording
+	if oldCommit == "" && newCommit == "" {
+		return nil
+	}
+	return m.logUpdate("HEAD", oldCommit, newCommit, reason)
 }
 
 // Returns current HEAD reference
@@ -46,11 +82,102 @@ func (m *Manager) GetHead() (string, error) {
 	return headStr, nil
 }
 
-// Updates a branch to point to a specific commit
-func (m *Manager) SetBranch(branch, commit string) error {
+// Updates a branch to point to a specific commit, whatever it pointed to before
+func (m *Manager) SetBranch(branch, commit, reason string) error {
+	return m.UpdateRef("refs/heads/"+branch, commit, "", reason)
+}
+
+// Returned when a compare-and-swap ref update finds the ref has moved
+type RefChangedError struct {
+	Ref      string
+	Actual   string
+	Expected string
+}
+
+func (e *RefChangedError) Error() string {
+	return fmt.Sprintf("cannot lock ref '%s': is at %s but expected %s", e.Ref, e.Actual, e.Expected)
+}
+
+// Points a ref ("HEAD" or a full "refs/..." name) at newHash while holding
+// its lock. A non-empty expectedOldHash must match the current value, with
+// ZeroHash meaning the ref must not exist yet; otherwise a *RefChangedError
+// is returned and nothing changes. Updating HEAD on a branch updates the
+// branch. The update is logged with the given reason, except for tags.
+func (m *Manager) UpdateRef(name, newHash, expectedOldHash, reason string) error {
+	logHead := name == "HEAD"
+	if logHead {
+		head, err := m.GetHead()
+		if err != nil {
+			return err
+		}
+		if strings.HasPrefix(head, "refs/") {
+			name = head
+		}
+	} else if current, err := m.GetHead(); err == nil && current == name {
+		// Moving the checked out branch moves HEAD too
+		logHead = true
+	}
+
+	refPath := filepath.Join(m.minigitDir, filepath.FromSlash(name))
+	// Branch names such as "feature/x" live in subdirectories
+	if err := os.MkdirAll(filepath.Dir(refPath), 0755); err != nil {
+		return err
+	}
+
+	lock, err := lockfile.Acquire(refPath)
+	if err != nil {
+		return err
+	}
+
+	oldHash := ""
+	content, err := os.ReadFile(refPath)
+	if err == nil {
+		oldHash = strings.TrimSpace(string(content))
+	} else if !os.IsNotExist(err) {
+		lock.Rollback()
+		return err
+	}
+
+	if expectedOldHash != "" && expectedOldHash != oldHash && !(expectedOldHash == ZeroHash && oldHash == "") {
+		lock.Rollback()
+		actual := oldHash
+		if actual == "" {
+			actual = ZeroHash
+		}
+		return &RefChangedError{Ref: name, Actual: actual, Expected: expectedOldHash}
+	}
+
+	if _, err := lock.Write([]byte(newHash + "\n")); err != nil {
+		lock.Rollback()
+		return err
+	}
+
+	// Logging while the lock is held keeps entries in the order of the
+	// updates. Like Git, tags keep no history of their values.
+	if !strings.HasPrefix(name, "refs/tags/") {
+		if err := m.logUpdate(name, oldHash, newHash, reason); err != nil {
+			lock.Rollback()
+			return err
+		}
+	}
+	if logHead && name != "HEAD" {
+		if err := m.logUpdate("HEAD", oldHash, newHash, reason); err != nil {
+			lock.Rollback()
+			return err
+		}
+	}
+
+	return lock.Commit()
+}
+
+func (m *Manager) writeBranch(branch, commit string) error {
 	branchPath := filepath.Join(m.refsDir, "heads", branch)
+	// Branch names such as "feature/x" live in subdirectories
+	if err := os.MkdirAll(filepath.Dir(branchPath), 0755); err != nil {
+		return err
+	}
 	// 0644 ~ owners can read and write, others can only read
-	return os.WriteFile(branchPath, []byte(commit+"\n"), 0644)
+	return lockfile.WriteFile(branchPath, []byte(commit+"\n"), 0644)
 }
 
 // Returns the commit hash that a branch points to
@@ -64,3 +191,248 @@ func (m *Manager) GetBranch(branch string) (string, error) {
 
 	return strings.TrimSpace(string(content)), nil
 }
+
+// Returns the commit hash HEAD resolves to, empty if the current branch has no commits yet
+func (m *Manager) ResolveHead() (string, error) {
+	headRef, err := m.GetHead()
+	if err != nil {
+		return "", err
+	}
+
+	if !strings.HasPrefix(headRef, "refs/heads/") {
+		// Detached HEAD
+		return headRef, nil
+	}
+
+	commit, err := m.GetBranch(strings.TrimPrefix(headRef, "refs/heads/"))
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	return commit, err
+}
+
+// Points the current branch at commit, or HEAD itself when it is detached,
+// provided it is still at expectedOld (see UpdateRef)
+func (m *Manager) UpdateHead(commit, expectedOld, reason string) error {
+	return m.UpdateRef("HEAD", commit, expectedOld, reason)
+}
+
+// Returns the name of the checked out branch, empty when HEAD is detached
+func (m *Manager) CurrentBranch() (string, error) {
+	headRef, err := m.GetHead()
+	if err != nil {
+		return "", err
+	}
+
+	if strings.HasPrefix(headRef, "refs/heads/") {
+		return strings.TrimPrefix(headRef, "refs/heads/"), nil
+	}
+	return "", nil
+}
+
+// Checks whether a branch ref exists
+func (m *Manager) BranchExists(branch string) bool {
+	info, err := os.Stat(filepath.Join(m.refsDir, "heads", branch))
+	return err == nil && !info.IsDir()
+}
+
+// Returns the names of all branches, sorted
+func (m *Manager) ListBranches() ([]string, error) {
+	return m.listRefsUnder(filepath.Join(m.refsDir, "heads"))
+}
+
+// Returns the names of the refs below dir relative to it, sorted
+func (m *Manager) listRefsUnder(dir string) ([]string, error) {
+	var names []string
+
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		// Lock files of refs being updated, and stale ones moved aside, are not refs
+		if info.IsDir() || lockfile.IsLockFile(path) {
+			return nil
+		}
+
+		relPath, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		names = append(names, filepath.ToSlash(relPath))
+		return nil
+	})
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	sort.Strings(names)
+	return names, nil
+}
+
+// Returns the full names of all refs under refs/, sorted
+func (m *Manager) ListRefs() ([]string, error) {
+	var names []string
+
+	err := filepath.Walk(m.refsDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() || lockfile.IsLockFile(path) {
+			return nil
+		}
+
+		relPath, err := filepath.Rel(m.minigitDir, path)
+		if err != nil {
+			return err
+		}
+		names = append(names, filepath.ToSlash(relPath))
+		return nil
+	})
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	sort.Strings(names)
+	return names, nil
+}
+
+// Removes a branch ref
+func (m *Manager) DeleteBranch(branch string) error {
+	headsDir := filepath.Join(m.refsDir, "heads")
+	branchPath := filepath.Join(headsDir, branch)
+	if err := os.Remove(branchPath); err != nil {
+		return err
+	}
+	m.pruneEmptyDirs(filepath.Dir(branchPath), headsDir)
+
+	// The branch's history goes with it
+	logPath := m.reflogPath("refs/heads/" + branch)
+	if err := os.Remove(logPath); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	m.pruneEmptyDirs(filepath.Dir(logPath), filepath.Join(m.minigitDir, "logs", "refs", "heads"))
+	return nil
+}
+
+// Renames a branch along with its reflog, moving HEAD along if it pointed at the old name
+func (m *Manager) RenameBranch(oldName, newName string) error {
+	commit, err := m.GetBranch(oldName)
+	if err != nil {
+		return err
+	}
+	if m.BranchExists(newName) {
+		return fmt.Errorf("a branch named '%s' already exists", newName)
+	}
+
+	// The old ref and its reflog are removed first: "a" may become "a/b",
+	// whose files sit where the old ones are
+	oldLog, newLog := m.reflogPath("refs/heads/"+oldName), m.reflogPath("refs/heads/"+newName)
+	history, err := os.ReadFile(oldLog)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err := m.DeleteBranch(oldName); err != nil {
+		return err
+	}
+
+	if err := m.writeBranch(newName, commit); err != nil {
+		return err
+	}
+	if history != nil {
+		// 0755 ~~ rwxr-xr-x
+		if err := os.MkdirAll(filepath.Dir(newLog), 0755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(newLog, history, 0644); err != nil {
+			return err
+		}
+	}
+
+	reason := fmt.Sprintf("Branch: renamed refs/heads/%s to refs/heads/%s", oldName, newName)
+	if err := m.logUpdate("refs/heads/"+newName, commit, commit, reason); err != nil {
+		return err
+	}
+
+	current, err := m.CurrentBranch()
+	if err != nil {
+		return err
+	}
+	if current == oldName {
+		return m.SetHead("refs/heads/"+newName, reason)
+	}
+	return nil
+}
+
+// Reports whether name is usable as a branch name (a subset of git check-ref-format)
+func ValidBranchName(name string) bool {
+	if name == "" || name == "@" || name == "HEAD" {
+		return false
+	}
+	if strings.HasPrefix(name, "-") || strings.HasSuffix(name, "/") || strings.HasSuffix(name, ".lock") {
+		return false
+	}
+	if strings.Contains(name, "..") || strings.Contains(name, "@{") || strings.Contains(name, "//") {
+		return false
+	}
+	if strings.ContainsAny(name, " ~^:?*[\\") {
+		return false
+	}
+
+	for _, r := range name {
+		if r < 0x20 || r == 0x7f {
+			return false
+		}
+	}
+
+	for part := range strings.SplitSeq(name, "/") {
+		if part == "" || strings.HasPrefix(part, ".") {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Removes empty directories from dir up to (but excluding) stop
+func (m *Manager) pruneEmptyDirs(dir, stop string) {
+	for dir != stop && strings.HasPrefix(dir, stop) {
+		if err := os.Remove(dir); err != nil {
+			return
+		}
+		dir = filepath.Dir(dir)
+	}
+}
+
+// Search order for short ref names, as in git rev-parse
+var refSearchPatterns = []string{"%s", "refs/%s", "refs/tags/%s", "refs/heads/%s", "refs/remotes/%s", "refs/remotes/%s/HEAD"}
+
+// Expands a short name such as "main" or "tags/v1" to the first existing ref
+// and returns its full name and the hash it points to
+func (m *Manager) ResolveRef(name string) (string, string, error) {
+	if name == "" || strings.Contains(name, "..") || strings.HasPrefix(name, "/") {
+		return "", "", os.ErrNotExist
+	}
+
+	for _, pattern := range refSearchPatterns {
+		fullName := fmt.Sprintf(pattern, name)
+
+		if fullName == "HEAD" {
+			hash, err := m.ResolveHead()
+			if err != nil || hash == "" {
+				continue
+			}
+			return "HEAD", hash, nil
+		}
+		if !strings.HasPrefix(fullName, "refs/") {
+			continue
+		}
+
+		content, err := os.ReadFile(filepath.Join(m.minigitDir, filepath.FromSlash(fullName)))
+		if err != nil {
+			continue
+		}
+		return fullName, strings.TrimSpace(string(content)), nil
+	}
+
+	return "", "", os.ErrNotExist
+}
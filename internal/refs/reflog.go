@@ -0,0 +1,125 @@
+package refs
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"minigit/internal/objects"
+)
+
+// Hash recorded as the old value of a ref that did not exist yet
+const ZeroHash = "0000000000000000000000000000000000000000"
+
+// One line of a ref's log: what it pointed at before and after an update
+type ReflogEntry struct {
+	Old       string
+	New       string
+	Committer objects.Signature
+	Message   string
+}
+
+// Appends an entry to the log of a ref ("HEAD" or a full "refs/..." name),
+// in Git's format: "<old> <new> <name> <<email>> <time> <zone>\t<message>"
+func (m *Manager) AppendReflog(ref string, entry ReflogEntry) error {
+	logPath := m.reflogPath(ref)
+	// 0755 ~~ rwxr-xr-x
+	if err := os.MkdirAll(filepath.Dir(logPath), 0755); err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(logPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if entry.Old == "" {
+		entry.Old = ZeroHash
+	}
+	if entry.New == "" {
+		entry.New = ZeroHash
+	}
+	// Messages are single lines
+	message := strings.ReplaceAll(strings.TrimSpace(entry.Message), "\n", " ")
+
+	_, err = fmt.Fprintf(file, "%s %s %s\t%s\n", entry.Old, entry.New, entry.Committer.Format(), message)
+	return err
+}
+
+// Reads the log of a ref, oldest entry first. A ref without a log has no entries.
+func (m *Manager) ReadReflog(ref string) ([]ReflogEntry, error) {
+	file, err := os.Open(m.reflogPath(ref))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var entries []ReflogEntry
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			continue
+		}
+
+		header, message, _ := strings.Cut(line, "\t")
+		fields := strings.SplitN(header, " ", 3)
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("malformed reflog line for %s: %q", ref, line)
+		}
+		committer, err := objects.ParseSignature(fields[2])
+		if err != nil {
+			return nil, fmt.Errorf("malformed reflog line for %s: %w", ref, err)
+		}
+
+		entries = append(entries, ReflogEntry{Old: fields[0], New: fields[1], Committer: committer, Message: message})
+	}
+
+	return entries, scanner.Err()
+}
+
+// Returns the branch (or commit) checked out before the n-th most recent
+// checkout, found from "checkout: moving from A to B" entries in HEAD's log
+func (m *Manager) PreviousBranch(n int) (string, error) {
+	entries, err := m.ReadReflog("HEAD")
+	if err != nil {
+		return "", err
+	}
+
+	for i := len(entries) - 1; i >= 0; i-- {
+		rest, ok := strings.CutPrefix(entries[i].Message, "checkout: moving from ")
+		if !ok {
+			continue
+		}
+		if n--; n > 0 {
+			continue
+		}
+
+		from, _, found := strings.Cut(rest, " to ")
+		if !found {
+			break
+		}
+		return from, nil
+	}
+
+	return "", fmt.Errorf("not enough checkouts in the history of HEAD")
+}
+
+// Appends an entry for an update of ref made by the current identity
+func (m *Manager) logUpdate(ref, oldCommit, newCommit, reason string) error {
+	entry := ReflogEntry{Old: oldCommit, New: newCommit, Committer: m.identity(), Message: reason}
+	if err := m.AppendReflog(ref, entry); err != nil {
+		return fmt.Errorf("failed to update reflog of %s: %w", ref, err)
+	}
+	return nil
+}
+
+func (m *Manager) reflogPath(ref string) string {
+	return filepath.Join(m.minigitDir, "logs", filepath.FromSlash(ref))
+}
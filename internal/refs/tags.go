@@ -0,0 +1,45 @@
+package refs
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Returns the object a tag points to: a commit for lightweight tags, a tag
+// object for annotated ones
+func (m *Manager) GetTag(tag string) (string, error) {
+	content, err := os.ReadFile(filepath.Join(m.refsDir, "tags", tag))
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(content)), nil
+}
+
+// Checks whether a tag ref exists
+func (m *Manager) TagExists(tag string) bool {
+	info, err := os.Stat(filepath.Join(m.refsDir, "tags", tag))
+	return err == nil && !info.IsDir()
+}
+
+// Returns the names of all tags, sorted
+func (m *Manager) ListTags() ([]string, error) {
+	return m.listRefsUnder(filepath.Join(m.refsDir, "tags"))
+}
+
+// Removes a tag ref
+func (m *Manager) DeleteTag(tag string) error {
+	tagsDir := filepath.Join(m.refsDir, "tags")
+	tagPath := filepath.Join(tagsDir, tag)
+	if err := os.Remove(tagPath); err != nil {
+		return err
+	}
+	m.pruneEmptyDirs(filepath.Dir(tagPath), tagsDir)
+	return nil
+}
+
+// Reports whether name is usable as a tag name, which follows the same
+// rules as branch names
+func ValidTagName(name string) bool {
+	return ValidBranchName(name)
+}
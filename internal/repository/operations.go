@@ -2,6 +2,7 @@ package repository
 
 import (
 	"fmt"
+	"minigit/internal/config"
 	"minigit/internal/index"
 	"minigit/internal/objects"
 	"minigit/internal/refs"
@@ -12,7 +13,8 @@ func (repo *Repository) AddToIndex(path, hash string, info os.FileInfo) error {
 	if repo.index == nil {
 		return fmt.Errorf("index not initialized")
 	}
-	return repo.index.AddEntry(path, hash, info)
+	repo.index.AddEntry(path, hash, info)
+	return nil
 }
 
 func (repo *Repository) GetIndex() (*index.Index, error) {
@@ -36,6 +38,13 @@ func (repo *Repository) GetRefsManager() (*refs.Manager, error) {
 	return repo.refs, nil
 }
 
+func (repo *Repository) GetConfig() (*config.Config, error) {
+	if repo.config == nil {
+		return nil, fmt.Errorf("config not initialized")
+	}
+	return repo.config, nil
+}
+
 func (repo *Repository) GetWorkingDirectory() string {
 	return repo.workDir
 }
@@ -43,3 +52,46 @@ func (repo *Repository) GetWorkingDirectory() string {
 func (repo *Repository) GetMinigitDirectory() string {
 	return repo.minigitDir
 }
+
+// The index is a snapshot of every tracked file, but versions that cleared
+// it after each commit left it empty, so such an index is seeded from HEAD's
+// tree. Any other empty index means every file was removed.
+func (repo *Repository) seedIndexFromHead() error {
+	if !repo.index.IsLegacy() || !repo.index.IsEmpty() {
+		return nil
+	}
+
+	head, err := repo.refs.ResolveHead()
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil // not initialized yet
+		}
+		return err
+	}
+	if head == "" {
+		return nil
+	}
+
+	commitObj, err := repo.objects.LoadObject(head)
+	if err != nil {
+		return err
+	}
+
+	commit, err := repo.objects.ParseCommit(commitObj.Content)
+	if err != nil {
+		return err
+	}
+
+	files, err := repo.objects.ReadTreeEntries(commit.Tree)
+	if err != nil {
+		return err
+	}
+
+	entries := make([]*index.Entry, 0, len(files))
+	for path, file := range files {
+		entries = append(entries, &index.Entry{Path: path, Hash: file.Hash, Mode: file.Mode})
+	}
+
+	repo.index.Reset(entries)
+	return nil
+}
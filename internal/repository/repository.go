@@ -50,6 +50,14 @@ func NewRepository(path string) (*Repository, error) {
 	if repo.config, err = config.NewConfig(minigitDir); err != nil {
 		return nil, fmt.Errorf("failed to initialize config: %w", err)
 	}
+	trustExecutable, err := repo.config.GetBool("core.filemode", true)
+	if err != nil {
+		return nil, err
+	}
+	repo.index.SetTrustExecutableBit(trustExecutable)
+	if err := repo.seedIndexFromHead(); err != nil {
+		return nil, fmt.Errorf("failed to initialize index: %w", err)
+	}
 
 	return repo, nil
 }
@@ -71,6 +79,18 @@ func (r *Repository) Initialize() error {
 		}
 	}
 
+	// Same defaults Git writes on init
+	defaults := [][2]string{
+		{"core.repositoryformatversion", "0"},
+		{"core.filemode", "true"},
+		{"core.bare", "false"},
+	}
+	for _, setting := range defaults {
+		if err := r.config.Set(config.ScopeLocal, setting[0], setting[1]); err != nil {
+			return fmt.Errorf("failed to write config: %w", err)
+		}
+	}
+
 	// Initialize HEAD to point to main branch
-	return r.refs.SetHead("refs/heads/main")
+	return r.refs.SetHead("refs/heads/main", "")
 }
@@ -0,0 +1,392 @@
+// Revision expressions: names, abbreviated hashes, ancestry and peeling
+// suffixes, tree paths and commit ranges
+package revparse
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"minigit/internal/objects"
+	"minigit/internal/refs"
+)
+
+// Shortest hash prefix accepted as an abbreviation
+const MinAbbrev = 4
+
+var hexPattern = regexp.MustCompile(`^[0-9a-f]+$`)
+
+// "<ref>@{n}", the n-th prior value of a ref
+var reflogPattern = regexp.MustCompile(`^(.*)@\{([0-9]+)\}$`)
+
+// Resolves revision expressions against a repository's objects and refs
+type Resolver struct {
+	store *objects.Store
+	refs  *refs.Manager
+}
+
+func New(store *objects.Store, refsMan *refs.Manager) *Resolver {
+	return &Resolver{store: store, refs: refsMan}
+}
+
+// Returned when an expression names nothing in the repository
+type UnknownRevisionError struct {
+	Rev string
+}
+
+func (e *UnknownRevisionError) Error() string {
+	return fmt.Sprintf("fatal: ambiguous argument '%s': unknown revision or path not in the working tree", e.Rev)
+}
+
+// Resolves an expression to the hash of the object it names:
+//
+//	<name>     HEAD, @, a branch, tag or full ref, or an abbreviated hash
+//	@{-n}      the n-th branch checked out before the current one
+//	<ref>@{n}  the n-th prior value of ref (of the current branch without one)
+//	<rev>~n    the n-th first-parent ancestor
+//	<rev>^n    the n-th parent (^0 is the commit itself)
+//	<rev>^{t}  the object peeled to type t (commit, tree, blob, tag; {} for any but a tag)
+//	<rev>:path the blob or tree at path in rev's tree
+func (r *Resolver) Resolve(expr string) (string, error) {
+	if expr == "" {
+		return "", &UnknownRevisionError{Rev: expr}
+	}
+
+	rev, path, hasPath := strings.Cut(expr, ":")
+	if hasPath {
+		if rev == "" {
+			return "", fmt.Errorf("fatal: index paths (':%s') are not supported", path)
+		}
+		hash, err := r.Resolve(rev)
+		if err != nil {
+			return "", err
+		}
+		return r.lookupPath(hash, rev, path)
+	}
+
+	// The base name runs up to the first suffix operator
+	end := strings.IndexAny(expr, "^~")
+	if end < 0 {
+		end = len(expr)
+	}
+	hash, err := r.resolveName(expr[:end])
+	if err != nil {
+		if _, unknown := err.(*UnknownRevisionError); unknown {
+			return "", &UnknownRevisionError{Rev: expr}
+		}
+		return "", err
+	}
+
+	for suffix := expr[end:]; suffix != ""; {
+		op := suffix[0]
+		suffix = suffix[1:]
+
+		// ^{type} peels rather than walking history
+		if op == '^' && strings.HasPrefix(suffix, "{") {
+			close := strings.IndexByte(suffix, '}')
+			if close < 0 {
+				return "", &UnknownRevisionError{Rev: expr}
+			}
+			if hash, err = r.peel(hash, suffix[1:close]); err != nil {
+				return "", err
+			}
+			suffix = suffix[close+1:]
+			continue
+		}
+
+		digits := len(suffix) - len(strings.TrimLeft(suffix, "0123456789"))
+		n := 1
+		if digits > 0 {
+			if n, err = strconv.Atoi(suffix[:digits]); err != nil {
+				return "", &UnknownRevisionError{Rev: expr}
+			}
+			suffix = suffix[digits:]
+		}
+
+		if op == '^' {
+			hash, err = r.parent(hash, n)
+		} else {
+			hash, err = r.ancestor(hash, n)
+		}
+		if err != nil {
+			return "", &UnknownRevisionError{Rev: expr}
+		}
+	}
+
+	return hash, nil
+}
+
+// Resolves an expression and peels the result to a commit
+func (r *Resolver) ResolveCommit(expr string) (string, error) {
+	hash, err := r.Resolve(expr)
+	if err != nil {
+		return "", err
+	}
+	commit, err := r.peel(hash, string(objects.CommitObject))
+	if err != nil {
+		return "", fmt.Errorf("fatal: '%s' is not a commit", expr)
+	}
+	return commit, nil
+}
+
+// Resolves a name without suffixes
+func (r *Resolver) resolveName(name string) (string, error) {
+	if name == "@" {
+		name = "HEAD"
+	}
+
+	if strings.HasPrefix(name, "@{-") {
+		previous, err := r.ExpandPrevious(name)
+		if err != nil {
+			return "", err
+		}
+		return r.resolveName(previous)
+	}
+
+	if match := reflogPattern.FindStringSubmatch(name); match != nil {
+		n, err := strconv.Atoi(match[2])
+		if err != nil {
+			return "", &UnknownRevisionError{Rev: name}
+		}
+		return r.reflogEntry(match[1], n)
+	}
+
+	// A full hash names the object directly
+	if len(name) == 40 && hexPattern.MatchString(name) {
+		if r.store.HasObject(name) {
+			return name, nil
+		}
+		return "", &UnknownRevisionError{Rev: name}
+	}
+
+	// Refs win over abbreviated hashes that happen to look the same
+	if _, hash, err := r.refs.ResolveRef(name); err == nil {
+		return hash, nil
+	} else if !os.IsNotExist(err) {
+		return "", err
+	}
+
+	if len(name) >= MinAbbrev && hexPattern.MatchString(name) {
+		return r.expandAbbrev(name)
+	}
+
+	return "", &UnknownRevisionError{Rev: name}
+}
+
+// Returns the branch name or commit hash that "@{-n}" stands for; any other
+// name is returned unchanged
+func (r *Resolver) ExpandPrevious(name string) (string, error) {
+	rest, ok := strings.CutPrefix(name, "@{-")
+	if !ok || !strings.HasSuffix(rest, "}") {
+		return name, nil
+	}
+
+	n, err := strconv.Atoi(strings.TrimSuffix(rest, "}"))
+	if err != nil || n < 1 {
+		return "", &UnknownRevisionError{Rev: name}
+	}
+	previous, err := r.refs.PreviousBranch(n)
+	if err != nil {
+		return "", fmt.Errorf("fatal: %s: %w", name, err)
+	}
+	return previous, nil
+}
+
+// Returns what a ref pointed to n updates ago, according to its reflog. A
+// bare "@{n}" is about the current branch, or HEAD when detached.
+func (r *Resolver) reflogEntry(ref string, n int) (string, error) {
+	fullName := "HEAD"
+	if ref == "" {
+		branch, err := r.refs.CurrentBranch()
+		if err != nil {
+			return "", err
+		}
+		if branch != "" {
+			fullName = "refs/heads/" + branch
+		}
+	} else if ref != "HEAD" {
+		var err error
+		if fullName, _, err = r.refs.ResolveRef(ref); err != nil {
+			return "", &UnknownRevisionError{Rev: fmt.Sprintf("%s@{%d}", ref, n)}
+		}
+	}
+
+	entries, err := r.refs.ReadReflog(fullName)
+	if err != nil {
+		return "", err
+	}
+	if n >= len(entries) {
+		return "", fmt.Errorf("fatal: log for '%s' only has %d entries", strings.TrimPrefix(fullName, "refs/heads/"), len(entries))
+	}
+
+	hash := entries[len(entries)-1-n].New
+	if hash == refs.ZeroHash {
+		return "", fmt.Errorf("fatal: %s@{%d} was deleted", ref, n)
+	}
+	return hash, nil
+}
+
+// Expands an abbreviated hash that matches exactly one object
+func (r *Resolver) expandAbbrev(prefix string) (string, error) {
+	matches, err := r.store.FindByPrefix(prefix)
+	if err != nil {
+		return "", err
+	}
+
+	switch len(matches) {
+	case 0:
+		return "", &UnknownRevisionError{Rev: prefix}
+	case 1:
+		return matches[0], nil
+	}
+
+	var msg strings.Builder
+	fmt.Fprintf(&msg, "error: short object ID %s is ambiguous\nhint: The candidates are:\n", prefix)
+	for _, hash := range matches {
+		objType := "unknown"
+		if obj, err := r.store.LoadObject(hash); err == nil {
+			objType = string(obj.Type)
+		}
+		fmt.Fprintf(&msg, "hint:   %s %s\n", hash[:len(prefix)+3], objType)
+	}
+	fmt.Fprintf(&msg, "fatal: ambiguous argument '%s': unknown revision or path not in the working tree", prefix)
+	return "", fmt.Errorf("%s", msg.String())
+}
+
+// Returns the shortest prefix of hash, at least minLength long, that names no other object
+func (r *Resolver) Abbreviate(hash string, minLength int) (string, error) {
+	minLength = max(minLength, MinAbbrev)
+	for length := minLength; length < len(hash); length++ {
+		matches, err := r.store.FindByPrefix(hash[:length])
+		if err != nil {
+			return "", err
+		}
+		if len(matches) <= 1 {
+			return hash[:length], nil
+		}
+	}
+	return hash, nil
+}
+
+// Peels an object to the given type, following tags; an empty type
+// accepts the first object that is not a tag
+func (r *Resolver) peel(hash, objType string) (string, error) {
+	obj, err := r.store.LoadObject(hash)
+	if err != nil {
+		return "", err
+	}
+
+	switch {
+	case objType == string(obj.Type):
+		return hash, nil
+	case obj.Type == objects.TagObject:
+		tag, err := r.store.ParseTag(obj.Content)
+		if err != nil {
+			return "", err
+		}
+		return r.peel(tag.Object, objType)
+	case objType == "":
+		return hash, nil
+	case objType == string(objects.TreeObject) && obj.Type == objects.CommitObject:
+		commit, err := r.store.ParseCommit(obj.Content)
+		if err != nil {
+			return "", err
+		}
+		return commit.Tree, nil
+	}
+
+	return "", fmt.Errorf("fatal: %s is a %s, not a %s", hash, obj.Type, objType)
+}
+
+// Returns the n-th parent of a commit; the 0th is the commit itself
+func (r *Resolver) parent(hash string, n int) (string, error) {
+	hash, err := r.peel(hash, string(objects.CommitObject))
+	if err != nil {
+		return "", err
+	}
+	obj, err := r.store.LoadObject(hash)
+	if err != nil {
+		return "", err
+	}
+	commit, err := r.store.ParseCommit(obj.Content)
+	if err != nil {
+		return "", err
+	}
+	if n == 0 {
+		return hash, nil
+	}
+	if n > len(commit.Parents) {
+		return "", fmt.Errorf("commit %s has no parent %d", hash, n)
+	}
+	return commit.Parents[n-1], nil
+}
+
+// Follows first parents n times
+func (r *Resolver) ancestor(hash string, n int) (string, error) {
+	for ; n > 0; n-- {
+		var err error
+		if hash, err = r.parent(hash, 1); err != nil {
+			return "", err
+		}
+	}
+	return r.parent(hash, 0)
+}
+
+// Finds the object at a slash-separated path inside rev's tree
+func (r *Resolver) lookupPath(hash, rev, path string) (string, error) {
+	hash, err := r.peel(hash, string(objects.TreeObject))
+	if err != nil {
+		return "", err
+	}
+
+	for _, part := range strings.Split(strings.Trim(path, "/"), "/") {
+		if part == "" {
+			continue
+		}
+
+		obj, err := r.store.LoadObject(hash)
+		if err != nil {
+			return "", err
+		}
+		if obj.Type != objects.TreeObject {
+			return "", fmt.Errorf("fatal: path '%s' does not exist in '%s'", path, rev)
+		}
+		tree, err := r.store.ParseTree(obj.Content)
+		if err != nil {
+			return "", err
+		}
+
+		found := false
+		for _, entry := range tree.Entries {
+			if entry.Name == part {
+				hash, found = entry.Hash, true
+				break
+			}
+		}
+		if !found {
+			return "", fmt.Errorf("fatal: path '%s' does not exist in '%s'", path, rev)
+		}
+	}
+
+	return hash, nil
+}
+
+// Splits "A..B" or "A...B" into its two ends, either of which defaults to
+// HEAD when left out. ok is false when expr is not a range.
+func SplitRange(expr string) (from, to string, symmetric, ok bool) {
+	if from, to, ok = strings.Cut(expr, "..."); ok {
+		symmetric = true
+	} else if from, to, ok = strings.Cut(expr, ".."); !ok {
+		return "", "", false, false
+	}
+
+	if from == "" {
+		from = "HEAD"
+	}
+	if to == "" {
+		to = "HEAD"
+	}
+	return from, to, symmetric, true
+}
@@ -1,6 +1,7 @@
 package fixtures
 
 import (
+	"io"
 	"os"
 	"path/filepath"
 	"testing"
@@ -10,23 +11,37 @@ import (
 
 // InitRepo creates a temp directory, initializes a minigit repo there,
 // and returns the repo path.
-func InitRepo(t *testing.T) string {
+func InitRepo(t testing.TB) string {
 	t.Helper()
 
 	tempDir := t.TempDir()
 	repoPath := filepath.Join(tempDir, "test_repo")
 
+	// Keep the user's ~/.minigitconfig out of tests
+	t.Setenv("HOME", tempDir)
+
 	// init via CLI
 	os.Args = []string{"minigit", "init", repoPath}
 	if err := cli.Execute(); err != nil {
 		t.Fatalf("fixtures.InitRepo: init failed: %v", err)
 	}
 
+	configPath := filepath.Join(repoPath, ".minigit", "config")
+	identity := "[user]\n\tname = Test User\n\temail = test@example.com\n"
+	f, err := os.OpenFile(configPath, os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		t.Fatalf("fixtures.InitRepo: open config: %v", err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(identity); err != nil {
+		t.Fatalf("fixtures.InitRepo: write config: %v", err)
+	}
+
 	return repoPath
 }
 
 // CreateFiles makes all dirs and writes files under basePath.
-func CreateFiles(t *testing.T, basePath string, files map[string]string) {
+func CreateFiles(t testing.TB, basePath string, files map[string]string) {
 	t.Helper()
 
 	for relPath, content := range files {
@@ -41,7 +56,7 @@ func CreateFiles(t *testing.T, basePath string, files map[string]string) {
 }
 
 // Chdir switches cwd to dir for the duration of the test.
-func Chdir(t *testing.T, dir string) func() {
+func Chdir(t testing.TB, dir string) func() {
 	t.Helper()
 
 	orig, err := os.Getwd()
@@ -57,7 +72,7 @@ func Chdir(t *testing.T, dir string) func() {
 }
 
 // RunCLI runs `minigit` with the given args
-func RunCLI(t *testing.T, args ...string) {
+func RunCLI(t testing.TB, args ...string) {
 	t.Helper()
 
 	cmd := append([]string{"minigit"}, args...)
@@ -69,10 +84,78 @@ func RunCLI(t *testing.T, args ...string) {
 
 // TryCLI runs `minigit` with the given args and returns the error.
 // Use this when we want to assert on the error instead of failing immediately.
-func TryCLI(t *testing.T, args ...string) error {
+func TryCLI(t testing.TB, args ...string) error {
 	t.Helper()
 
 	cmd := append([]string{"minigit"}, args...)
 	os.Args = cmd
 	return cli.Execute()
 }
+
+// CommitFile writes a single file under repoPath, stages it and commits it.
+func CommitFile(t testing.TB, repoPath, name, content, message string) {
+	t.Helper()
+
+	CreateFiles(t, repoPath, map[string]string{name: content})
+	RunCLI(t, "add", name)
+	RunCLI(t, "commit", "-m", message)
+}
+
+// CaptureCLI runs `minigit` with the given args and returns what it printed to stdout.
+func CaptureCLI(t testing.TB, args ...string) string {
+	t.Helper()
+
+	out, err := TryCaptureCLI(t, args...)
+	if err != nil {
+		t.Fatalf("fixtures.CaptureCLI %v: %v", args, err)
+	}
+	return out
+}
+
+// TryCaptureCLI is like CaptureCLI but returns the error instead of failing.
+func TryCaptureCLI(t testing.TB, args ...string) (string, error) {
+	t.Helper()
+
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("fixtures.TryCaptureCLI: pipe: %v", err)
+	}
+
+	output := make(chan string)
+	go func() {
+		data, _ := io.ReadAll(reader)
+		output <- string(data)
+	}()
+
+	orig := os.Stdout
+	os.Stdout = writer
+	runErr := TryCLI(t, args...)
+	os.Stdout = orig
+
+	writer.Close()
+	out := <-output
+	reader.Close()
+
+	return out, runErr
+}
+
+// SetStdin makes os.Stdin read the given content for the rest of the test.
+func SetStdin(t testing.TB, content string) {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "stdin")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("fixtures.SetStdin: %v", err)
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("fixtures.SetStdin: %v", err)
+	}
+
+	orig := os.Stdin
+	os.Stdin = file
+	t.Cleanup(func() {
+		os.Stdin = orig
+		file.Close()
+	})
+}
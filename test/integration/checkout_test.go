@@ -0,0 +1,80 @@
+package integration
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"minigit/test/fixtures"
+)
+
+func TestCheckoutReplacesDirectoryWithFile(t *testing.T) {
+	repoPath := fixtures.InitRepo(t)
+	cleanup := fixtures.Chdir(t, repoPath)
+	defer cleanup()
+
+	fixtures.CommitFile(t, repoPath, "a/b", "in a directory\n", "Add a/b")
+	fixtures.RunCLI(t, "checkout", "-b", "other")
+	replacePath(t, repoPath, "a", "a file now\n", "Make a a file")
+
+	fixtures.RunCLI(t, "checkout", "main")
+	verifyCheckout(t, repoPath, map[string]string{"a/b": "in a directory\n"}, "a/b\n")
+
+	fixtures.RunCLI(t, "checkout", "other")
+	verifyCheckout(t, repoPath, map[string]string{"a": "a file now\n"}, "a\n")
+}
+
+func TestCheckoutReplacesFileWithDirectory(t *testing.T) {
+	repoPath := fixtures.InitRepo(t)
+	cleanup := fixtures.Chdir(t, repoPath)
+	defer cleanup()
+
+	fixtures.CommitFile(t, repoPath, "a", "a file\n", "Add a")
+	fixtures.RunCLI(t, "checkout", "-b", "other")
+	replacePath(t, repoPath, "a/b", "in a directory now\n", "Make a a directory")
+
+	fixtures.RunCLI(t, "checkout", "main")
+	verifyCheckout(t, repoPath, map[string]string{"a": "a file\n"}, "a\n")
+
+	fixtures.RunCLI(t, "checkout", "other")
+	verifyCheckout(t, repoPath, map[string]string{"a/b": "in a directory now\n"}, "a/b\n")
+}
+
+// Commits the removal of whatever is at the top of name, then a file there
+func replacePath(t *testing.T, repoPath, name, content, message string) {
+	t.Helper()
+
+	top, _, _ := strings.Cut(name, "/")
+	if err := os.RemoveAll(filepath.Join(repoPath, top)); err != nil {
+		t.Fatal(err)
+	}
+	fixtures.RunCLI(t, "add", ".")
+	fixtures.RunCLI(t, "commit", "-m", "Remove "+top)
+
+	fixtures.CreateFiles(t, repoPath, map[string]string{name: content})
+	fixtures.RunCLI(t, "add", ".")
+	fixtures.RunCLI(t, "commit", "-m", message)
+}
+
+// Checks the working tree files, the index and that nothing is left to commit
+func verifyCheckout(t *testing.T, repoPath string, files map[string]string, lsFiles string) {
+	t.Helper()
+
+	for name, want := range files {
+		got, err := os.ReadFile(filepath.Join(repoPath, name))
+		if err != nil {
+			t.Fatalf("read %s: %v", name, err)
+		}
+		if string(got) != want {
+			t.Fatalf("%s = %q, want %q", name, got, want)
+		}
+	}
+
+	if out := fixtures.CaptureCLI(t, "ls-files"); out != lsFiles {
+		t.Fatalf("ls-files = %q, want %q", out, lsFiles)
+	}
+	if out := fixtures.CaptureCLI(t, "status"); !strings.Contains(out, "working tree clean") {
+		t.Fatalf("checkout should leave a clean tree:\n%s", out)
+	}
+}
@@ -54,7 +54,7 @@ func TestMultipleCommits(t *testing.T) {
 	verifyMultipleCommitsState(t, repoPath)
 }
 
-func TestCommitClearsIndex(t *testing.T) {
+func TestCommitKeepsIndexSnapshot(t *testing.T) {
 	repoPath := fixtures.InitRepo(t)
 	cleanup := fixtures.Chdir(t, repoPath)
 	defer cleanup()
@@ -72,15 +72,15 @@ func TestCommitClearsIndex(t *testing.T) {
 	// commit
 	fixtures.RunCLI(t, "commit", "-m", "Test commit")
 
-	// post-check
+	// post-check: the index still tracks the committed file
 	repo2, _ := repository.NewRepository(repoPath)
 	index2, _ := repo2.GetIndex()
-	if len(index2.GetEntries()) != 0 {
-		t.Fatalf("expected 0 entries post-commit")
+	if len(index2.GetEntries()) != 1 {
+		t.Fatalf("expected 1 entry post-commit, got %d", len(index2.GetEntries()))
 	}
 }
 
-func verifyCommitState(t *testing.T, repoPath string, _ map[string]string) {
+func verifyCommitState(t *testing.T, repoPath string, files map[string]string) {
 	repo, err := repository.NewRepository(repoPath)
 	if err != nil {
 		t.Fatalf("failed to open repository: %v", err)
@@ -139,8 +139,8 @@ func verifyCommitState(t *testing.T, repoPath string, _ map[string]string) {
 	}
 
 	entries := index.GetEntries()
-	if len(entries) != 0 {
-		t.Fatalf("index should be empty after commit, got %d entries", len(entries))
+	if len(entries) != len(files) {
+		t.Fatalf("index should still track %d files after commit, got %d entries", len(files), len(entries))
 	}
 }
 
@@ -198,4 +198,15 @@ func verifyMultipleCommitsState(t *testing.T, repoPath string) {
 	if len(parentCommit.Parents) != 0 {
 		t.Fatalf("first commit should have 0 parents, got %d", len(parentCommit.Parents))
 	}
+
+	// The second commit is a full snapshot, not just the newly staged file
+	files, err := store.ReadTreeEntries(commit.Tree)
+	if err != nil {
+		t.Fatalf("failed to read latest tree: %v", err)
+	}
+	for _, name := range []string{"file1.txt", "file2.txt"} {
+		if _, ok := files[name]; !ok {
+			t.Fatalf("%s missing from second commit's tree", name)
+		}
+	}
 }
@@ -1,11 +1,13 @@
 package unit
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
 
+	"minigit/internal/parallel"
 	"minigit/internal/repository"
 	"minigit/test/fixtures"
 )
@@ -90,3 +92,67 @@ func TestAddNonExistentFile(t *testing.T) {
 		t.Fatalf("wrong error: %v", err)
 	}
 }
+
+func TestAddStagesDeletedFiles(t *testing.T) {
+	repoPath := fixtures.InitRepo(t)
+	cleanup := fixtures.Chdir(t, repoPath)
+	defer cleanup()
+
+	fixtures.CreateFiles(t, repoPath, map[string]string{
+		"dir/a.txt": "a",
+		"dir/b.txt": "b",
+		"top.txt":   "top",
+	})
+	fixtures.RunCLI(t, "add", ".")
+	fixtures.RunCLI(t, "commit", "-m", "Initial commit")
+
+	os.Remove(filepath.Join(repoPath, "dir", "a.txt"))
+	os.Remove(filepath.Join(repoPath, "top.txt"))
+
+	fixtures.RunCLI(t, "add", "dir")
+	fixtures.RunCLI(t, "add", "top.txt")
+
+	repo, _ := repository.NewRepository(repoPath)
+	index, _ := repo.GetIndex()
+	entries := index.GetEntries()
+	if len(entries) != 1 {
+		t.Fatalf("expected only dir/b.txt to remain tracked, got %d entries", len(entries))
+	}
+	if _, ok := entries["dir/b.txt"]; !ok {
+		t.Fatal("dir/b.txt should still be tracked")
+	}
+}
+
+// Stages a tree of 2000 8 KiB files from scratch, hashing and compressing
+// on one goroutine and on the default worker pool
+func BenchmarkAddLargeTree(b *testing.B) {
+	repoPath := fixtures.InitRepo(b)
+	cleanup := fixtures.Chdir(b, repoPath)
+	defer cleanup()
+
+	files := make(map[string]string)
+	for i := range 2000 {
+		files[fmt.Sprintf("dir%02d/file%04d.txt", i%50, i)] = strings.Repeat(fmt.Sprintf("%d ", i), 8192/5)
+	}
+	fixtures.CreateFiles(b, repoPath, files)
+
+	counts := []int{1}
+	if parallel.Workers > 1 {
+		counts = append(counts, parallel.Workers)
+	}
+
+	minigitDir := filepath.Join(repoPath, ".minigit")
+	for _, workers := range counts {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			setWorkers(b, workers)
+			for b.Loop() {
+				b.StopTimer()
+				os.Remove(filepath.Join(minigitDir, "index"))
+				os.RemoveAll(filepath.Join(minigitDir, "objects"))
+				b.StartTimer()
+
+				fixtures.RunCLI(b, "add", ".")
+			}
+		})
+	}
+}
@@ -0,0 +1,175 @@
+package unit
+
+import (
+	"strings"
+	"testing"
+
+	"minigit/internal/repository"
+	"minigit/test/fixtures"
+)
+
+func TestBranchCreateAndList(t *testing.T) {
+	repoPath := fixtures.InitRepo(t)
+	cleanup := fixtures.Chdir(t, repoPath)
+	defer cleanup()
+
+	fixtures.CommitFile(t, repoPath, "a.txt", "a", "Initial commit")
+
+	fixtures.RunCLI(t, "branch", "feature")
+	fixtures.RunCLI(t, "branch", "topic/nested")
+
+	out := fixtures.CaptureCLI(t, "branch")
+	want := "  feature\n* main\n  topic/nested\n"
+	if out != want {
+		t.Fatalf("branch list mismatch:\ngot:\n%s\nwant:\n%s", out, want)
+	}
+
+	repo, _ := repository.NewRepository(repoPath)
+	refsMan, _ := repo.GetRefsManager()
+	mainCommit, _ := refsMan.GetBranch("main")
+	featureCommit, _ := refsMan.GetBranch("feature")
+	if mainCommit != featureCommit {
+		t.Fatalf("new branch should start at HEAD: %s != %s", featureCommit, mainCommit)
+	}
+}
+
+func TestBranchCreateErrors(t *testing.T) {
+	repoPath := fixtures.InitRepo(t)
+	cleanup := fixtures.Chdir(t, repoPath)
+	defer cleanup()
+
+	if err := fixtures.TryCLI(t, "branch", "feature"); err == nil || !strings.Contains(err.Error(), "not a valid object name") {
+		t.Fatalf("expected error on unborn HEAD, got %v", err)
+	}
+
+	fixtures.CommitFile(t, repoPath, "a.txt", "a", "Initial commit")
+
+	if err := fixtures.TryCLI(t, "branch", "main"); err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Fatalf("expected duplicate branch error, got %v", err)
+	}
+	if err := fixtures.TryCLI(t, "branch", "bad..name"); err == nil || !strings.Contains(err.Error(), "not a valid branch name") {
+		t.Fatalf("expected invalid name error, got %v", err)
+	}
+}
+
+func TestBranchCreateFromStartPoint(t *testing.T) {
+	repoPath := fixtures.InitRepo(t)
+	cleanup := fixtures.Chdir(t, repoPath)
+	defer cleanup()
+
+	fixtures.CommitFile(t, repoPath, "a.txt", "a", "First")
+	fixtures.RunCLI(t, "branch", "old")
+	fixtures.CommitFile(t, repoPath, "b.txt", "b", "Second")
+
+	fixtures.RunCLI(t, "branch", "copy", "old")
+
+	repo, _ := repository.NewRepository(repoPath)
+	refsMan, _ := repo.GetRefsManager()
+	oldCommit, _ := refsMan.GetBranch("old")
+	copyCommit, _ := refsMan.GetBranch("copy")
+	if oldCommit != copyCommit {
+		t.Fatalf("expected copy to start at old: %s != %s", copyCommit, oldCommit)
+	}
+}
+
+func TestBranchDelete(t *testing.T) {
+	repoPath := fixtures.InitRepo(t)
+	cleanup := fixtures.Chdir(t, repoPath)
+	defer cleanup()
+
+	fixtures.CommitFile(t, repoPath, "a.txt", "a", "Initial commit")
+	fixtures.RunCLI(t, "branch", "merged")
+
+	if err := fixtures.TryCLI(t, "branch", "-d", "main"); err == nil || !strings.Contains(err.Error(), "cannot delete branch") {
+		t.Fatalf("expected error deleting current branch, got %v", err)
+	}
+
+	fixtures.RunCLI(t, "branch", "-d", "merged")
+
+	repo, _ := repository.NewRepository(repoPath)
+	refsMan, _ := repo.GetRefsManager()
+	if refsMan.BranchExists("merged") {
+		t.Fatal("branch should have been deleted")
+	}
+}
+
+func TestBranchDeleteUnmerged(t *testing.T) {
+	repoPath := fixtures.InitRepo(t)
+	cleanup := fixtures.Chdir(t, repoPath)
+	defer cleanup()
+
+	fixtures.CommitFile(t, repoPath, "a.txt", "a", "Initial commit")
+
+	// Point "side" at a commit that HEAD cannot reach
+	repo, _ := repository.NewRepository(repoPath)
+	store, _ := repo.GetObjectStore()
+	refsMan, _ := repo.GetRefsManager()
+	head, _ := refsMan.GetBranch("main")
+	headCommit, _ := store.ParseCommit(mustLoad(t, store, head))
+	sideHash, err := store.CreateCommit(headCommit.Tree, []string{head}, "Test User <test@example.com>", "Side commit")
+	if err != nil {
+		t.Fatalf("create commit: %v", err)
+	}
+	if err := refsMan.SetBranch("side", sideHash, ""); err != nil {
+		t.Fatalf("set branch: %v", err)
+	}
+
+	err = fixtures.TryCLI(t, "branch", "-d", "side")
+	if err == nil || !strings.Contains(err.Error(), "not fully merged") {
+		t.Fatalf("expected unmerged error, got %v", err)
+	}
+
+	fixtures.RunCLI(t, "branch", "-D", "side")
+	if refsMan.BranchExists("side") {
+		t.Fatal("branch should have been force deleted")
+	}
+}
+
+func TestBranchRenameCurrent(t *testing.T) {
+	repoPath := fixtures.InitRepo(t)
+	cleanup := fixtures.Chdir(t, repoPath)
+	defer cleanup()
+
+	fixtures.CommitFile(t, repoPath, "a.txt", "a", "Initial commit")
+	fixtures.RunCLI(t, "branch", "-m", "main", "trunk")
+
+	repo, _ := repository.NewRepository(repoPath)
+	refsMan, _ := repo.GetRefsManager()
+	head, _ := refsMan.GetHead()
+	if head != "refs/heads/trunk" {
+		t.Fatalf("HEAD should follow renamed branch, got %s", head)
+	}
+	if refsMan.BranchExists("main") || !refsMan.BranchExists("trunk") {
+		t.Fatal("branch was not renamed")
+	}
+
+	fixtures.RunCLI(t, "branch", "other")
+	if err := fixtures.TryCLI(t, "branch", "-m", "other", "trunk"); err == nil {
+		t.Fatal("expected error renaming onto an existing branch")
+	}
+}
+
+func TestBranchRenameIntoOwnDirectory(t *testing.T) {
+	repoPath := fixtures.InitRepo(t)
+	cleanup := fixtures.Chdir(t, repoPath)
+	defer cleanup()
+
+	fixtures.CommitFile(t, repoPath, "a.txt", "a", "Initial commit")
+	fixtures.RunCLI(t, "branch", "old")
+	fixtures.RunCLI(t, "branch", "-m", "old", "old/sub")
+
+	repo, _ := repository.NewRepository(repoPath)
+	refsMan, _ := repo.GetRefsManager()
+	if refsMan.BranchExists("old") || !refsMan.BranchExists("old/sub") {
+		t.Fatal("branch was not renamed")
+	}
+	if out := fixtures.CaptureCLI(t, "reflog", "show", "old/sub"); !strings.Contains(out, "branch: Created from") || !strings.Contains(out, "Branch: renamed refs/heads/old to refs/heads/old/sub") {
+		t.Fatalf("reflog should move with the branch:\n%s", out)
+	}
+
+	// And back out of it again
+	fixtures.RunCLI(t, "branch", "-m", "old/sub", "old")
+	if refsMan.BranchExists("old/sub") || !refsMan.BranchExists("old") {
+		t.Fatal("branch was not renamed back")
+	}
+}
@@ -0,0 +1,94 @@
+package unit
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"minigit/internal/cli"
+	"minigit/internal/repository"
+	"minigit/test/fixtures"
+)
+
+func TestCatFileInspectsObjects(t *testing.T) {
+	repoPath := fixtures.InitRepo(t)
+	cleanup := fixtures.Chdir(t, repoPath)
+	defer cleanup()
+
+	fixtures.CreateFiles(t, repoPath, map[string]string{
+		"test.txt":   "version 1\n",
+		"lib/foo.go": "package lib\n",
+	})
+	fixtures.RunCLI(t, "add", ".")
+	fixtures.RunCLI(t, "commit", "-m", "Initial commit")
+
+	repo, _ := repository.NewRepository(repoPath)
+	refsMan, _ := repo.GetRefsManager()
+	head, _ := refsMan.ResolveHead()
+	store, _ := repo.GetObjectStore()
+	commit, _ := store.ParseCommit(mustLoad(t, store, head))
+	blob := "83baae61804e65cc73a7201a7252750c76066a30"
+
+	if out := fixtures.CaptureCLI(t, "cat-file", "-t", head); out != "commit\n" {
+		t.Fatalf("unexpected type %q", out)
+	}
+	if out := fixtures.CaptureCLI(t, "cat-file", "-t", blob); out != "blob\n" {
+		t.Fatalf("unexpected type %q", out)
+	}
+	if out := fixtures.CaptureCLI(t, "cat-file", "-s", blob); out != "10\n" {
+		t.Fatalf("unexpected size %q", out)
+	}
+	if out := fixtures.CaptureCLI(t, "cat-file", "-p", blob); out != "version 1\n" {
+		t.Fatalf("unexpected content %q", out)
+	}
+	if out := fixtures.CaptureCLI(t, "cat-file", "blob", blob); out != "version 1\n" {
+		t.Fatalf("unexpected content %q", out)
+	}
+
+	out := fixtures.CaptureCLI(t, "cat-file", "-p", "HEAD")
+	if !strings.HasPrefix(out, "tree "+commit.Tree+"\n") || !strings.HasSuffix(out, "\nInitial commit\n") {
+		t.Fatalf("unexpected commit output:\n%s", out)
+	}
+
+	out = fixtures.CaptureCLI(t, "cat-file", "-p", commit.Tree)
+	lines := strings.Split(strings.TrimSuffix(out, "\n"), "\n")
+	if len(lines) != 2 || !strings.HasPrefix(lines[0], "040000 tree ") || !strings.HasSuffix(lines[0], "\tlib") ||
+		lines[1] != "100644 blob "+blob+"\ttest.txt" {
+		t.Fatalf("unexpected tree output:\n%s", out)
+	}
+
+	if err := fixtures.TryCLI(t, "cat-file", "-e", blob); err != nil {
+		t.Fatalf("expected existing object, got %v", err)
+	}
+	var code cli.ExitCode
+	err := fixtures.TryCLI(t, "cat-file", "-e", strings.Repeat("0", 40))
+	if !errors.As(err, &code) || code != 1 {
+		t.Fatalf("expected exit status 1 for a missing object, got %v", err)
+	}
+
+	if err := fixtures.TryCLI(t, "cat-file", "tree", blob); err == nil {
+		t.Fatal("expected an error when the type does not match")
+	}
+}
+
+func TestCatFileBatch(t *testing.T) {
+	repoPath := fixtures.InitRepo(t)
+	cleanup := fixtures.Chdir(t, repoPath)
+	defer cleanup()
+
+	fixtures.CommitFile(t, repoPath, "test.txt", "version 1\n", "Initial commit")
+
+	blob := "83baae61804e65cc73a7201a7252750c76066a30"
+	fixtures.SetStdin(t, blob+"\nnope\n")
+	out := fixtures.CaptureCLI(t, "cat-file", "--batch")
+	if want := blob + " blob 10\nversion 1\n\nnope missing\n"; out != want {
+		t.Fatalf("unexpected batch output:\ngot:\n%q\nwant:\n%q", out, want)
+	}
+
+	fixtures.SetStdin(t, "main\n"+blob+"\n")
+	out = fixtures.CaptureCLI(t, "cat-file", "--batch-check")
+	lines := strings.Split(strings.TrimSuffix(out, "\n"), "\n")
+	if len(lines) != 2 || len(strings.Fields(lines[0])) != 3 || strings.Fields(lines[0])[1] != "commit" || lines[1] != blob+" blob 10" {
+		t.Fatalf("unexpected batch-check output:\n%s", out)
+	}
+}
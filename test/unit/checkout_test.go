@@ -0,0 +1,168 @@
+package unit
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"minigit/internal/repository"
+	"minigit/test/fixtures"
+)
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read %s: %v", path, err)
+	}
+	return string(data)
+}
+
+// Writes a file and commits the whole working tree
+func commitSnapshot(t *testing.T, repoPath, name, content, message string) {
+	t.Helper()
+
+	fixtures.CreateFiles(t, repoPath, map[string]string{name: content})
+	fixtures.RunCLI(t, "add", ".")
+	fixtures.RunCLI(t, "commit", "-m", message)
+}
+
+func TestCheckoutSwitchesWorkingTree(t *testing.T) {
+	repoPath := fixtures.InitRepo(t)
+	cleanup := fixtures.Chdir(t, repoPath)
+	defer cleanup()
+
+	commitSnapshot(t, repoPath, "shared.txt", "v1", "Initial commit")
+	fixtures.RunCLI(t, "checkout", "-b", "feature")
+
+	commitSnapshot(t, repoPath, "shared.txt", "v2", "Update shared")
+	commitSnapshot(t, repoPath, "dir/feature.txt", "feature only", "Add feature file")
+
+	fixtures.RunCLI(t, "checkout", "main")
+
+	if got := readFile(t, filepath.Join(repoPath, "shared.txt")); got != "v1" {
+		t.Fatalf("shared.txt = %q, want v1", got)
+	}
+	if _, err := os.Stat(filepath.Join(repoPath, "dir")); !os.IsNotExist(err) {
+		t.Fatal("feature-only file and its directory should be removed on main")
+	}
+
+	repo, _ := repository.NewRepository(repoPath)
+	refsMan, _ := repo.GetRefsManager()
+	if head, _ := refsMan.GetHead(); head != "refs/heads/main" {
+		t.Fatalf("HEAD = %s, want refs/heads/main", head)
+	}
+
+	fixtures.RunCLI(t, "checkout", "feature")
+	if got := readFile(t, filepath.Join(repoPath, "dir", "feature.txt")); got != "feature only" {
+		t.Fatalf("dir/feature.txt = %q", got)
+	}
+	if got := readFile(t, filepath.Join(repoPath, "shared.txt")); got != "v2" {
+		t.Fatalf("shared.txt = %q, want v2", got)
+	}
+}
+
+func TestCheckoutRefusesToOverwriteLocalChanges(t *testing.T) {
+	repoPath := fixtures.InitRepo(t)
+	cleanup := fixtures.Chdir(t, repoPath)
+	defer cleanup()
+
+	commitSnapshot(t, repoPath, "file.txt", "base", "Initial commit")
+	fixtures.RunCLI(t, "branch", "other")
+	commitSnapshot(t, repoPath, "file.txt", "main change", "Change on main")
+
+	fixtures.CreateFiles(t, repoPath, map[string]string{"file.txt": "uncommitted"})
+
+	err := fixtures.TryCLI(t, "checkout", "other")
+	if err == nil || !strings.Contains(err.Error(), "would be overwritten by checkout") || !strings.Contains(err.Error(), "file.txt") {
+		t.Fatalf("expected overwrite error listing file.txt, got %v", err)
+	}
+
+	if got := readFile(t, filepath.Join(repoPath, "file.txt")); got != "uncommitted" {
+		t.Fatalf("local change was clobbered: %q", got)
+	}
+}
+
+func TestCheckoutRefusesToOverwriteUntrackedFiles(t *testing.T) {
+	repoPath := fixtures.InitRepo(t)
+	cleanup := fixtures.Chdir(t, repoPath)
+	defer cleanup()
+
+	commitSnapshot(t, repoPath, "base.txt", "base", "Initial commit")
+	fixtures.RunCLI(t, "checkout", "-b", "other")
+	commitSnapshot(t, repoPath, "new.txt", "tracked on other", "Add new.txt")
+	fixtures.RunCLI(t, "checkout", "main")
+
+	fixtures.CreateFiles(t, repoPath, map[string]string{"new.txt": "untracked"})
+
+	err := fixtures.TryCLI(t, "checkout", "other")
+	if err == nil || !strings.Contains(err.Error(), "untracked working tree files") {
+		t.Fatalf("expected untracked overwrite error, got %v", err)
+	}
+}
+
+func TestCheckoutKeepsUnrelatedLocalChanges(t *testing.T) {
+	repoPath := fixtures.InitRepo(t)
+	cleanup := fixtures.Chdir(t, repoPath)
+	defer cleanup()
+
+	commitSnapshot(t, repoPath, "keep.txt", "keep", "Initial commit")
+	fixtures.RunCLI(t, "checkout", "-b", "other")
+	commitSnapshot(t, repoPath, "other.txt", "other", "Add other")
+	fixtures.RunCLI(t, "checkout", "main")
+
+	fixtures.CreateFiles(t, repoPath, map[string]string{"keep.txt": "edited"})
+	fixtures.RunCLI(t, "checkout", "other")
+
+	if got := readFile(t, filepath.Join(repoPath, "keep.txt")); got != "edited" {
+		t.Fatalf("unrelated local change lost: %q", got)
+	}
+}
+
+func TestCheckoutDetachedHead(t *testing.T) {
+	repoPath := fixtures.InitRepo(t)
+	cleanup := fixtures.Chdir(t, repoPath)
+	defer cleanup()
+
+	commitSnapshot(t, repoPath, "file.txt", "first", "First")
+
+	repo, _ := repository.NewRepository(repoPath)
+	refsMan, _ := repo.GetRefsManager()
+	first, _ := refsMan.GetBranch("main")
+
+	commitSnapshot(t, repoPath, "file.txt", "second", "Second")
+
+	out := fixtures.CaptureCLI(t, "checkout", first)
+	if !strings.Contains(out, "detached HEAD") {
+		t.Fatalf("expected detached HEAD note, got:\n%s", out)
+	}
+
+	if head, _ := refsMan.GetHead(); head != first {
+		t.Fatalf("HEAD = %s, want %s", head, first)
+	}
+	if got := readFile(t, filepath.Join(repoPath, "file.txt")); got != "first" {
+		t.Fatalf("file.txt = %q, want first", got)
+	}
+
+	branches := fixtures.CaptureCLI(t, "branch")
+	if !strings.HasPrefix(branches, "* (HEAD detached at "+first[:7]+")") {
+		t.Fatalf("branch should show detached HEAD:\n%s", branches)
+	}
+}
+
+func TestCheckoutUnknownTarget(t *testing.T) {
+	repoPath := fixtures.InitRepo(t)
+	cleanup := fixtures.Chdir(t, repoPath)
+	defer cleanup()
+
+	commitSnapshot(t, repoPath, "file.txt", "content", "Initial commit")
+
+	if err := fixtures.TryCLI(t, "checkout", "nope"); err == nil {
+		t.Fatal("expected error for unknown revision")
+	}
+	if err := fixtures.TryCLI(t, "checkout", "-b", "main"); err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Fatalf("expected existing branch error, got %v", err)
+	}
+}
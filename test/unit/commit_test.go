@@ -4,6 +4,7 @@ import (
 	"strings"
 	"testing"
 
+	"minigit/internal/repository"
 	"minigit/test/fixtures"
 )
 
@@ -81,3 +82,40 @@ func TestCommitMissingMessage(t *testing.T) {
 		t.Fatal("expected missing -m error")
 	}
 }
+
+func TestCommitHonoursIdentityEnvironment(t *testing.T) {
+	repoPath := fixtures.InitRepo(t)
+	cleanup := fixtures.Chdir(t, repoPath)
+	defer cleanup()
+
+	t.Setenv("MINIGIT_AUTHOR_NAME", "Ada Lovelace")
+	t.Setenv("MINIGIT_AUTHOR_EMAIL", "ada@example.com")
+	t.Setenv("MINIGIT_AUTHOR_DATE", "1700000000 +0530")
+	t.Setenv("MINIGIT_COMMITTER_DATE", "2023-11-14T23:13:20-08:00")
+
+	fixtures.CommitFile(t, repoPath, "a.txt", "a\n", "Add a")
+
+	repo, _ := repository.NewRepository(repoPath)
+	store, _ := repo.GetObjectStore()
+	refsMan, _ := repo.GetRefsManager()
+	head, _ := refsMan.ResolveHead()
+	commit, err := store.ParseCommit(mustLoad(t, store, head))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := commit.Author.Format(); got != "Ada Lovelace <ada@example.com> 1700000000 +0530" {
+		t.Fatalf("unexpected author %q", got)
+	}
+	// The committer falls back to the configured identity
+	if got := commit.Committer.Format(); got != "Test User <test@example.com> 1700032400 -0800" {
+		t.Fatalf("unexpected committer %q", got)
+	}
+
+	t.Setenv("MINIGIT_AUTHOR_DATE", "yesterday-ish")
+	fixtures.CreateFiles(t, repoPath, map[string]string{"b.txt": "b\n"})
+	fixtures.RunCLI(t, "add", "b.txt")
+	if err := fixtures.TryCLI(t, "commit", "-m", "Add b"); err == nil || !strings.Contains(err.Error(), "invalid date format") {
+		t.Fatalf("expected invalid date error, got %v", err)
+	}
+}
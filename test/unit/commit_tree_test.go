@@ -0,0 +1,68 @@
+package unit
+
+import (
+	"strings"
+	"testing"
+
+	"minigit/internal/repository"
+	"minigit/test/fixtures"
+)
+
+func TestWriteTreeAndCommitTree(t *testing.T) {
+	repoPath := fixtures.InitRepo(t)
+	cleanup := fixtures.Chdir(t, repoPath)
+	defer cleanup()
+
+	// An empty index writes Git's empty tree
+	if out := fixtures.CaptureCLI(t, "write-tree"); out != "4b825dc642cb6eb9a060e54bf8d69288fbee4904\n" {
+		t.Fatalf("unexpected empty tree %q", out)
+	}
+
+	fixtures.CreateFiles(t, repoPath, map[string]string{"test.txt": "version 1\n"})
+	fixtures.RunCLI(t, "add", "test.txt")
+
+	tree := strings.TrimSpace(fixtures.CaptureCLI(t, "write-tree"))
+	if tree != "d8329fc1cc938780ffdd9f94e0d364e0ea74f579" {
+		t.Fatalf("unexpected tree %s", tree)
+	}
+
+	t.Setenv("MINIGIT_AUTHOR_DATE", "1243040974 -0700")
+	t.Setenv("MINIGIT_COMMITTER_DATE", "1243040974 -0700")
+
+	first := strings.TrimSpace(fixtures.CaptureCLI(t, "commit-tree", tree, "-m", "first commit"))
+	second := strings.TrimSpace(fixtures.CaptureCLI(t, "commit-tree", tree, "-p", first, "-m", "Subject", "-m", "Body"))
+
+	fixtures.SetStdin(t, "from stdin\n")
+	third := strings.TrimSpace(fixtures.CaptureCLI(t, "commit-tree", tree, "-p", first, "-p", second))
+
+	repo, _ := repository.NewRepository(repoPath)
+	store, _ := repo.GetObjectStore()
+
+	commit, err := store.ParseCommit(mustLoad(t, store, second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if commit.Tree != tree || len(commit.Parents) != 1 || commit.Parents[0] != first || commit.Message != "Subject\n\nBody" {
+		t.Fatalf("unexpected commit %+v", commit)
+	}
+
+	commit, _ = store.ParseCommit(mustLoad(t, store, third))
+	if len(commit.Parents) != 2 || commit.Message != "from stdin" {
+		t.Fatalf("unexpected commit %+v", commit)
+	}
+
+	// commit-tree leaves refs alone
+	refsMan, _ := repo.GetRefsManager()
+	if head, _ := refsMan.ResolveHead(); head != "" {
+		t.Fatalf("commit-tree should not move HEAD, got %s", head)
+	}
+
+	if err := fixtures.TryCLI(t, "commit-tree", "83baae61804e65cc73a7201a7252750c76066a30", "-m", "x"); err == nil ||
+		!strings.Contains(err.Error(), "is not a valid 'tree' object") {
+		t.Fatalf("expected invalid tree error, got %v", err)
+	}
+	if err := fixtures.TryCLI(t, "commit-tree", tree, "-p", tree, "-m", "x"); err == nil ||
+		!strings.Contains(err.Error(), "is not a valid 'commit' object") {
+		t.Fatalf("expected invalid parent error, got %v", err)
+	}
+}
@@ -0,0 +1,238 @@
+package unit
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"minigit/internal/config"
+	"minigit/internal/repository"
+	"minigit/test/fixtures"
+)
+
+func TestConfigFileParsing(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config")
+	content := strings.Join([]string{
+		"# comment",
+		"[core]",
+		"\tfilemode = false ; trailing comment",
+		"\tbare",
+		"[User]",
+		"\tName = \"  Padded Name  \"",
+		"\temail = someone@example.com # comment",
+		"[branch \"Feature\"]",
+		"\tremote = origin",
+		"[remote.Origin]",
+		"\turl = \"a\\\"b\\\\c\"",
+		"[pack]",
+		"\tlimit = 2k",
+	}, "\n")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	file, err := config.LoadFile(path)
+	if err != nil {
+		t.Fatalf("LoadFile failed: %v", err)
+	}
+
+	cases := map[string]string{
+		"core.filemode":         "false",
+		"core.bare":             "true",
+		"user.name":             "  Padded Name  ",
+		"USER.EMAIL":            "someone@example.com",
+		"branch.Feature.remote": "origin",
+		"remote.origin.url":     `a"b\c`,
+		"pack.limit":            "2k",
+	}
+	for key, want := range cases {
+		if got, found := file.Get(key); !found || got != want {
+			t.Errorf("%s = %q (found %v), want %q", key, got, found, want)
+		}
+	}
+
+	if _, found := file.Get("branch.feature.remote"); found {
+		t.Error("quoted subsections should be case-sensitive")
+	}
+}
+
+func TestConfigFileRejectsBadLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config")
+	if err := os.WriteFile(path, []byte("[core]\n\tvalue = \"unterminated\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := config.LoadFile(path); err == nil || !strings.Contains(err.Error(), "bad config line 2") {
+		t.Fatalf("expected bad config line error, got %v", err)
+	}
+}
+
+func TestConfigSetPreservesLayout(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config")
+	original := "# keep me\n[user]\n\tname = Old\n[core]\n\tbare = false\n"
+	if err := os.WriteFile(path, []byte(original), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	file, err := config.LoadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := file.Set("user.name", "New Name"); err != nil {
+		t.Fatal(err)
+	}
+	if err := file.Set("user.email", "new@example.com"); err != nil {
+		t.Fatal(err)
+	}
+	if err := file.Set("branch.main.merge", "value; with comment chars"); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "# keep me\n[user]\n\tname = New Name\n\temail = new@example.com\n[core]\n\tbare = false\n" +
+		"[branch \"main\"]\n\tmerge = \"value; with comment chars\"\n"
+	if got := readFile(t, path); got != want {
+		t.Fatalf("unexpected file content:\n%s", got)
+	}
+
+	reloaded, err := config.LoadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := reloaded.Get("branch.main.merge"); got != "value; with comment chars" {
+		t.Fatalf("value did not round-trip: %q", got)
+	}
+
+	if err := file.Set("nosection", "x"); err == nil || !strings.Contains(err.Error(), "does not contain a section") {
+		t.Fatalf("expected missing section error, got %v", err)
+	}
+}
+
+func TestConfigPrecedenceAndTypedGetters(t *testing.T) {
+	repoPath := fixtures.InitRepo(t)
+	home := filepath.Dir(repoPath)
+
+	global := "[user]\n\tname = Global Name\n\temail = global@example.com\n[core]\n\tabbrev = 12\n"
+	if err := os.WriteFile(filepath.Join(home, ".minigitconfig"), []byte(global), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	repo, err := repository.NewRepository(repoPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg, _ := repo.GetConfig()
+
+	// The repository's own identity (written by the fixture) wins
+	if got := cfg.GetString("user.name", ""); got != "Test User" {
+		t.Fatalf("expected local value to win, got %q", got)
+	}
+	if got, _ := cfg.GetScoped(config.ScopeGlobal, "user.name"); got != "Global Name" {
+		t.Fatalf("unexpected global value %q", got)
+	}
+
+	if abbrev, err := cfg.GetInt("core.abbrev", 7); err != nil || abbrev != 12 {
+		t.Fatalf("GetInt = %d, %v", abbrev, err)
+	}
+	if filemode, err := cfg.GetBool("core.filemode", false); err != nil || !filemode {
+		t.Fatalf("GetBool = %v, %v", filemode, err)
+	}
+	if missing, _ := cfg.GetBool("core.missing", true); !missing {
+		t.Fatal("missing keys should return the fallback")
+	}
+
+	if err := cfg.Set(config.ScopeLocal, "core.bare", "maybe"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cfg.GetBool("core.bare", false); err == nil {
+		t.Fatal("expected an error for a non-boolean value")
+	}
+}
+
+func TestConfigCommand(t *testing.T) {
+	repoPath := fixtures.InitRepo(t)
+	cleanup := fixtures.Chdir(t, repoPath)
+	defer cleanup()
+
+	fixtures.RunCLI(t, "config", "user.name", "Jane Doe")
+	if out := fixtures.CaptureCLI(t, "config", "--get", "user.name"); out != "Jane Doe\n" {
+		t.Fatalf("unexpected get output %q", out)
+	}
+
+	fixtures.RunCLI(t, "config", "--global", "--set", "alias.st", "status")
+	if out := fixtures.CaptureCLI(t, "config", "alias.st"); out != "status\n" {
+		t.Fatalf("global value should be visible, got %q", out)
+	}
+	if err := fixtures.TryCLI(t, "config", "--local", "--get", "alias.st"); err == nil {
+		t.Fatal("--local should not see global values")
+	}
+
+	list := fixtures.CaptureCLI(t, "config", "--list")
+	if !strings.HasPrefix(list, "alias.st=status\n") || !strings.Contains(list, "user.name=Jane Doe\n") {
+		t.Fatalf("unexpected --list output:\n%s", list)
+	}
+
+	fixtures.RunCLI(t, "config", "--unset", "user.name")
+	if err := fixtures.TryCLI(t, "config", "--get", "user.name"); err == nil || !strings.Contains(err.Error(), "is not set") {
+		t.Fatalf("expected unset key error, got %v", err)
+	}
+	if err := fixtures.TryCLI(t, "config", "--unset", "user.name"); err == nil {
+		t.Fatal("unsetting a missing key should fail")
+	}
+
+	// User-level settings work outside a repository too
+	outside := t.TempDir()
+	restore := fixtures.Chdir(t, outside)
+	defer restore()
+	if out := fixtures.CaptureCLI(t, "config", "--global", "--get", "alias.st"); out != "status\n" {
+		t.Fatalf("unexpected global get outside repository %q", out)
+	}
+	if err := fixtures.TryCLI(t, "config", "user.email", "x@example.com"); err == nil || !strings.Contains(err.Error(), "not a minigit repository") {
+		t.Fatalf("expected local write outside a repository to fail, got %v", err)
+	}
+}
+
+func TestCommitUsesConfiguredIdentity(t *testing.T) {
+	repoPath := fixtures.InitRepo(t)
+	cleanup := fixtures.Chdir(t, repoPath)
+	defer cleanup()
+
+	fixtures.RunCLI(t, "config", "user.name", "Ada Lovelace")
+	fixtures.RunCLI(t, "config", "user.email", "ada@example.com")
+	fixtures.CommitFile(t, repoPath, "a.txt", "a", "Initial commit")
+
+	out := fixtures.CaptureCLI(t, "log")
+	if !strings.Contains(out, "Author: Ada Lovelace <ada@example.com>\n") {
+		t.Fatalf("commit should use the configured identity:\n%s", out)
+	}
+}
+
+func TestFileModeConfigControlsExecutableBit(t *testing.T) {
+	repoPath := fixtures.InitRepo(t)
+	cleanup := fixtures.Chdir(t, repoPath)
+	defer cleanup()
+
+	fixtures.CommitFile(t, repoPath, "run.sh", "echo hi\n", "Initial commit")
+	if err := os.Chmod(filepath.Join(repoPath, "run.sh"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	diff := fixtures.CaptureCLI(t, "diff")
+	if !strings.Contains(diff, "old mode 100644\nnew mode 100755\n") {
+		t.Fatalf("mode change should show with core.filemode on:\n%s", diff)
+	}
+
+	// Without a trusted executable bit the chmod is invisible
+	fixtures.RunCLI(t, "config", "core.filemode", "false")
+	if diff := fixtures.CaptureCLI(t, "diff"); diff != "" {
+		t.Fatalf("mode change should be ignored with core.filemode off:\n%s", diff)
+	}
+	if status := fixtures.CaptureCLI(t, "status"); !strings.Contains(status, "working tree clean") {
+		t.Fatalf("status should be clean with core.filemode off:\n%s", status)
+	}
+
+	fixtures.RunCLI(t, "add", "run.sh")
+	if out := fixtures.CaptureCLI(t, "ls-files", "-s"); !strings.HasPrefix(out, "100644 ") {
+		t.Fatalf("add should keep the staged mode with core.filemode off:\n%s", out)
+	}
+}
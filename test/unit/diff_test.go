@@ -0,0 +1,331 @@
+package unit
+
+import (
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+
+	"minigit/internal/diff"
+	"minigit/internal/repository"
+	"minigit/test/fixtures"
+)
+
+// Applies an edit script to the old lines and checks it yields the new lines
+func checkEditScript(t *testing.T, oldLines, newLines []string, edits []diff.Edit) {
+	t.Helper()
+
+	var rebuilt []string
+	oldPos := 0
+	for _, edit := range edits {
+		switch edit.Type {
+		case diff.Equal:
+			if oldLines[oldPos] != edit.Content {
+				t.Fatalf("equal edit %q does not match old line %q", edit.Content, oldLines[oldPos])
+			}
+			rebuilt = append(rebuilt, edit.Content)
+			oldPos++
+		case diff.Delete:
+			if oldLines[oldPos] != edit.Content {
+				t.Fatalf("delete edit %q does not match old line %q", edit.Content, oldLines[oldPos])
+			}
+			oldPos++
+		case diff.Insert:
+			rebuilt = append(rebuilt, edit.Content)
+		}
+	}
+
+	if oldPos != len(oldLines) || strings.Join(rebuilt, "\n") != strings.Join(newLines, "\n") {
+		t.Fatalf("edit script does not transform old into new: got %v, want %v", rebuilt, newLines)
+	}
+}
+
+func countChanges(edits []diff.Edit) int {
+	count := 0
+	for _, edit := range edits {
+		if edit.Type != diff.Equal {
+			count++
+		}
+	}
+	return count
+}
+
+func TestMyersShortestEditScript(t *testing.T) {
+	// The example from Myers' paper has a shortest edit script of length 5
+	oldLines := strings.Split("A B C A B B A", " ")
+	newLines := strings.Split("C B A B A C", " ")
+
+	edits := diff.Myers(oldLines, newLines)
+	checkEditScript(t, oldLines, newLines, edits)
+
+	if got := countChanges(edits); got != 5 {
+		t.Fatalf("expected 5 changes, got %d", got)
+	}
+}
+
+func TestMyersEdgeCases(t *testing.T) {
+	cases := []struct {
+		name     string
+		old, new []string
+		changes  int
+	}{
+		{"both empty", nil, nil, 0},
+		{"all inserted", nil, []string{"a", "b"}, 2},
+		{"all deleted", []string{"a", "b"}, nil, 2},
+		{"identical", []string{"a", "b", "c"}, []string{"a", "b", "c"}, 0},
+		{"replace middle", []string{"a", "b", "c"}, []string{"a", "x", "c"}, 2},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			edits := diff.Myers(tc.old, tc.new)
+			checkEditScript(t, tc.old, tc.new, edits)
+			if got := countChanges(edits); got != tc.changes {
+				t.Fatalf("expected %d changes, got %d", tc.changes, got)
+			}
+		})
+	}
+}
+
+func TestMyersMemoryStaysLinear(t *testing.T) {
+	// Every line differs, so the search runs for 6000 steps; keeping each
+	// step's full frontier would take hundreds of megabytes
+	oldLines := make([]string, 3000)
+	newLines := make([]string, 3000)
+	for i := range oldLines {
+		oldLines[i] = "old " + strconv.Itoa(i)
+		newLines[i] = "new " + strconv.Itoa(i)
+	}
+
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	edits := diff.Myers(oldLines, newLines)
+	runtime.ReadMemStats(&after)
+
+	checkEditScript(t, oldLines, newLines, edits)
+	if got := countChanges(edits); got != 6000 {
+		t.Fatalf("expected 6000 changes, got %d", got)
+	}
+	if allocated := after.TotalAlloc - before.TotalAlloc; allocated > 16<<20 {
+		t.Fatalf("diff allocated %d bytes", allocated)
+	}
+}
+
+func TestCompareFilesInsertAtTop(t *testing.T) {
+	oldContent := []byte("one\ntwo\nthree\n")
+	newContent := []byte("zero\none\ntwo\nthree\n")
+
+	fileDiff := diff.CompareFiles(oldContent, newContent)
+	if len(fileDiff.Changes) != 1 {
+		t.Fatalf("expected a single change, got %d: %+v", len(fileDiff.Changes), fileDiff.Changes)
+	}
+
+	change := fileDiff.Changes[0]
+	if change.Type != diff.Added || change.LineNum != 1 || change.Content != "zero" {
+		t.Fatalf("unexpected change: %+v", change)
+	}
+}
+
+func TestCompareFilesCountsMissingFinalNewline(t *testing.T) {
+	fileDiff := diff.CompareFiles([]byte("x"), []byte("x\n"))
+	if len(fileDiff.Changes) != 2 {
+		t.Fatalf("expected the last line to change, got %+v", fileDiff.Changes)
+	}
+	for _, change := range fileDiff.Changes {
+		if change.Content != "x" || change.LineNum != 1 {
+			t.Fatalf("unexpected change: %+v", change)
+		}
+	}
+
+	repoPath := fixtures.InitRepo(t)
+	cleanup := fixtures.Chdir(t, repoPath)
+	defer cleanup()
+
+	fixtures.CommitFile(t, repoPath, "g.txt", "x", "Initial commit")
+	fixtures.CreateFiles(t, repoPath, map[string]string{"g.txt": "x\n"})
+	fixtures.RunCLI(t, "add", "g.txt")
+	if out := fixtures.CaptureCLI(t, "commit", "-m", "Terminate g"); !strings.Contains(out, " 1 file changed, 1 insertion(+), 1 deletion(-)\n") {
+		t.Fatalf("summary should count the changed last line:\n%s", out)
+	}
+}
+
+func TestCommitSummaryCountsOnlyRealChanges(t *testing.T) {
+	repoPath := fixtures.InitRepo(t)
+	cleanup := fixtures.Chdir(t, repoPath)
+	defer cleanup()
+
+	fixtures.CommitFile(t, repoPath, "file.txt", "one\ntwo\nthree\n", "Initial commit")
+
+	fixtures.CreateFiles(t, repoPath, map[string]string{"file.txt": "zero\none\ntwo\nthree\n"})
+	fixtures.RunCLI(t, "add", "file.txt")
+	out := fixtures.CaptureCLI(t, "commit", "-m", "Prepend a line")
+
+	if !strings.Contains(out, " 1 file changed, 1 insertion(+)\n") {
+		t.Fatalf("unexpected commit summary:\n%s", out)
+	}
+}
+
+func TestCommitSummaryCountsNewAndBinaryFiles(t *testing.T) {
+	repoPath := fixtures.InitRepo(t)
+	cleanup := fixtures.Chdir(t, repoPath)
+	defer cleanup()
+
+	var content strings.Builder
+	for i := range 10000 {
+		content.WriteString("line " + strconv.Itoa(i) + "\n")
+	}
+	fixtures.CreateFiles(t, repoPath, map[string]string{"big.txt": content.String()})
+	fixtures.RunCLI(t, "add", "big.txt")
+	if out := fixtures.CaptureCLI(t, "commit", "-m", "Add big file"); !strings.Contains(out, " 1 file changed, 10000 insertions(+)\n") {
+		t.Fatalf("unexpected commit summary:\n%s", out)
+	}
+
+	// Binary files are changed files without line counts
+	fixtures.CreateFiles(t, repoPath, map[string]string{"data.bin": "a\x00b\nc\n"})
+	fixtures.RunCLI(t, "add", "data.bin")
+	if out := fixtures.CaptureCLI(t, "commit", "-m", "Add binary"); !strings.Contains(out, " 1 file changed\n") {
+		t.Fatalf("unexpected commit summary:\n%s", out)
+	}
+}
+
+func TestUnifiedHunkHeaders(t *testing.T) {
+	var oldLines []string
+	for i := 1; i <= 20; i++ {
+		oldLines = append(oldLines, "line"+strconv.Itoa(i))
+	}
+	newLines := append([]string{}, oldLines...)
+	newLines[1] = "changed2"
+	newLines[17] = "changed18"
+
+	oldContent := []byte(strings.Join(oldLines, "\n") + "\n")
+	newContent := []byte(strings.Join(newLines, "\n") + "\n")
+
+	out := diff.Unified(oldContent, newContent, 3)
+	if strings.Count(out, "@@ -") != 2 {
+		t.Fatalf("expected two separate hunks:\n%s", out)
+	}
+	if !strings.HasPrefix(out, "@@ -1,5 +1,5 @@\n line1\n-line2\n+changed2\n") {
+		t.Fatalf("unexpected first hunk:\n%s", out)
+	}
+	if !strings.Contains(out, "@@ -15,6 +15,6 @@\n") {
+		t.Fatalf("unexpected second hunk header:\n%s", out)
+	}
+
+	// With enough context both changes share a hunk
+	merged := diff.Unified(oldContent, newContent, 8)
+	if strings.Count(merged, "@@ -") != 1 {
+		t.Fatalf("expected a single merged hunk:\n%s", merged)
+	}
+}
+
+func TestUnifiedMissingNewline(t *testing.T) {
+	out := diff.Unified([]byte("a\nb"), []byte("a\nb\n"), 3)
+	want := "@@ -1,2 +1,2 @@\n a\n-b\n\\ No newline at end of file\n+b\n"
+	if out != want {
+		t.Fatalf("got:\n%q\nwant:\n%q", out, want)
+	}
+}
+
+func TestDiffCommandModes(t *testing.T) {
+	repoPath := fixtures.InitRepo(t)
+	cleanup := fixtures.Chdir(t, repoPath)
+	defer cleanup()
+
+	fixtures.CommitFile(t, repoPath, "file.txt", "one\ntwo\nthree\n", "Initial commit")
+
+	repo, _ := repository.NewRepository(repoPath)
+	refsMan, _ := repo.GetRefsManager()
+	first, _ := refsMan.GetBranch("main")
+
+	// Unstaged change: worktree vs index
+	fixtures.CreateFiles(t, repoPath, map[string]string{"file.txt": "one\n2\nthree\n"})
+	out := fixtures.CaptureCLI(t, "diff")
+	for _, want := range []string{
+		"diff --git a/file.txt b/file.txt\n",
+		"--- a/file.txt\n+++ b/file.txt\n",
+		"@@ -1,3 +1,3 @@\n one\n-two\n+2\n three\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("diff output missing %q:\n%s", want, out)
+		}
+	}
+
+	if staged := fixtures.CaptureCLI(t, "diff", "--staged"); staged != "" {
+		t.Fatalf("nothing is staged yet, got:\n%s", staged)
+	}
+
+	// Staged change: index vs HEAD
+	fixtures.RunCLI(t, "add", "file.txt")
+	fixtures.CreateFiles(t, repoPath, map[string]string{"new.txt": "new\n"})
+	fixtures.RunCLI(t, "add", "new.txt")
+
+	if unstaged := fixtures.CaptureCLI(t, "diff"); unstaged != "" {
+		t.Fatalf("working tree matches index, got:\n%s", unstaged)
+	}
+
+	nameStatus := fixtures.CaptureCLI(t, "diff", "--staged", "--name-status")
+	if nameStatus != "M\tfile.txt\nA\tnew.txt\n" {
+		t.Fatalf("unexpected --name-status output:\n%s", nameStatus)
+	}
+
+	staged := fixtures.CaptureCLI(t, "diff", "--cached", "-U0")
+	if !strings.Contains(staged, "@@ -2 +2 @@\n-two\n+2\n") || !strings.Contains(staged, "new file mode 100644\n") {
+		t.Fatalf("unexpected staged diff:\n%s", staged)
+	}
+
+	fixtures.RunCLI(t, "commit", "-m", "Second")
+	second, _ := refsMan.GetBranch("main")
+
+	// Commit vs commit
+	nameOnly := fixtures.CaptureCLI(t, "diff", "--name-only", first, second)
+	if nameOnly != "file.txt\nnew.txt\n" {
+		t.Fatalf("unexpected --name-only output:\n%s", nameOnly)
+	}
+
+	stat := fixtures.CaptureCLI(t, "diff", "--stat", first, second)
+	if !strings.Contains(stat, " file.txt | 2 +-\n") || !strings.Contains(stat, " 2 files changed, 2 insertions(+), 1 deletion(-)\n") {
+		t.Fatalf("unexpected --stat output:\n%s", stat)
+	}
+
+	limited := fixtures.CaptureCLI(t, "diff", "--name-only", first, second, "--", "new.txt")
+	if limited != "new.txt\n" {
+		t.Fatalf("pathspec should limit output:\n%s", limited)
+	}
+}
+
+func TestDiffTakesPathsWithoutDashDash(t *testing.T) {
+	repoPath := fixtures.InitRepo(t)
+	cleanup := fixtures.Chdir(t, repoPath)
+	defer cleanup()
+
+	fixtures.CreateFiles(t, repoPath, map[string]string{"f.txt": "one\n", "g.txt": "one\n"})
+	fixtures.RunCLI(t, "add", ".")
+	fixtures.RunCLI(t, "commit", "-m", "Initial commit")
+	fixtures.CreateFiles(t, repoPath, map[string]string{"f.txt": "two\n", "g.txt": "two\n"})
+
+	if out := fixtures.CaptureCLI(t, "diff", "--name-only", "f.txt"); out != "f.txt\n" {
+		t.Fatalf("diff <path> should limit output to the path:\n%s", out)
+	}
+	if out := fixtures.CaptureCLI(t, "diff", "--name-only", "HEAD", "g.txt"); out != "g.txt\n" {
+		t.Fatalf("diff <commit> <path> should limit output to the path:\n%s", out)
+	}
+	if err := fixtures.TryCLI(t, "diff", "nope.txt"); err == nil || !strings.Contains(err.Error(), "nope.txt") {
+		t.Fatalf("an argument that is neither a revision nor a path should fail, got %v", err)
+	}
+}
+
+func TestDiffStatCountsMissingFinalNewline(t *testing.T) {
+	repoPath := fixtures.InitRepo(t)
+	cleanup := fixtures.Chdir(t, repoPath)
+	defer cleanup()
+
+	fixtures.CommitFile(t, repoPath, "g.txt", "x", "Initial commit")
+	fixtures.CreateFiles(t, repoPath, map[string]string{"g.txt": "x\n"})
+
+	if patch := fixtures.CaptureCLI(t, "diff"); !strings.Contains(patch, "-x\n\\ No newline at end of file\n+x\n") {
+		t.Fatalf("unexpected patch:\n%s", patch)
+	}
+	if stat := fixtures.CaptureCLI(t, "diff", "--stat"); !strings.Contains(stat, " g.txt | 2 +-\n") {
+		t.Fatalf("--stat should count the changed last line:\n%s", stat)
+	}
+}
@@ -0,0 +1,190 @@
+package unit
+
+import (
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"minigit/internal/cli"
+	"minigit/internal/objects"
+	"minigit/internal/repository"
+	"minigit/test/fixtures"
+)
+
+// Path of a loose object, made writable so tests can damage it
+func looseObjectPath(t *testing.T, repoPath, hash string) string {
+	t.Helper()
+
+	path := filepath.Join(repoPath, ".minigit", "objects", hash[:2], hash[2:])
+	if err := os.Chmod(path, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func expectFsckFailure(t *testing.T, args ...string) string {
+	t.Helper()
+
+	out, err := fixtures.TryCaptureCLI(t, append([]string{"fsck"}, args...)...)
+	var code cli.ExitCode
+	if !errors.As(err, &code) || code != 1 {
+		t.Fatalf("expected fsck to exit with status 1, got %v\n%s", err, out)
+	}
+	return out
+}
+
+func TestFsckAcceptsHealthyRepository(t *testing.T) {
+	repoPath := fixtures.InitRepo(t)
+	cleanup := fixtures.Chdir(t, repoPath)
+	defer cleanup()
+
+	// An unborn branch is only worth a notice
+	if out := fixtures.CaptureCLI(t, "fsck"); out != "notice: HEAD points to an unborn branch (main)\n" {
+		t.Fatalf("unexpected output for an empty repository: %q", out)
+	}
+
+	fixtures.CreateFiles(t, repoPath, map[string]string{"a.txt": "a\n", "dir/b.txt": "b\n"})
+	fixtures.RunCLI(t, "add", ".")
+	fixtures.RunCLI(t, "commit", "-m", "First")
+	fixtures.CommitFile(t, repoPath, "a.txt", "changed\n", "Second")
+
+	if out := fixtures.CaptureCLI(t, "fsck"); out != "" {
+		t.Fatalf("unexpected output for a healthy repository:\n%s", out)
+	}
+
+	fixtures.RunCLI(t, "gc", "-q")
+	if out := fixtures.CaptureCLI(t, "fsck", "--full"); out != "" {
+		t.Fatalf("unexpected output for a packed repository:\n%s", out)
+	}
+}
+
+func TestFsckReportsDanglingObjects(t *testing.T) {
+	repoPath := fixtures.InitRepo(t)
+	cleanup := fixtures.Chdir(t, repoPath)
+	defer cleanup()
+
+	fixtures.CommitFile(t, repoPath, "a.txt", "a\n", "First")
+
+	repo, _ := repository.NewRepository(repoPath)
+	store, _ := repo.GetObjectStore()
+	blob, _ := store.StoreObject(objects.BlobObject, []byte("lost\n"))
+	rawBlob, _ := hex.DecodeString(blob)
+	tree, _ := store.StoreObject(objects.TreeObject, []byte("100644 lost.txt\x00"+string(rawBlob)))
+
+	// The blob is only referenced by the unreachable tree, so just the tree dangles
+	if out := fixtures.CaptureCLI(t, "fsck"); out != "dangling tree "+tree+"\n" {
+		t.Fatalf("unexpected fsck output:\n%s", out)
+	}
+
+	out := fixtures.CaptureCLI(t, "fsck", "--unreachable")
+	if !strings.Contains(out, "unreachable blob "+blob+"\n") || !strings.Contains(out, "unreachable tree "+tree+"\n") {
+		t.Fatalf("unexpected --unreachable output:\n%s", out)
+	}
+
+	orphan, _ := store.StoreObject(objects.BlobObject, []byte("orphan\n"))
+	fixtures.CaptureCLI(t, "fsck", "--lost-found")
+	content, err := os.ReadFile(filepath.Join(repoPath, ".minigit", "lost-found", "other", orphan))
+	if err != nil || string(content) != "orphan\n" {
+		t.Fatalf("dangling blob not saved by content: %q, %v", content, err)
+	}
+	content, err = os.ReadFile(filepath.Join(repoPath, ".minigit", "lost-found", "other", tree))
+	if err != nil || string(content) != tree+"\n" {
+		t.Fatalf("dangling tree not saved by name: %q, %v", content, err)
+	}
+}
+
+func TestFsckDetectsCorruptAndMissingObjects(t *testing.T) {
+	repoPath := fixtures.InitRepo(t)
+	cleanup := fixtures.Chdir(t, repoPath)
+	defer cleanup()
+
+	fixtures.CreateFiles(t, repoPath, map[string]string{"a.txt": "a\n", "b.txt": "b\n"})
+	fixtures.RunCLI(t, "add", ".")
+	fixtures.RunCLI(t, "commit", "-m", "First")
+
+	blobA := strings.TrimSpace(fixtures.CaptureCLI(t, "rev-parse", "HEAD:a.txt"))
+	blobB := strings.TrimSpace(fixtures.CaptureCLI(t, "rev-parse", "HEAD:b.txt"))
+
+	// b's file now holds a's object
+	data, err := os.ReadFile(looseObjectPath(t, repoPath, blobA))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(looseObjectPath(t, repoPath, blobB), data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	out := expectFsckFailure(t)
+	if !strings.Contains(out, "error: hash mismatch for "+blobB+" (found "+blobA+")") {
+		t.Fatalf("hash mismatch not reported:\n%s", out)
+	}
+
+	if err := os.Remove(looseObjectPath(t, repoPath, blobB)); err != nil {
+		t.Fatal(err)
+	}
+	out = expectFsckFailure(t)
+	if !strings.Contains(out, "missing blob "+blobB+"\n") {
+		t.Fatalf("missing blob not reported:\n%s", out)
+	}
+}
+
+func TestFsckChecksRefs(t *testing.T) {
+	repoPath := fixtures.InitRepo(t)
+	cleanup := fixtures.Chdir(t, repoPath)
+	defer cleanup()
+
+	fixtures.CommitFile(t, repoPath, "a.txt", "a\n", "First")
+	tree := strings.TrimSpace(fixtures.CaptureCLI(t, "rev-parse", "HEAD^{tree}"))
+
+	headsDir := filepath.Join(repoPath, ".minigit", "refs", "heads")
+	if err := os.WriteFile(filepath.Join(headsDir, "treeish"), []byte(tree+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	out := expectFsckFailure(t)
+	if !strings.Contains(out, "error: refs/heads/treeish: not a commit") {
+		t.Fatalf("branch pointing at a tree not reported:\n%s", out)
+	}
+
+	bogus := strings.Repeat("ab", 20)
+	if err := os.WriteFile(filepath.Join(headsDir, "treeish"), []byte(bogus+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	out = expectFsckFailure(t)
+	if !strings.Contains(out, "error: refs/heads/treeish: invalid sha1 pointer "+bogus) {
+		t.Fatalf("dangling ref not reported:\n%s", out)
+	}
+}
+
+func TestFsckFullVerifiesPacks(t *testing.T) {
+	repoPath := fixtures.InitRepo(t)
+	cleanup := fixtures.Chdir(t, repoPath)
+	defer cleanup()
+
+	fixtures.CommitFile(t, repoPath, "a.txt", "a\n", "First")
+	fixtures.RunCLI(t, "gc", "-q")
+
+	packs, _ := filepath.Glob(filepath.Join(repoPath, ".minigit", "objects", "pack", "*.pack"))
+	if len(packs) != 1 {
+		t.Fatalf("expected one pack, found %v", packs)
+	}
+	os.Chmod(packs[0], 0644)
+	data, err := os.ReadFile(packs[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Damage the checksum rather than an object so the pack still reads
+	data[len(data)-1] ^= 0xff
+	if err := os.WriteFile(packs[0], data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if out := fixtures.CaptureCLI(t, "fsck"); out != "" {
+		t.Fatalf("packs should only be checked with --full:\n%s", out)
+	}
+	out := expectFsckFailure(t, "--full")
+	if !strings.Contains(out, "checksum mismatch") {
+		t.Fatalf("pack checksum mismatch not reported:\n%s", out)
+	}
+}
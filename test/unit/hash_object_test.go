@@ -0,0 +1,57 @@
+package unit
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"minigit/internal/repository"
+	"minigit/test/fixtures"
+)
+
+func TestHashObject(t *testing.T) {
+	repoPath := fixtures.InitRepo(t)
+	cleanup := fixtures.Chdir(t, repoPath)
+	defer cleanup()
+
+	fixtures.CreateFiles(t, repoPath, map[string]string{"test.txt": "test content\n"})
+	want := "d670460b4b4aece5915caf5c68d12f560a9fe3e4"
+
+	if out := fixtures.CaptureCLI(t, "hash-object", "test.txt"); out != want+"\n" {
+		t.Fatalf("unexpected hash %q", out)
+	}
+
+	repo, _ := repository.NewRepository(repoPath)
+	store, _ := repo.GetObjectStore()
+	if store.HasObject(want) {
+		t.Fatal("hash-object without -w must not store the object")
+	}
+
+	fixtures.SetStdin(t, "test content\n")
+	if out := fixtures.CaptureCLI(t, "hash-object", "-w", "--stdin"); out != want+"\n" {
+		t.Fatalf("unexpected hash %q", out)
+	}
+	if !store.HasObject(want) {
+		t.Fatal("hash-object -w should store the object")
+	}
+
+	fixtures.SetStdin(t, "test.txt\n")
+	if out := fixtures.CaptureCLI(t, "hash-object", "--stdin-paths"); out != want+"\n" {
+		t.Fatalf("unexpected hash %q", out)
+	}
+
+	// A tree built by hand hashes like Git's
+	rawHash, _ := hex.DecodeString("83baae61804e65cc73a7201a7252750c76066a30")
+	fixtures.SetStdin(t, "100644 test.txt\x00"+string(rawHash))
+	if out := fixtures.CaptureCLI(t, "hash-object", "-t", "tree", "--stdin"); out != "d8329fc1cc938780ffdd9f94e0d364e0ea74f579\n" {
+		t.Fatalf("unexpected tree hash %q", out)
+	}
+
+	fixtures.SetStdin(t, "not a tree")
+	if err := fixtures.TryCLI(t, "hash-object", "-t", "tree", "--stdin"); err == nil || !strings.Contains(err.Error(), "corrupt tree") {
+		t.Fatalf("expected corrupt tree error, got %v", err)
+	}
+	if err := fixtures.TryCLI(t, "hash-object", "-t", "bogus", "test.txt"); err == nil || !strings.Contains(err.Error(), "invalid object type") {
+		t.Fatalf("expected invalid type error, got %v", err)
+	}
+}
@@ -0,0 +1,174 @@
+package unit
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"minigit/internal/ignore"
+	"minigit/internal/repository"
+	"minigit/test/fixtures"
+)
+
+func TestIgnorePatterns(t *testing.T) {
+	workDir := t.TempDir()
+	fixtures.CreateFiles(t, workDir, map[string]string{
+		".minigitignore": strings.Join([]string{
+			"# build outputs",
+			"*.log",
+			"!keep.log",
+			"build/",
+			"/root-only.txt",
+			"docs/*.tmp",
+			"**/cache",
+			"assets/**",
+			"file[0-9].txt",
+			`\#hash`,
+		}, "\n"),
+		"sub/.minigitignore":         "local.txt\n!*.log\n",
+		".minigit/info/exclude":      "secret.txt\n",
+		"sub/deeper/placeholder.txt": "",
+	})
+
+	matcher, err := ignore.NewMatcher(workDir, filepath.Join(workDir, ".minigit"))
+	if err != nil {
+		t.Fatalf("NewMatcher failed: %v", err)
+	}
+
+	cases := []struct {
+		path    string
+		isDir   bool
+		ignored bool
+	}{
+		{"debug.log", false, true},
+		{"nested/dir/debug.log", false, true},
+		{"keep.log", false, false},
+		{"build", true, true},
+		{"build", false, false}, // directory-only rule
+		{"build/output.bin", false, true},
+		{"src/build/output.bin", false, true},
+		{"root-only.txt", false, true},
+		{"sub/root-only.txt", false, false},
+		{"docs/a.tmp", false, true},
+		{"docs/nested/a.tmp", false, false},
+		{"cache", true, true},
+		{"a/b/cache", true, true},
+		{"assets/img/logo.png", false, true},
+		{"file7.txt", false, true},
+		{"fileX.txt", false, false},
+		{"#hash", false, true},
+		{"secret.txt", false, true},
+		{"sub/local.txt", false, true},
+		{"local.txt", false, false},
+		{"sub/deeper/trace.log", false, false}, // re-included by the deeper file
+		{".github/workflows/ci.yml", false, false},
+		{"my.gitfoo", false, false},
+	}
+
+	for _, tc := range cases {
+		got, err := matcher.IsIgnored(tc.path, tc.isDir)
+		if err != nil {
+			t.Fatalf("IsIgnored(%s): %v", tc.path, err)
+		}
+		if got != tc.ignored {
+			t.Errorf("IsIgnored(%q, dir=%v) = %v, want %v", tc.path, tc.isDir, got, tc.ignored)
+		}
+	}
+}
+
+func TestIgnoreCannotReincludeInsideIgnoredDirectory(t *testing.T) {
+	workDir := t.TempDir()
+	fixtures.CreateFiles(t, workDir, map[string]string{
+		".minigitignore": "logs/\n!logs/important.log\n",
+	})
+
+	matcher, err := ignore.NewMatcher(workDir, filepath.Join(workDir, ".minigit"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ignored, _ := matcher.IsIgnored("logs/important.log", false); !ignored {
+		t.Fatal("files inside an ignored directory stay ignored")
+	}
+}
+
+func TestAddAndStatusRespectIgnoreFiles(t *testing.T) {
+	repoPath := fixtures.InitRepo(t)
+	cleanup := fixtures.Chdir(t, repoPath)
+	defer cleanup()
+
+	fixtures.CreateFiles(t, repoPath, map[string]string{
+		".minigitignore":           "*.log\nbuild/\n",
+		"main.go":                  "package main\n",
+		"debug.log":                "noise\n",
+		"build/app":                "binary\n",
+		".github/workflows/ci.yml": "on: push\n",
+		"my.gitfoo":                "data\n",
+	})
+
+	status := fixtures.CaptureCLI(t, "status")
+	for _, hidden := range []string{"debug.log", "build/app"} {
+		if strings.Contains(status, hidden) {
+			t.Fatalf("status should hide ignored %s:\n%s", hidden, status)
+		}
+	}
+	for _, shown := range []string{".github/workflows/ci.yml", "my.gitfoo", ".minigitignore"} {
+		if !strings.Contains(status, shown) {
+			t.Fatalf("status should list %s:\n%s", shown, status)
+		}
+	}
+
+	fixtures.RunCLI(t, "add", ".")
+
+	repo, _ := repository.NewRepository(repoPath)
+	idx, _ := repo.GetIndex()
+	entries := idx.GetEntries()
+	for _, path := range []string{"main.go", ".github/workflows/ci.yml", "my.gitfoo", ".minigitignore"} {
+		if _, ok := entries[path]; !ok {
+			t.Fatalf("expected %s to be staged, index has %v", path, entries)
+		}
+	}
+	for _, path := range []string{"debug.log", "build/app"} {
+		if _, ok := entries[path]; ok {
+			t.Fatalf("ignored %s should not be staged", path)
+		}
+	}
+
+	err := fixtures.TryCLI(t, "add", "debug.log")
+	if err == nil || !strings.Contains(err.Error(), "ignored by one of your .minigitignore files") {
+		t.Fatalf("expected ignored path error, got %v", err)
+	}
+
+	// Forcing tracks it, after which changes show up despite the rule
+	fixtures.RunCLI(t, "add", "-f", "debug.log")
+	fixtures.RunCLI(t, "commit", "-m", "Initial commit")
+	fixtures.CreateFiles(t, repoPath, map[string]string{"debug.log": "changed\n"})
+
+	status = fixtures.CaptureCLI(t, "status")
+	if !strings.Contains(status, "modified:   debug.log") {
+		t.Fatalf("tracked files are not subject to ignore rules:\n%s", status)
+	}
+}
+
+func TestCheckIgnore(t *testing.T) {
+	repoPath := fixtures.InitRepo(t)
+	cleanup := fixtures.Chdir(t, repoPath)
+	defer cleanup()
+
+	fixtures.CreateFiles(t, repoPath, map[string]string{
+		".minigitignore": "*.log\n!keep.log\n",
+		"a.log":          "",
+		"keep.log":       "",
+		"main.go":        "",
+	})
+
+	if out := fixtures.CaptureCLI(t, "check-ignore", "a.log", "keep.log", "main.go"); out != "a.log\n" {
+		t.Fatalf("unexpected check-ignore output:\n%s", out)
+	}
+
+	out := fixtures.CaptureCLI(t, "check-ignore", "-v", "a.log", "keep.log", "main.go")
+	want := ".minigitignore:1:*.log\ta.log\n.minigitignore:2:!keep.log\tkeep.log\n"
+	if out != want {
+		t.Fatalf("unexpected verbose output:\ngot:\n%s\nwant:\n%s", out, want)
+	}
+}
@@ -0,0 +1,159 @@
+package unit
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"minigit/internal/repository"
+	"minigit/test/fixtures"
+)
+
+func TestIndexIsWrittenInGitFormat(t *testing.T) {
+	repoPath := fixtures.InitRepo(t)
+	cleanup := fixtures.Chdir(t, repoPath)
+	defer cleanup()
+
+	fixtures.CreateFiles(t, repoPath, map[string]string{
+		"b.txt":       "b\n",
+		"dir/a.txt":   "a\n",
+		"dir.txt":     "sorted between dir and dir/\n",
+		"nested/x/yz": "deep\n",
+	})
+	fixtures.RunCLI(t, "add", ".")
+
+	data, err := os.ReadFile(filepath.Join(repoPath, ".minigit", "index"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(data, []byte("DIRC\x00\x00\x00\x02\x00\x00\x00\x04")) {
+		t.Fatalf("unexpected index header % x", data[:12])
+	}
+	sum := sha1.Sum(data[:len(data)-sha1.Size])
+	if !bytes.Equal(sum[:], data[len(data)-sha1.Size:]) {
+		t.Fatal("index checksum does not match its content")
+	}
+
+	// Entries are sorted by their slash-separated path
+	want := "b.txt\ndir.txt\ndir/a.txt\nnested/x/yz\n"
+	if got := fixtures.CaptureCLI(t, "ls-files"); got != want {
+		t.Fatalf("unexpected entries:\n%s", got)
+	}
+
+	gitPath, err := exec.LookPath("git")
+	if err != nil {
+		t.Skip("git not installed")
+	}
+	cmd := exec.Command(gitPath, "--git-dir", ".minigit", "ls-files", "--stage")
+	cmd.Env = append(os.Environ(), "GIT_INDEX_FILE="+filepath.Join(repoPath, ".minigit", "index"))
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git ls-files failed: %v\n%s", err, out)
+	}
+	if got := fixtures.CaptureCLI(t, "ls-files", "--stage"); string(out) != got {
+		t.Fatalf("git reads the index as:\n%s\nwant:\n%s", out, got)
+	}
+}
+
+func TestIndexKeepsStatDataAndConflicts(t *testing.T) {
+	repoPath := fixtures.InitRepo(t)
+	cleanup := fixtures.Chdir(t, repoPath)
+	defer cleanup()
+
+	fixtures.CommitFile(t, repoPath, "file.txt", "base\n", "Base")
+	fixtures.RunCLI(t, "checkout", "-b", "feature")
+	fixtures.CommitFile(t, repoPath, "file.txt", "theirs\n", "Theirs")
+	fixtures.RunCLI(t, "checkout", "main")
+	fixtures.CreateFiles(t, repoPath, map[string]string{"other.txt": "other\n"})
+	fixtures.RunCLI(t, "add", "other.txt")
+	fixtures.CommitFile(t, repoPath, "file.txt", "ours\n", "Ours")
+	fixtures.TryCLI(t, "merge", "feature")
+
+	repo, err := repository.NewRepository(repoPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	idx, _ := repo.GetIndex()
+
+	info, _ := os.Stat(filepath.Join(repoPath, "other.txt"))
+	entry := idx.GetEntries()["other.txt"]
+	if entry == nil || entry.Size != info.Size() || !entry.ModTime.Equal(info.ModTime()) {
+		t.Fatalf("stat data not kept: %+v", entry)
+	}
+	if entry.CTime.IsZero() {
+		t.Fatal("ctime not recorded")
+	}
+
+	stages := idx.GetConflicts()["file.txt"]
+	if len(stages) != 3 {
+		t.Fatalf("expected three stages, got %d", len(stages))
+	}
+	for i, stage := range stages {
+		if stage.Stage != i+1 {
+			t.Fatalf("stage %d recorded as %d", i+1, stage.Stage)
+		}
+	}
+}
+
+func TestJSONIndexIsMigrated(t *testing.T) {
+	repoPath := fixtures.InitRepo(t)
+	cleanup := fixtures.Chdir(t, repoPath)
+	defer cleanup()
+
+	fixtures.CreateFiles(t, repoPath, map[string]string{"old.txt": "old\n", "new.txt": "new\n"})
+
+	// As written by older versions, with raw permission bits
+	indexPath := filepath.Join(repoPath, ".minigit", "index")
+	legacy := `{
+  "version": 1,
+  "entries": {
+    "old.txt": {
+      "path": "old.txt",
+      "hash": "` + calculateBlobHash("old\n") + `",
+      "mode": 420,
+      "size": 4,
+      "mod_time": "2024-01-01T00:00:00Z"
+    }
+  }
+}`
+	if err := os.WriteFile(indexPath, []byte(legacy), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := fixtures.CaptureCLI(t, "ls-files", "--stage"); !strings.Contains(got, "100644 "+calculateBlobHash("old\n")+" 0\told.txt") {
+		t.Fatalf("legacy index not read:\n%s", got)
+	}
+
+	fixtures.RunCLI(t, "add", "new.txt")
+	data, _ := os.ReadFile(indexPath)
+	if !bytes.HasPrefix(data, []byte("DIRC")) {
+		t.Fatal("index was not rewritten in the binary format")
+	}
+	if got := fixtures.CaptureCLI(t, "ls-files"); got != "new.txt\nold.txt\n" {
+		t.Fatalf("entries lost in migration:\n%s", got)
+	}
+}
+
+func TestCorruptIndexIsRefused(t *testing.T) {
+	repoPath := fixtures.InitRepo(t)
+	cleanup := fixtures.Chdir(t, repoPath)
+	defer cleanup()
+
+	fixtures.CommitFile(t, repoPath, "file.txt", "content\n", "Initial")
+
+	indexPath := filepath.Join(repoPath, ".minigit", "index")
+	data, _ := os.ReadFile(indexPath)
+	data[20] ^= 0xff
+	if err := os.WriteFile(indexPath, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	err := fixtures.TryCLI(t, "status")
+	if err == nil || !strings.Contains(err.Error(), "bad index file sha1 signature") {
+		t.Fatalf("expected a checksum error, got %v", err)
+	}
+}
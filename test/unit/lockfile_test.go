@@ -0,0 +1,300 @@
+package unit
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"minigit/internal/lockfile"
+	"minigit/internal/refs"
+	"minigit/internal/repository"
+	"minigit/test/fixtures"
+)
+
+// Makes Acquire give up quickly on held locks for the rest of the test
+func shortLockTimeout(t *testing.T) {
+	t.Helper()
+
+	orig := lockfile.Timeout
+	lockfile.Timeout = 20 * time.Millisecond
+	t.Cleanup(func() { lockfile.Timeout = orig })
+}
+
+func TestLockfileCommitAndRollback(t *testing.T) {
+	shortLockTimeout(t)
+	path := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(path, []byte("old\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	lock, err := lockfile.Acquire(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := lockfile.Acquire(path); !errors.Is(err, lockfile.ErrLocked) {
+		t.Fatalf("expected a held lock to be refused, got %v", err)
+	}
+
+	lock.Write([]byte("discarded\n"))
+	if err := lock.Rollback(); err != nil {
+		t.Fatal(err)
+	}
+	if content, _ := os.ReadFile(path); string(content) != "old\n" {
+		t.Fatalf("rollback changed the file: %q", content)
+	}
+
+	if err := lockfile.WriteFile(path, []byte("new\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if content, _ := os.ReadFile(path); string(content) != "new\n" {
+		t.Fatalf("unexpected content after commit: %q", content)
+	}
+	if _, err := os.Stat(path + ".lock"); !os.IsNotExist(err) {
+		t.Fatalf("lock file left behind: %v", err)
+	}
+}
+
+func TestLockfileClearsStaleLocks(t *testing.T) {
+	shortLockTimeout(t)
+	path := filepath.Join(t.TempDir(), "file")
+
+	if err := os.WriteFile(path+".lock", nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-2 * lockfile.StaleAfter)
+	if err := os.Chtimes(path+".lock", old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := lockfile.WriteFile(path, []byte("content\n"), 0644); err != nil {
+		t.Fatalf("stale lock was not cleared: %v", err)
+	}
+}
+
+func TestStaleLockIsBrokenOnlyOnce(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "file")
+	old := time.Now().Add(-2 * lockfile.StaleAfter)
+
+	orig := lockfile.Timeout
+	lockfile.Timeout = time.Minute
+	t.Cleanup(func() { lockfile.Timeout = orig })
+
+	for range 5 {
+		if err := os.WriteFile(path+".lock", nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path+".lock", old, old); err != nil {
+			t.Fatal(err)
+		}
+
+		// Racing processes all see the stale lock, but only one may hold it at a time
+		var wg sync.WaitGroup
+		var holders, overlaps atomic.Int32
+		for range 8 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				lock, err := lockfile.Acquire(path)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				if holders.Add(1) > 1 {
+					overlaps.Add(1)
+				}
+				time.Sleep(100 * time.Microsecond)
+				holders.Add(-1)
+				if err := lock.Rollback(); err != nil {
+					t.Error(err)
+				}
+			}()
+		}
+		wg.Wait()
+
+		if n := overlaps.Load(); n > 0 {
+			t.Fatalf("lock was held by several writers at once %d times", n)
+		}
+	}
+
+	if leftovers, _ := filepath.Glob(path + ".lock*"); len(leftovers) > 0 {
+		t.Fatalf("lock files left behind: %v", leftovers)
+	}
+}
+
+func TestUpdateRefComparesAndSwaps(t *testing.T) {
+	repoPath := fixtures.InitRepo(t)
+	cleanup := fixtures.Chdir(t, repoPath)
+	defer cleanup()
+
+	fixtures.CommitFile(t, repoPath, "file.txt", "one\n", "First")
+	first := strings.TrimSpace(fixtures.CaptureCLI(t, "rev-parse", "HEAD"))
+	fixtures.CommitFile(t, repoPath, "file.txt", "two\n", "Second")
+	second := strings.TrimSpace(fixtures.CaptureCLI(t, "rev-parse", "HEAD"))
+
+	repo, _ := repository.NewRepository(repoPath)
+	refsMan, _ := repo.GetRefsManager()
+
+	err := refsMan.UpdateRef("refs/heads/main", first, first, "test")
+	var changed *refs.RefChangedError
+	if !errors.As(err, &changed) || changed.Actual != second || changed.Expected != first {
+		t.Fatalf("expected a RefChangedError, got %v", err)
+	}
+	if head, _ := refsMan.GetBranch("main"); head != second {
+		t.Fatalf("failed update moved main to %s", head)
+	}
+
+	if err := refsMan.UpdateRef("refs/heads/main", first, second, "test"); err != nil {
+		t.Fatal(err)
+	}
+	if head, _ := refsMan.GetBranch("main"); head != first {
+		t.Fatalf("main is at %s after update", head)
+	}
+
+	// ZeroHash only accepts refs that don't exist yet
+	if err := refsMan.UpdateRef("refs/heads/main", second, refs.ZeroHash, "test"); !errors.As(err, &changed) {
+		t.Fatalf("expected creating an existing ref to fail, got %v", err)
+	}
+	if err := refsMan.UpdateRef("refs/heads/new", second, refs.ZeroHash, "test"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestConcurrentRefUpdatesDoNotLoseWrites(t *testing.T) {
+	repoPath := fixtures.InitRepo(t)
+	cleanup := fixtures.Chdir(t, repoPath)
+	defer cleanup()
+
+	fixtures.CommitFile(t, repoPath, "file.txt", "one\n", "First")
+	base := strings.TrimSpace(fixtures.CaptureCLI(t, "rev-parse", "HEAD"))
+	fixtures.CommitFile(t, repoPath, "file.txt", "two\n", "Second")
+	fixtures.RunCLI(t, "reset", "--soft", "HEAD~1")
+	next := strings.TrimSpace(fixtures.CaptureCLI(t, "rev-parse", "HEAD@{1}"))
+
+	repo, _ := repository.NewRepository(repoPath)
+	refsMan, _ := repo.GetRefsManager()
+
+	// Every writer expects the same old value, so exactly one may win
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	succeeded := 0
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := refsMan.UpdateRef("refs/heads/main", next, base, "race"); err == nil {
+				mu.Lock()
+				succeeded++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if succeeded != 1 {
+		t.Fatalf("%d concurrent compare-and-swap updates succeeded, want 1", succeeded)
+	}
+	if head, _ := refsMan.GetBranch("main"); head != next {
+		t.Fatalf("main is at %s, want %s", head, next)
+	}
+}
+
+func TestConcurrentRefUpdatesAreLoggedInOrder(t *testing.T) {
+	repoPath := fixtures.InitRepo(t)
+	cleanup := fixtures.Chdir(t, repoPath)
+	defer cleanup()
+
+	repo, _ := repository.NewRepository(repoPath)
+	refsMan, _ := repo.GetRefsManager()
+
+	// Writers queue up behind each other's flushes
+	orig := lockfile.Timeout
+	lockfile.Timeout = time.Minute
+	t.Cleanup(func() { lockfile.Timeout = orig })
+
+	var wg sync.WaitGroup
+	for i := range 16 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			hash := fmt.Sprintf("%040x", i+1)
+			if err := refsMan.UpdateRef("refs/heads/topic", hash, "", "race"); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	// Each entry starts where the one before it ended
+	entries, err := refsMan.ReadReflog("refs/heads/topic")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 16 {
+		t.Fatalf("expected 16 reflog entries, got %d", len(entries))
+	}
+	for i := 1; i < len(entries); i++ {
+		if entries[i].Old != entries[i-1].New {
+			t.Fatalf("entry %d moves from %s, but the previous one ended at %s", i, entries[i].Old, entries[i-1].New)
+		}
+	}
+}
+
+func TestCommitRefusesLockedBranch(t *testing.T) {
+	shortLockTimeout(t)
+	repoPath := fixtures.InitRepo(t)
+	cleanup := fixtures.Chdir(t, repoPath)
+	defer cleanup()
+
+	fixtures.CommitFile(t, repoPath, "file.txt", "one\n", "First")
+	head := fixtures.CaptureCLI(t, "rev-parse", "HEAD")
+
+	lockPath := filepath.Join(repoPath, ".minigit", "refs", "heads", "main.lock")
+	if err := os.WriteFile(lockPath, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	fixtures.CreateFiles(t, repoPath, map[string]string{"file.txt": "two\n"})
+	fixtures.RunCLI(t, "add", "file.txt")
+	err := fixtures.TryCLI(t, "commit", "-m", "Second")
+	if err == nil || !strings.Contains(err.Error(), "main.lock': File exists") {
+		t.Fatalf("expected the commit to fail on the held lock, got %v", err)
+	}
+	if got := fixtures.CaptureCLI(t, "rev-parse", "HEAD"); got != head {
+		t.Fatalf("HEAD moved to %q despite the lock", got)
+	}
+
+	// The lock is not mistaken for a branch
+	if out := fixtures.CaptureCLI(t, "branch"); strings.Contains(out, "lock") {
+		t.Fatalf("branch listing shows the lock file:\n%s", out)
+	}
+
+	os.Remove(lockPath)
+	fixtures.RunCLI(t, "commit", "-m", "Second")
+}
+
+func TestStaleLocksMovedAsideAreNotRefs(t *testing.T) {
+	repoPath := fixtures.InitRepo(t)
+	cleanup := fixtures.Chdir(t, repoPath)
+	defer cleanup()
+
+	fixtures.CommitFile(t, repoPath, "file.txt", "one\n", "First")
+
+	// Left behind when a stale lock could not be put back
+	aside := filepath.Join(repoPath, ".minigit", "refs", "heads", "main.lock.stale-1-2")
+	if err := os.WriteFile(aside, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if out := fixtures.CaptureCLI(t, "branch"); out != "* main\n" {
+		t.Fatalf("branch listing shows the stale lock:\n%s", out)
+	}
+	if out := fixtures.CaptureCLI(t, "fsck"); strings.Contains(out, "stale") {
+		t.Fatalf("fsck should not check the stale lock as a ref:\n%s", out)
+	}
+}
@@ -0,0 +1,174 @@
+package unit
+
+import (
+	"strings"
+	"testing"
+
+	"minigit/internal/objects"
+	"minigit/internal/repository"
+	"minigit/test/fixtures"
+)
+
+func TestLogShowsHistoryNewestFirst(t *testing.T) {
+	repoPath := fixtures.InitRepo(t)
+	cleanup := fixtures.Chdir(t, repoPath)
+	defer cleanup()
+
+	fixtures.CreateFiles(t, repoPath, map[string]string{"a.txt": "a"})
+	fixtures.RunCLI(t, "add", "a.txt")
+	fixtures.RunCLI(t, "commit", "-m", "First commit")
+
+	fixtures.CreateFiles(t, repoPath, map[string]string{"b.txt": "b"})
+	fixtures.RunCLI(t, "add", "b.txt")
+	fixtures.RunCLI(t, "commit", "-m", "Second commit")
+
+	out := fixtures.CaptureCLI(t, "log")
+	if strings.Count(out, "commit ") != 2 {
+		t.Fatalf("expected 2 commits in log, got:\n%s", out)
+	}
+	if !strings.Contains(out, "Author: ") || !strings.Contains(out, "Date:   ") {
+		t.Fatalf("log missing author/date lines:\n%s", out)
+	}
+	if strings.Index(out, "Second commit") > strings.Index(out, "First commit") {
+		t.Fatalf("expected newest commit first:\n%s", out)
+	}
+}
+
+func TestLogOnelineAndMaxCount(t *testing.T) {
+	repoPath := fixtures.InitRepo(t)
+	cleanup := fixtures.Chdir(t, repoPath)
+	defer cleanup()
+
+	for i, name := range []string{"one.txt", "two.txt", "three.txt"} {
+		fixtures.CreateFiles(t, repoPath, map[string]string{name: name})
+		fixtures.RunCLI(t, "add", name)
+		fixtures.RunCLI(t, "commit", "-m", "Commit "+string(rune('1'+i)))
+	}
+
+	out := fixtures.CaptureCLI(t, "log", "--oneline", "-n", "2")
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines, got %d:\n%s", len(lines), out)
+	}
+	if !strings.HasSuffix(lines[0], " Commit 3") || !strings.HasSuffix(lines[1], " Commit 2") {
+		t.Fatalf("unexpected oneline output:\n%s", out)
+	}
+	if len(strings.Fields(lines[0])[0]) != 7 {
+		t.Fatalf("expected abbreviated hash, got %q", lines[0])
+	}
+}
+
+func TestLogMergeHistory(t *testing.T) {
+	repoPath := fixtures.InitRepo(t)
+	cleanup := fixtures.Chdir(t, repoPath)
+	defer cleanup()
+
+	fixtures.CreateFiles(t, repoPath, map[string]string{"base.txt": "base"})
+	fixtures.RunCLI(t, "add", "base.txt")
+	fixtures.RunCLI(t, "commit", "-m", "Base")
+
+	fixtures.CreateFiles(t, repoPath, map[string]string{"main.txt": "main"})
+	fixtures.RunCLI(t, "add", "main.txt")
+	fixtures.RunCLI(t, "commit", "-m", "Mainline")
+
+	repo, _ := repository.NewRepository(repoPath)
+	store, _ := repo.GetObjectStore()
+	refsMan, _ := repo.GetRefsManager()
+
+	mainHead, _ := refsMan.GetBranch("main")
+	mainCommit, _ := store.ParseCommit(mustLoad(t, store, mainHead))
+	baseHash := mainCommit.Parents[0]
+	baseCommit, _ := store.ParseCommit(mustLoad(t, store, baseHash))
+
+	sideHash, err := store.CreateCommit(baseCommit.Tree, []string{baseHash}, "Test User <test@example.com>", "Side work")
+	if err != nil {
+		t.Fatalf("create side commit: %v", err)
+	}
+	mergeHash, err := store.CreateCommit(mainCommit.Tree, []string{mainHead, sideHash}, "Test User <test@example.com>", "Merge side")
+	if err != nil {
+		t.Fatalf("create merge commit: %v", err)
+	}
+	if err := refsMan.SetBranch("main", mergeHash, ""); err != nil {
+		t.Fatalf("set branch: %v", err)
+	}
+
+	full := fixtures.CaptureCLI(t, "log", "--oneline")
+	if strings.Count(full, "\n") != 4 {
+		t.Fatalf("expected 4 commits in full history, got:\n%s", full)
+	}
+	if strings.Count(full, "Base") != 1 {
+		t.Fatalf("base commit visited more than once:\n%s", full)
+	}
+
+	firstParent := fixtures.CaptureCLI(t, "log", "--oneline", "--first-parent")
+	if strings.Contains(firstParent, "Side work") {
+		t.Fatalf("--first-parent should not include side commit:\n%s", firstParent)
+	}
+	if strings.Count(firstParent, "\n") != 3 {
+		t.Fatalf("expected 3 first-parent commits, got:\n%s", firstParent)
+	}
+
+	out := fixtures.CaptureCLI(t, "log", "-n", "1")
+	if !strings.Contains(out, "Merge: ") {
+		t.Fatalf("merge commit should list its parents:\n%s", out)
+	}
+
+	fromRev := fixtures.CaptureCLI(t, "log", "--oneline", sideHash)
+	if strings.Count(fromRev, "\n") != 2 || !strings.Contains(fromRev, "Side work") {
+		t.Fatalf("log from revision gave unexpected output:\n%s", fromRev)
+	}
+}
+
+func TestLogEmptyRepository(t *testing.T) {
+	repoPath := fixtures.InitRepo(t)
+	cleanup := fixtures.Chdir(t, repoPath)
+	defer cleanup()
+
+	err := fixtures.TryCLI(t, "log")
+	if err == nil || !strings.Contains(err.Error(), "does not have any commits yet") {
+		t.Fatalf("expected no commits error, got %v", err)
+	}
+}
+
+func mustLoad(t *testing.T, store *objects.Store, hash string) []byte {
+	t.Helper()
+
+	obj, err := store.LoadObject(hash)
+	if err != nil {
+		t.Fatalf("load object %s: %v", hash, err)
+	}
+	return obj.Content
+}
+
+func TestLogSymmetricDifferenceWithCrissCrossMerges(t *testing.T) {
+	repoPath := fixtures.InitRepo(t)
+	cleanup := fixtures.Chdir(t, repoPath)
+	defer cleanup()
+
+	fixtures.CommitFile(t, repoPath, "a.txt", "a", "root")
+	tree := strings.TrimSpace(fixtures.CaptureCLI(t, "write-tree"))
+	commitTree := func(message string, parents ...string) string {
+		args := []string{"commit-tree", tree, "-m", message}
+		for _, parent := range parents {
+			args = append(args, "-p", parent)
+		}
+		return strings.TrimSpace(fixtures.CaptureCLI(t, args...))
+	}
+
+	// a1 and b1 are both merged into each side, so both are merge bases
+	root := strings.TrimSpace(fixtures.CaptureCLI(t, "rev-parse", "HEAD"))
+	a1 := commitTree("a1", root)
+	b1 := commitTree("b1", root)
+	fixtures.RunCLI(t, "branch", "left", commitTree("a2", a1, b1))
+	fixtures.RunCLI(t, "branch", "right", commitTree("b2", b1, a1))
+
+	got := fixtures.CaptureCLI(t, "log", "--oneline", "left...right")
+	if strings.Count(got, "\n") != 2 || !strings.Contains(got, " a2\n") || !strings.Contains(got, " b2\n") {
+		t.Fatalf("log left...right should leave out everything both sides have:\n%s", got)
+	}
+
+	out := fixtures.CaptureCLI(t, "rev-parse", "left...right")
+	if strings.Count(out, "\n") != 4 || !strings.Contains(out, "^"+a1+"\n") || !strings.Contains(out, "^"+b1+"\n") {
+		t.Fatalf("rev-parse left...right should exclude both merge bases:\n%s", out)
+	}
+}
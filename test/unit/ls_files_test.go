@@ -0,0 +1,79 @@
+package unit
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"minigit/test/fixtures"
+)
+
+func TestLsFiles(t *testing.T) {
+	repoPath := fixtures.InitRepo(t)
+	cleanup := fixtures.Chdir(t, repoPath)
+	defer cleanup()
+
+	fixtures.CreateFiles(t, repoPath, map[string]string{
+		"a.txt":          "a\n",
+		"b.txt":          "version 1\n",
+		"lib/c.txt":      "c\n",
+		".minigitignore": "*.log\n",
+	})
+	fixtures.RunCLI(t, "add", ".")
+	fixtures.RunCLI(t, "commit", "-m", "Initial commit")
+
+	fixtures.CreateFiles(t, repoPath, map[string]string{
+		"a.txt":     "changed\n",
+		"new.txt":   "new\n",
+		"debug.log": "noise\n",
+	})
+	os.Remove(filepath.Join(repoPath, "lib", "c.txt"))
+
+	cases := []struct {
+		args []string
+		want string
+	}{
+		{nil, ".minigitignore\na.txt\nb.txt\nlib/c.txt\n"},
+		{[]string{"--modified"}, "a.txt\nlib/c.txt\n"},
+		{[]string{"--deleted"}, "lib/c.txt\n"},
+		{[]string{"--others"}, "debug.log\nnew.txt\n"},
+		{[]string{"--others", "--exclude-standard"}, "new.txt\n"},
+	}
+	for _, tc := range cases {
+		if out := fixtures.CaptureCLI(t, append([]string{"ls-files"}, tc.args...)...); out != tc.want {
+			t.Errorf("ls-files %v:\ngot:\n%s\nwant:\n%s", tc.args, out, tc.want)
+		}
+	}
+
+	out := fixtures.CaptureCLI(t, "ls-files", "--stage")
+	if !strings.Contains(out, "100644 83baae61804e65cc73a7201a7252750c76066a30 0\tb.txt\n") {
+		t.Fatalf("unexpected --stage output:\n%s", out)
+	}
+}
+
+func TestLsFilesShowsConflictStages(t *testing.T) {
+	repoPath := fixtures.InitRepo(t)
+	cleanup := fixtures.Chdir(t, repoPath)
+	defer cleanup()
+
+	setupDivergedBranches(t, repoPath)
+	commitSnapshot(t, repoPath, "file.txt", "one\ntwo\nmain\nfour\nfive\n", "Main edit")
+	fixtures.RunCLI(t, "checkout", "feature")
+	commitSnapshot(t, repoPath, "file.txt", "one\ntwo\nfeature\nfour\nfive\n", "Feature edit")
+	fixtures.RunCLI(t, "checkout", "main")
+
+	if err := fixtures.TryCLI(t, "merge", "feature"); err == nil {
+		t.Fatal("expected the merge to conflict")
+	}
+
+	out := fixtures.CaptureCLI(t, "ls-files", "--stage")
+	for _, stage := range []string{" 1\tfile.txt", " 2\tfile.txt", " 3\tfile.txt"} {
+		if !strings.Contains(out, stage) {
+			t.Fatalf("expected stage %q in:\n%s", stage, out)
+		}
+	}
+	if out := fixtures.CaptureCLI(t, "ls-files"); out != "file.txt\n" {
+		t.Fatalf("unmerged paths should be listed once:\n%s", out)
+	}
+}
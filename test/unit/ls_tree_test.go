@@ -0,0 +1,54 @@
+package unit
+
+import (
+	"strings"
+	"testing"
+
+	"minigit/test/fixtures"
+)
+
+func TestLsTree(t *testing.T) {
+	repoPath := fixtures.InitRepo(t)
+	cleanup := fixtures.Chdir(t, repoPath)
+	defer cleanup()
+
+	fixtures.CreateFiles(t, repoPath, map[string]string{
+		"test.txt":       "version 1\n",
+		"lib/foo.go":     "package lib\n",
+		"lib/sub/bar.go": "package sub\n",
+	})
+	fixtures.RunCLI(t, "add", ".")
+	fixtures.RunCLI(t, "commit", "-m", "Initial commit")
+
+	out := fixtures.CaptureCLI(t, "ls-tree", "HEAD")
+	lines := strings.Split(strings.TrimSuffix(out, "\n"), "\n")
+	if len(lines) != 2 || !strings.HasPrefix(lines[0], "040000 tree ") || !strings.HasSuffix(lines[0], "\tlib") ||
+		lines[1] != "100644 blob 83baae61804e65cc73a7201a7252750c76066a30\ttest.txt" {
+		t.Fatalf("unexpected ls-tree output:\n%s", out)
+	}
+
+	cases := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"-r", "--name-only", "HEAD"}, "lib/foo.go\nlib/sub/bar.go\ntest.txt\n"},
+		{[]string{"-r", "-t", "--name-only", "HEAD"}, "lib\nlib/foo.go\nlib/sub\nlib/sub/bar.go\ntest.txt\n"},
+		{[]string{"--name-only", "HEAD", "lib"}, "lib\n"},
+		// A trailing slash lists the tree's contents, as in Git
+		{[]string{"--name-only", "HEAD", "lib/"}, "lib/foo.go\nlib/sub\n"},
+		{[]string{"-t", "--name-only", "HEAD", "lib/sub/"}, "lib\nlib/sub\nlib/sub/bar.go\n"},
+		{[]string{"-r", "--name-only", "HEAD", "lib/"}, "lib/foo.go\nlib/sub/bar.go\n"},
+		{[]string{"--name-only", "HEAD", "lib/foo.go/"}, ""},
+		{[]string{"-r", "--name-only", "HEAD", "lib"}, "lib/foo.go\nlib/sub/bar.go\n"},
+		{[]string{"--name-only", "HEAD", "lib/foo.go"}, "lib/foo.go\n"},
+	}
+	for _, tc := range cases {
+		if out := fixtures.CaptureCLI(t, append([]string{"ls-tree"}, tc.args...)...); out != tc.want {
+			t.Errorf("ls-tree %v:\ngot:\n%s\nwant:\n%s", tc.args, out, tc.want)
+		}
+	}
+
+	if err := fixtures.TryCLI(t, "ls-tree", "83baae61804e65cc73a7201a7252750c76066a30"); err == nil {
+		t.Fatal("expected an error listing a blob")
+	}
+}
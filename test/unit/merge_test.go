@@ -0,0 +1,309 @@
+package unit
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"minigit/internal/diff"
+	"minigit/internal/repository"
+	"minigit/test/fixtures"
+)
+
+// Creates a base commit on main and a "feature" branch from it,
+// leaving main checked out
+func setupDivergedBranches(t *testing.T, repoPath string) {
+	t.Helper()
+
+	commitSnapshot(t, repoPath, "file.txt", "one\ntwo\nthree\nfour\nfive\n", "Base")
+	fixtures.RunCLI(t, "branch", "feature")
+}
+
+func TestMerge3(t *testing.T) {
+	base := []byte("one\ntwo\nthree\nfour\nfive\n")
+
+	clean := diff.Merge3(base,
+		[]byte("ONE\ntwo\nthree\nfour\nfive\n"),
+		[]byte("one\ntwo\nthree\nfour\nFIVE\n"),
+		"HEAD", "feature")
+	if clean.Conflicts != 0 || string(clean.Content) != "ONE\ntwo\nthree\nfour\nFIVE\n" {
+		t.Fatalf("expected clean merge, got %d conflicts:\n%s", clean.Conflicts, clean.Content)
+	}
+
+	conflicted := diff.Merge3(base,
+		[]byte("one\ntwo\nours\nfour\nfive\n"),
+		[]byte("one\ntwo\ntheirs\nfour\nfive\n"),
+		"HEAD", "feature")
+	want := "one\ntwo\n<<<<<<< HEAD\nours\n=======\ntheirs\n>>>>>>> feature\nfour\nfive\n"
+	if conflicted.Conflicts != 1 || string(conflicted.Content) != want {
+		t.Fatalf("unexpected conflict output (%d conflicts):\n%s", conflicted.Conflicts, conflicted.Content)
+	}
+
+	// The same change on both sides is not a conflict
+	same := diff.Merge3(base, []byte("one\n"), []byte("one\n"), "HEAD", "feature")
+	if same.Conflicts != 0 || string(same.Content) != "one\n" {
+		t.Fatalf("identical changes should merge cleanly, got:\n%s", same.Content)
+	}
+}
+
+func TestMerge3KeepsMissingFinalNewline(t *testing.T) {
+	base := []byte("one\ntwo\nthree")
+
+	clean := diff.Merge3(base, []byte("ONE\ntwo\nthree"), []byte("one\ntwo\nTHREE"), "HEAD", "feature")
+	if clean.Conflicts != 0 || string(clean.Content) != "ONE\ntwo\nTHREE" {
+		t.Fatalf("expected a clean merge without a final newline, got %q", clean.Content)
+	}
+
+	// Adding the newline is a change like any other
+	added := diff.Merge3(base, []byte("ONE\ntwo\nthree"), []byte("one\ntwo\nthree\n"), "HEAD", "feature")
+	if added.Conflicts != 0 || string(added.Content) != "ONE\ntwo\nthree\n" {
+		t.Fatalf("expected the added newline to be merged, got %q", added.Content)
+	}
+
+	// Conflicting last lines still get their markers on lines of their own
+	conflicted := diff.Merge3(base, []byte("one\ntwo\nours"), []byte("one\ntwo\ntheirs"), "HEAD", "feature")
+	want := "one\ntwo\n<<<<<<< HEAD\nours\n=======\ntheirs\n>>>>>>> feature\n"
+	if conflicted.Conflicts != 1 || string(conflicted.Content) != want {
+		t.Fatalf("unexpected conflict output %q", conflicted.Content)
+	}
+}
+
+func TestMergeFastForward(t *testing.T) {
+	repoPath := fixtures.InitRepo(t)
+	cleanup := fixtures.Chdir(t, repoPath)
+	defer cleanup()
+
+	setupDivergedBranches(t, repoPath)
+	fixtures.RunCLI(t, "checkout", "feature")
+	commitSnapshot(t, repoPath, "new.txt", "new\n", "Feature work")
+	fixtures.RunCLI(t, "checkout", "main")
+
+	out := fixtures.CaptureCLI(t, "merge", "feature")
+	if !strings.Contains(out, "Fast-forward\n") {
+		t.Fatalf("expected fast-forward, got:\n%s", out)
+	}
+
+	repo, _ := repository.NewRepository(repoPath)
+	refsMan, _ := repo.GetRefsManager()
+	mainCommit, _ := refsMan.GetBranch("main")
+	featureCommit, _ := refsMan.GetBranch("feature")
+	if mainCommit != featureCommit {
+		t.Fatalf("main should now point at feature: %s != %s", mainCommit, featureCommit)
+	}
+	if readFile(t, filepath.Join(repoPath, "new.txt")) != "new\n" {
+		t.Fatal("fast-forward should check out the new file")
+	}
+
+	if out := fixtures.CaptureCLI(t, "merge", "feature"); out != "Already up to date.\n" {
+		t.Fatalf("expected nothing to merge, got:\n%s", out)
+	}
+}
+
+func TestMergeCleanCreatesMergeCommit(t *testing.T) {
+	repoPath := fixtures.InitRepo(t)
+	cleanup := fixtures.Chdir(t, repoPath)
+	defer cleanup()
+
+	setupDivergedBranches(t, repoPath)
+	commitSnapshot(t, repoPath, "file.txt", "ONE\ntwo\nthree\nfour\nfive\n", "Main edit")
+
+	fixtures.RunCLI(t, "checkout", "feature")
+	commitSnapshot(t, repoPath, "file.txt", "one\ntwo\nthree\nfour\nFIVE\n", "Feature edit")
+	commitSnapshot(t, repoPath, "feature.txt", "feature\n", "Feature file")
+	fixtures.RunCLI(t, "checkout", "main")
+
+	out := fixtures.CaptureCLI(t, "merge", "feature")
+	if !strings.Contains(out, "Auto-merging file.txt\n") || !strings.Contains(out, "Merge made by") {
+		t.Fatalf("unexpected merge output:\n%s", out)
+	}
+
+	if got := readFile(t, filepath.Join(repoPath, "file.txt")); got != "ONE\ntwo\nthree\nfour\nFIVE\n" {
+		t.Fatalf("merged content mismatch:\n%s", got)
+	}
+	if readFile(t, filepath.Join(repoPath, "feature.txt")) != "feature\n" {
+		t.Fatal("file added on feature should be merged in")
+	}
+
+	repo, _ := repository.NewRepository(repoPath)
+	store, _ := repo.GetObjectStore()
+	refsMan, _ := repo.GetRefsManager()
+	mainHead, _ := refsMan.GetBranch("main")
+	featureHead, _ := refsMan.GetBranch("feature")
+
+	commit, _ := store.ParseCommit(mustLoad(t, store, mainHead))
+	if len(commit.Parents) != 2 || commit.Parents[1] != featureHead {
+		t.Fatalf("expected a two-parent merge commit, got parents %v", commit.Parents)
+	}
+	if commit.Message != "Merge branch 'feature'" {
+		t.Fatalf("unexpected merge message %q", commit.Message)
+	}
+
+	if status := fixtures.CaptureCLI(t, "status"); !strings.Contains(status, "nothing to commit") {
+		t.Fatalf("working tree should be clean after merge:\n%s", status)
+	}
+}
+
+func TestMergeConflictAndContinue(t *testing.T) {
+	repoPath := fixtures.InitRepo(t)
+	cleanup := fixtures.Chdir(t, repoPath)
+	defer cleanup()
+
+	setupDivergedBranches(t, repoPath)
+	commitSnapshot(t, repoPath, "file.txt", "one\ntwo\nmain\nfour\nfive\n", "Main edit")
+
+	fixtures.RunCLI(t, "checkout", "feature")
+	commitSnapshot(t, repoPath, "file.txt", "one\ntwo\nfeature\nfour\nfive\n", "Feature edit")
+	fixtures.RunCLI(t, "checkout", "main")
+
+	out, err := fixtures.TryCaptureCLI(t, "merge", "feature")
+	if err == nil || !strings.Contains(err.Error(), "Automatic merge failed") {
+		t.Fatalf("expected merge to stop on conflict, got %v", err)
+	}
+	if !strings.Contains(out, "CONFLICT (content): Merge conflict in file.txt\n") {
+		t.Fatalf("conflict not reported:\n%s", out)
+	}
+
+	want := "one\ntwo\n<<<<<<< HEAD\nmain\n=======\nfeature\n>>>>>>> feature\nfour\nfive\n"
+	if got := readFile(t, filepath.Join(repoPath, "file.txt")); got != want {
+		t.Fatalf("conflict markers mismatch:\n%s", got)
+	}
+
+	repo, _ := repository.NewRepository(repoPath)
+	idx, _ := repo.GetIndex()
+	stages := idx.GetConflicts()["file.txt"]
+	if len(stages) != 3 || stages[0].Stage != 1 || stages[1].Stage != 2 || stages[2].Stage != 3 {
+		t.Fatalf("expected base/ours/theirs stages in the index, got %+v", stages)
+	}
+
+	status := fixtures.CaptureCLI(t, "status")
+	if !strings.Contains(status, "Unmerged paths:") || !strings.Contains(status, "both modified:   file.txt") {
+		t.Fatalf("status should list the unmerged path:\n%s", status)
+	}
+
+	if err := fixtures.TryCLI(t, "commit", "-m", "too early"); err == nil || !strings.Contains(err.Error(), "unmerged files") {
+		t.Fatalf("expected commit to refuse unmerged files, got %v", err)
+	}
+
+	fixtures.CreateFiles(t, repoPath, map[string]string{"file.txt": "one\ntwo\nboth\nfour\nfive\n"})
+	fixtures.RunCLI(t, "add", "file.txt")
+	fixtures.RunCLI(t, "merge", "--continue")
+
+	refsMan, _ := repo.GetRefsManager()
+	store, _ := repo.GetObjectStore()
+	mainHead, _ := refsMan.GetBranch("main")
+	featureHead, _ := refsMan.GetBranch("feature")
+
+	commit, _ := store.ParseCommit(mustLoad(t, store, mainHead))
+	if len(commit.Parents) != 2 || commit.Parents[1] != featureHead {
+		t.Fatalf("expected a two-parent merge commit, got parents %v", commit.Parents)
+	}
+	if _, err := os.Stat(filepath.Join(repoPath, ".minigit", "MERGE_HEAD")); !os.IsNotExist(err) {
+		t.Fatal("MERGE_HEAD should be removed once the merge is concluded")
+	}
+}
+
+func TestMergeAbort(t *testing.T) {
+	repoPath := fixtures.InitRepo(t)
+	cleanup := fixtures.Chdir(t, repoPath)
+	defer cleanup()
+
+	setupDivergedBranches(t, repoPath)
+	commitSnapshot(t, repoPath, "file.txt", "main\n", "Main edit")
+
+	fixtures.RunCLI(t, "checkout", "feature")
+	commitSnapshot(t, repoPath, "file.txt", "feature\n", "Feature edit")
+	commitSnapshot(t, repoPath, "extra.txt", "extra\n", "Feature extra")
+	fixtures.RunCLI(t, "checkout", "main")
+
+	if _, err := fixtures.TryCaptureCLI(t, "merge", "feature"); err == nil {
+		t.Fatal("expected a conflict")
+	}
+
+	fixtures.RunCLI(t, "merge", "--abort")
+
+	if got := readFile(t, filepath.Join(repoPath, "file.txt")); got != "main\n" {
+		t.Fatalf("abort should restore HEAD's version, got:\n%s", got)
+	}
+	if _, err := os.Stat(filepath.Join(repoPath, "extra.txt")); !os.IsNotExist(err) {
+		t.Fatal("abort should remove files brought in by the merge")
+	}
+	if status := fixtures.CaptureCLI(t, "status"); !strings.Contains(status, "nothing to commit") {
+		t.Fatalf("expected a clean tree after abort:\n%s", status)
+	}
+
+	if err := fixtures.TryCLI(t, "merge", "--abort"); err == nil || !strings.Contains(err.Error(), "There is no merge to abort") {
+		t.Fatalf("expected no merge in progress error, got %v", err)
+	}
+}
+
+func TestMergeRefusesToOverwriteLocalChanges(t *testing.T) {
+	repoPath := fixtures.InitRepo(t)
+	cleanup := fixtures.Chdir(t, repoPath)
+	defer cleanup()
+
+	setupDivergedBranches(t, repoPath)
+	commitSnapshot(t, repoPath, "other.txt", "other\n", "Main work")
+
+	fixtures.RunCLI(t, "checkout", "feature")
+	commitSnapshot(t, repoPath, "file.txt", "changed\n", "Feature edit")
+	fixtures.RunCLI(t, "checkout", "main")
+
+	fixtures.CreateFiles(t, repoPath, map[string]string{"file.txt": "local edit\n"})
+
+	err := fixtures.TryCLI(t, "merge", "feature")
+	if err == nil || !strings.Contains(err.Error(), "would be overwritten by merge") {
+		t.Fatalf("expected merge to refuse, got %v", err)
+	}
+	if got := readFile(t, filepath.Join(repoPath, "file.txt")); got != "local edit\n" {
+		t.Fatalf("local changes should be left alone, got:\n%s", got)
+	}
+}
+
+func TestMergeRefusesUnrelatedHistories(t *testing.T) {
+	repoPath := fixtures.InitRepo(t)
+	cleanup := fixtures.Chdir(t, repoPath)
+	defer cleanup()
+
+	commitSnapshot(t, repoPath, "file.txt", "main\n", "Main root")
+	tree := strings.TrimSpace(fixtures.CaptureCLI(t, "write-tree"))
+	root := strings.TrimSpace(fixtures.CaptureCLI(t, "commit-tree", tree, "-m", "Other root"))
+	fixtures.RunCLI(t, "branch", "other", root)
+
+	err := fixtures.TryCLI(t, "merge", "other")
+	if err == nil || !strings.Contains(err.Error(), "refusing to merge unrelated histories") {
+		t.Fatalf("expected unrelated histories to be refused, got %v", err)
+	}
+}
+
+func TestMergeConflictsOnDifferentModeChanges(t *testing.T) {
+	repoPath := fixtures.InitRepo(t)
+	cleanup := fixtures.Chdir(t, repoPath)
+	defer cleanup()
+
+	setupDivergedBranches(t, repoPath)
+	commitSnapshot(t, repoPath, "run.sh", "echo hi\n", "Add run.sh")
+
+	fixtures.RunCLI(t, "checkout", "feature")
+	fixtures.CreateFiles(t, repoPath, map[string]string{"run.sh": "echo hi\n"})
+	if err := os.Chmod(filepath.Join(repoPath, "run.sh"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	fixtures.RunCLI(t, "add", "run.sh")
+	fixtures.RunCLI(t, "commit", "-m", "Add executable run.sh")
+	fixtures.RunCLI(t, "checkout", "main")
+
+	out, err := fixtures.TryCaptureCLI(t, "merge", "feature")
+	if err == nil || !strings.Contains(err.Error(), "Automatic merge failed") {
+		t.Fatalf("expected the mode change to conflict, got %v", err)
+	}
+	if !strings.Contains(out, "CONFLICT (add/add): Merge conflict in run.sh\n") {
+		t.Fatalf("conflict not reported:\n%s", out)
+	}
+
+	repo, _ := repository.NewRepository(repoPath)
+	idx, _ := repo.GetIndex()
+	if stages := idx.GetConflicts()["run.sh"]; len(stages) != 2 || stages[0].Mode != 0644 || stages[1].Mode != 0755 {
+		t.Fatalf("expected both modes staged, got %+v", stages)
+	}
+}
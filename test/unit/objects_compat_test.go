@@ -0,0 +1,285 @@
+package unit
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"minigit/internal/objects"
+	"minigit/internal/repository"
+	"minigit/test/fixtures"
+)
+
+// Hashes below were produced by git itself for the same content
+
+func TestBlobHashesMatchGit(t *testing.T) {
+	store, _ := objects.NewStore(t.TempDir())
+
+	cases := map[string]string{
+		"":               "e69de29bb2d1d6434b8b29ae775ad8c2e48c5391",
+		"test content\n": "d670460b4b4aece5915caf5c68d12f560a9fe3e4",
+		"version 1\n":    "83baae61804e65cc73a7201a7252750c76066a30",
+	}
+
+	for content, want := range cases {
+		hash, err := store.StoreObject(objects.BlobObject, []byte(content))
+		if err != nil {
+			t.Fatalf("StoreObject failed: %v", err)
+		}
+		if hash != want {
+			t.Errorf("blob %q hashed to %s, want %s", content, hash, want)
+		}
+	}
+}
+
+func TestTreeHashesMatchGit(t *testing.T) {
+	store, _ := objects.NewStore(t.TempDir())
+
+	single, err := store.CreateTreeFromIndex(map[string]*objects.IndexEntry{
+		"test.txt": {Path: "test.txt", Hash: "83baae61804e65cc73a7201a7252750c76066a30", Mode: 0644},
+	})
+	if err != nil {
+		t.Fatalf("CreateTreeFromIndex failed: %v", err)
+	}
+	if want := "d8329fc1cc938780ffdd9f94e0d364e0ea74f579"; single != want {
+		t.Fatalf("single file tree hashed to %s, want %s", single, want)
+	}
+
+	// "foo" sorts after "foo-bar" and "foo.txt" because Git compares it as "foo/"
+	store.StoreObject(objects.BlobObject, []byte("bar\n"))
+	tree, err := store.CreateTreeFromIndex(map[string]*objects.IndexEntry{
+		"foo-bar": {Path: "foo-bar", Hash: "975fbec8256d3e8a3797e7a3611380f27c49f4ac", Mode: 0644},
+		"foo.txt": {Path: "foo.txt", Hash: "587be6b4c3f93f93c489c0111bba5596147a26cb", Mode: 0644},
+		"foo/bar": {Path: "foo/bar", Hash: "5716ca5987cbf97d6bb54920bea6adde242d87e6", Mode: 0644},
+		"link":    {Path: "link", Hash: "996f1789ff67c0e3f69ef5933a55d54c5d0e9954", Mode: os.ModeSymlink | 0777},
+		"run.sh":  {Path: "run.sh", Hash: "1a2485251c33a70432394c93fb89330ef214bfc9", Mode: 0755},
+	})
+	if err != nil {
+		t.Fatalf("CreateTreeFromIndex failed: %v", err)
+	}
+	if want := "542183ac909fd1a54dc2512eb3db858fc915c88b"; tree != want {
+		t.Fatalf("tree hashed to %s, want %s", tree, want)
+	}
+
+	obj, err := store.LoadObject(tree)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := store.ParseTree(obj.Content)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	for _, entry := range parsed.Entries {
+		names = append(names, entry.Name+" "+objects.FormatMode(entry.Mode))
+	}
+	want := []string{"foo-bar 100644", "foo.txt 100644", "foo 40000", "link 120000", "run.sh 100755"}
+	if len(names) != len(want) {
+		t.Fatalf("parsed entries %v, want %v", names, want)
+	}
+	for i := range want {
+		if names[i] != want[i] {
+			t.Fatalf("parsed entries %v, want %v", names, want)
+		}
+	}
+}
+
+func TestCommitHashMatchesGit(t *testing.T) {
+	store, _ := objects.NewStore(t.TempDir())
+
+	scott := objects.Signature{
+		Name:  "Scott Chacon",
+		Email: "schacon@gmail.com",
+		When:  time.Unix(1243040974, 0).In(time.FixedZone("", -7*60*60)),
+	}
+	hash, err := store.WriteCommit(&objects.Commit{
+		Tree:      "542183ac909fd1a54dc2512eb3db858fc915c88b",
+		Author:    scott,
+		Committer: scott,
+		Message:   "first commit",
+	})
+	if err != nil {
+		t.Fatalf("WriteCommit failed: %v", err)
+	}
+	if want := "ad28d99e8a20704fcab4fcd7716e100389c0ff68"; hash != want {
+		t.Fatalf("commit hashed to %s, want %s", hash, want)
+	}
+}
+
+func TestParseModeAcceptsLegacyTrees(t *testing.T) {
+	cases := []struct {
+		mode    string
+		want    os.FileMode
+		objType objects.ObjectType
+	}{
+		{"100644", 0644, objects.BlobObject},
+		{"100755", 0755, objects.BlobObject},
+		{"120000", os.ModeSymlink | 0777, objects.BlobObject},
+		{"40000", os.ModeDir | 0755, objects.TreeObject},
+		{"644", 0644, objects.BlobObject},
+		{"664", 0644, objects.BlobObject},
+		{"755", 0755, objects.BlobObject},
+	}
+
+	for _, tc := range cases {
+		mode, objType, err := objects.ParseMode(tc.mode)
+		if err != nil {
+			t.Fatalf("ParseMode(%s): %v", tc.mode, err)
+		}
+		if mode != tc.want || objType != tc.objType {
+			t.Errorf("ParseMode(%s) = %v %s, want %v %s", tc.mode, mode, objType, tc.want, tc.objType)
+		}
+	}
+
+	if _, _, err := objects.ParseMode("bogus"); err == nil {
+		t.Fatal("expected an error for an invalid mode")
+	}
+}
+
+func TestSymlinksAreStoredAsLinks(t *testing.T) {
+	repoPath := fixtures.InitRepo(t)
+	cleanup := fixtures.Chdir(t, repoPath)
+	defer cleanup()
+
+	fixtures.CreateFiles(t, repoPath, map[string]string{"foo.txt": "x\n"})
+	if err := os.Symlink("foo.txt", filepath.Join(repoPath, "link")); err != nil {
+		t.Skipf("symlinks unsupported: %v", err)
+	}
+	fixtures.RunCLI(t, "add", ".")
+	fixtures.RunCLI(t, "commit", "-m", "Add link")
+
+	repo, _ := repository.NewRepository(repoPath)
+	idx, _ := repo.GetIndex()
+	entry := idx.GetEntries()["link"]
+	if entry == nil || objects.FormatMode(entry.Mode) != objects.ModeSymlink {
+		t.Fatalf("expected link to be staged as a symlink, got %+v", entry)
+	}
+	if want := "996f1789ff67c0e3f69ef5933a55d54c5d0e9954"; entry.Hash != want {
+		t.Fatalf("link blob is %s, want %s (the link target)", entry.Hash, want)
+	}
+
+	os.Remove(filepath.Join(repoPath, "link"))
+	fixtures.RunCLI(t, "restore", "link")
+
+	target, err := os.Readlink(filepath.Join(repoPath, "link"))
+	if err != nil || target != "foo.txt" {
+		t.Fatalf("expected link to be restored as a symlink to foo.txt, got %q (%v)", target, err)
+	}
+}
+
+func TestDanglingSymlinksStayTracked(t *testing.T) {
+	repoPath := fixtures.InitRepo(t)
+	cleanup := fixtures.Chdir(t, repoPath)
+	defer cleanup()
+
+	fixtures.CreateFiles(t, repoPath, map[string]string{"dir/a.txt": "a\n"})
+	if err := os.Symlink("missing.txt", filepath.Join(repoPath, "dir", "link")); err != nil {
+		t.Skipf("symlinks unsupported: %v", err)
+	}
+	fixtures.RunCLI(t, "add", ".")
+	fixtures.RunCLI(t, "commit", "-m", "Add dangling link")
+
+	// Adding the directory again must not take the link for a deleted file
+	fixtures.RunCLI(t, "add", "dir")
+	fixtures.RunCLI(t, "add", ".")
+	if out := fixtures.CaptureCLI(t, "ls-files"); out != "dir/a.txt\ndir/link\n" {
+		t.Fatalf("expected the link to stay staged, got:\n%s", out)
+	}
+	if out := fixtures.CaptureCLI(t, "status"); !strings.Contains(out, "nothing to commit, working tree clean") {
+		t.Fatalf("expected a clean status:\n%s", out)
+	}
+}
+
+func TestCommitRoundTripsExactly(t *testing.T) {
+	store, _ := objects.NewStore(t.TempDir())
+
+	cases := map[string]string{
+		// Written by git with distinct identities and zones
+		"git": "tree aaff74984cccd156a469afa7d9ab10e4777beb24\n" +
+			"author Ada Lovelace <ada@example.com> 1700000000 +0530\n" +
+			"committer Charles Babbage <cb@example.com> 1700003600 -0800\n" +
+			"\nAdd a\n\nBody line\n",
+		"signed": "tree aaff74984cccd156a469afa7d9ab10e4777beb24\n" +
+			"parent e6a1d38e5df0e99c1d78d4d1670c2c03c608e71f\n" +
+			"author Ada Lovelace <ada@example.com> 1700000000 +0530\n" +
+			"committer Ada Lovelace <ada@example.com> 1700000000 +0530\n" +
+			"gpgsig -----BEGIN PGP SIGNATURE-----\n \n iQEzBAAB\n -----END PGP SIGNATURE-----\n" +
+			"\nSigned\n",
+		"unterminated": "tree aaff74984cccd156a469afa7d9ab10e4777beb24\n" +
+			"author A <a@example.com> 0 +0000\n" +
+			"committer A <a@example.com> 0 +0000\n" +
+			"\nno newline",
+		"empty message": "tree aaff74984cccd156a469afa7d9ab10e4777beb24\n" +
+			"author A <a@example.com> 0 -0130\n" +
+			"committer A <a@example.com> 0 -0130\n\n",
+	}
+
+	for name, raw := range cases {
+		commit, err := store.ParseCommit([]byte(raw))
+		if err != nil {
+			t.Fatalf("%s: ParseCommit failed: %v", name, err)
+		}
+
+		hash, err := store.WriteCommit(commit)
+		if err != nil {
+			t.Fatalf("%s: WriteCommit failed: %v", name, err)
+		}
+		if want := store.HashContent(objects.CommitObject, []byte(raw)); hash != want {
+			obj, _ := store.LoadObject(hash)
+			t.Fatalf("%s: rehashed to %s, want %s; wrote:\n%s", name, hash, want, obj.Content)
+		}
+	}
+
+	commit, _ := store.ParseCommit([]byte(cases["git"]))
+	if commit.Author.String() != "Ada Lovelace <ada@example.com>" || commit.Committer.String() != "Charles Babbage <cb@example.com>" {
+		t.Fatalf("unexpected identities: %s / %s", commit.Author, commit.Committer)
+	}
+	if _, offset := commit.Author.When.Zone(); offset != 5*60*60+30*60 {
+		t.Fatalf("author zone offset %d, want +0530", offset)
+	}
+	if _, offset := commit.Committer.When.Zone(); offset != -8*60*60 || commit.Committer.When.Unix() != 1700003600 {
+		t.Fatalf("unexpected committer time %v", commit.Committer.When)
+	}
+	if commit.Message != "Add a\n\nBody line" {
+		t.Fatalf("unexpected message %q", commit.Message)
+	}
+}
+
+func TestTagHashMatchesGit(t *testing.T) {
+	store, _ := objects.NewStore(t.TempDir())
+
+	tagger := objects.Signature{
+		Name:  "Test User",
+		Email: "test@example.com",
+		When:  time.Unix(1700000000, 0).In(time.FixedZone("", 60*60)),
+	}
+	hash, err := store.WriteTag(&objects.Tag{
+		Object:  "d8329fc1cc938780ffdd9f94e0d364e0ea74f579",
+		Type:    objects.TreeObject,
+		Name:    "v1.0",
+		Tagger:  &tagger,
+		Message: "Release 1.0",
+	})
+	if err != nil {
+		t.Fatalf("WriteTag failed: %v", err)
+	}
+	if want := "6cc8468d6b355aa317386a4907b185465b56d2cc"; hash != want {
+		t.Fatalf("tag hashed to %s, want %s", hash, want)
+	}
+
+	// Old tags without a tagger or a trailing newline re-serialize as they were
+	raw := "object d8329fc1cc938780ffdd9f94e0d364e0ea74f579\ntype tree\ntag old\n\nno newline"
+	tag, err := store.ParseTag([]byte(raw))
+	if err != nil {
+		t.Fatalf("ParseTag failed: %v", err)
+	}
+	if tag.Tagger != nil || tag.Name != "old" || tag.Message != "no newline" {
+		t.Fatalf("unexpected tag %+v", tag)
+	}
+	if hash, _ := store.WriteTag(tag); hash != store.HashContent(objects.TagObject, []byte(raw)) {
+		t.Fatalf("tag did not round-trip, hashed to %s", hash)
+	}
+}
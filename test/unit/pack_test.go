@@ -0,0 +1,197 @@
+package unit
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"minigit/internal/objects"
+	"minigit/internal/repository"
+	"minigit/test/fixtures"
+)
+
+// Commits several revisions of a growing file so that packing finds deltas
+func commitRevisions(t *testing.T, repoPath string, count int) {
+	t.Helper()
+
+	var content strings.Builder
+	for i := 1; i <= count; i++ {
+		for line := 0; line < 100; line++ {
+			fmt.Fprintf(&content, "revision %d line %d\n", i, line)
+		}
+		fixtures.CommitFile(t, repoPath, "notes.txt", content.String(), fmt.Sprintf("Revision %d", i))
+	}
+}
+
+// Loads every object in the store, keyed by hash
+func snapshotObjects(t *testing.T, store *objects.Store) map[string]*objects.Object {
+	t.Helper()
+
+	hashes, err := store.ListObjects()
+	if err != nil {
+		t.Fatalf("ListObjects failed: %v", err)
+	}
+
+	snapshot := make(map[string]*objects.Object)
+	for _, hash := range hashes {
+		obj, err := store.LoadObject(hash)
+		if err != nil {
+			t.Fatalf("LoadObject(%s) failed: %v", hash, err)
+		}
+		snapshot[hash] = obj
+	}
+	return snapshot
+}
+
+func assertSameObjects(t *testing.T, store *objects.Store, want map[string]*objects.Object) {
+	t.Helper()
+
+	got := snapshotObjects(t, store)
+	if len(got) != len(want) {
+		t.Fatalf("store has %d objects, want %d", len(got), len(want))
+	}
+	for hash, obj := range want {
+		packed, ok := got[hash]
+		if !ok {
+			t.Fatalf("object %s went missing", hash)
+		}
+		if packed.Type != obj.Type || string(packed.Content) != string(obj.Content) {
+			t.Fatalf("object %s changed after packing", hash)
+		}
+		if store.HashContent(packed.Type, packed.Content) != hash {
+			t.Fatalf("object %s no longer hashes to its name", hash)
+		}
+	}
+}
+
+func TestGCPacksEverythingWithDeltas(t *testing.T) {
+	repoPath := fixtures.InitRepo(t)
+	cleanup := fixtures.Chdir(t, repoPath)
+	defer cleanup()
+
+	commitRevisions(t, repoPath, 6)
+
+	repo, _ := repository.NewRepository(repoPath)
+	store, _ := repo.GetObjectStore()
+	before := snapshotObjects(t, store)
+
+	out := fixtures.CaptureCLI(t, "gc")
+	if !strings.HasPrefix(out, fmt.Sprintf("Total %d (delta ", len(before))) || strings.Contains(out, "(delta 0)") {
+		t.Fatalf("expected every object packed with some deltas, got %q", out)
+	}
+
+	if loose, _ := store.LooseObjects(); len(loose) != 0 {
+		t.Fatalf("gc should remove packed loose objects, %d left", len(loose))
+	}
+	if packs, _ := store.PackNames(); len(packs) != 1 {
+		t.Fatalf("expected a single pack, got %v", packs)
+	}
+
+	// A fresh store only has the pack to go on
+	repo, _ = repository.NewRepository(repoPath)
+	store, _ = repo.GetObjectStore()
+	assertSameObjects(t, store, before)
+
+	log := fixtures.CaptureCLI(t, "log", "--oneline")
+	if strings.Count(log, "Revision") != 6 {
+		t.Fatalf("history should be readable from the pack:\n%s", log)
+	}
+}
+
+func TestRepackOnlyPacksLooseObjects(t *testing.T) {
+	repoPath := fixtures.InitRepo(t)
+	cleanup := fixtures.Chdir(t, repoPath)
+	defer cleanup()
+
+	commitRevisions(t, repoPath, 2)
+	fixtures.RunCLI(t, "repack", "-d")
+
+	fixtures.CommitFile(t, repoPath, "other.txt", "other\n", "Add other")
+
+	repo, _ := repository.NewRepository(repoPath)
+	store, _ := repo.GetObjectStore()
+	loose, _ := store.LooseObjects()
+	if len(loose) != 3 { // blob, tree and commit
+		t.Fatalf("expected 3 new loose objects, got %d", len(loose))
+	}
+
+	// Without -d the loose copies stay
+	if out := fixtures.CaptureCLI(t, "repack"); out != "Total 3 (delta 0)\n" {
+		t.Fatalf("unexpected repack output %q", out)
+	}
+	if packs, _ := store.PackNames(); len(packs) != 2 {
+		t.Fatalf("expected two packs, got %v", packs)
+	}
+	if after, _ := store.LooseObjects(); len(after) != 3 {
+		t.Fatalf("repack without -d should keep loose objects, %d left", len(after))
+	}
+
+	before := snapshotObjects(t, store)
+	fixtures.RunCLI(t, "repack", "-a", "-d")
+	if packs, _ := store.PackNames(); len(packs) != 1 {
+		t.Fatalf("repack -a -d should leave one pack, got %v", packs)
+	}
+	assertSameObjects(t, store, before)
+
+	if out := fixtures.CaptureCLI(t, "gc"); out != "Nothing new to pack.\n" {
+		t.Fatalf("unexpected gc output %q", out)
+	}
+
+	// Objects already in a pack are not written loose again
+	fixtures.RunCLI(t, "add", "other.txt")
+	if loose, _ := store.LooseObjects(); len(loose) != 0 {
+		t.Fatalf("re-adding packed content created loose objects: %v", loose)
+	}
+}
+
+func TestRepackWithRefDeltas(t *testing.T) {
+	repoPath := fixtures.InitRepo(t)
+	cleanup := fixtures.Chdir(t, repoPath)
+	defer cleanup()
+
+	commitRevisions(t, repoPath, 4)
+	fixtures.RunCLI(t, "config", "repack.useDeltaBaseOffset", "false")
+
+	repo, _ := repository.NewRepository(repoPath)
+	store, _ := repo.GetObjectStore()
+	before := snapshotObjects(t, store)
+
+	fixtures.RunCLI(t, "gc", "-q")
+
+	repo, _ = repository.NewRepository(repoPath)
+	store, _ = repo.GetObjectStore()
+	assertSameObjects(t, store, before)
+}
+
+// Cross-checks the pack and index with git itself when it is installed
+func TestPacksAreReadableByGit(t *testing.T) {
+	gitPath, err := exec.LookPath("git")
+	if err != nil {
+		t.Skip("git not installed")
+	}
+
+	repoPath := fixtures.InitRepo(t)
+	cleanup := fixtures.Chdir(t, repoPath)
+	defer cleanup()
+
+	commitRevisions(t, repoPath, 4)
+	fixtures.RunCLI(t, "gc", "-q")
+
+	idxPaths, _ := filepath.Glob(filepath.Join(repoPath, ".minigit", "objects", "pack", "*.idx"))
+	if len(idxPaths) != 1 {
+		t.Fatalf("expected one pack index, got %v", idxPaths)
+	}
+
+	cmd := exec.Command(gitPath, "--git-dir", filepath.Join(repoPath, ".minigit"), "verify-pack", "-v", idxPaths[0])
+	cmd.Env = append(os.Environ(), "GIT_INDEX_FILE="+filepath.Join(t.TempDir(), "index"))
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git verify-pack failed: %v\n%s", err, out)
+	}
+	if !strings.Contains(string(out), "chain length = 1") {
+		t.Fatalf("expected git to see deltas:\n%s", out)
+	}
+}
@@ -0,0 +1,80 @@
+package unit
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"minigit/internal/parallel"
+	"minigit/test/fixtures"
+)
+
+// Runs the rest of the test with the given number of workers
+func setWorkers(t testing.TB, workers int) {
+	t.Helper()
+
+	orig := parallel.Workers
+	parallel.Workers = workers
+	t.Cleanup(func() { parallel.Workers = orig })
+}
+
+func TestParallelMapKeepsOrderAndJoinsErrors(t *testing.T) {
+	setWorkers(t, 4)
+
+	items := make([]int, 100)
+	for i := range items {
+		items[i] = i
+	}
+
+	results, err := parallel.Map(items, func(n int) (string, error) {
+		if n%40 == 39 {
+			return "", fmt.Errorf("item %d failed", n)
+		}
+		return fmt.Sprint(n * 2), nil
+	})
+
+	if err == nil || err.Error() != "item 39 failed\nitem 79 failed" {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for i, result := range results {
+		if i%40 != 39 && result != fmt.Sprint(i*2) {
+			t.Fatalf("result %d is %q", i, result)
+		}
+	}
+}
+
+func TestParallelMapWithoutItems(t *testing.T) {
+	results, err := parallel.Map(nil, func(n int) (int, error) {
+		return 0, errors.New("called")
+	})
+	if err != nil || len(results) != 0 {
+		t.Fatalf("unexpected results %v, %v", results, err)
+	}
+}
+
+func TestParallelAddMatchesSequentialAdd(t *testing.T) {
+	files := make(map[string]string)
+	for i := range 200 {
+		files[fmt.Sprintf("dir%d/file%d.txt", i%7, i)] = strings.Repeat(fmt.Sprintf("line %d\n", i), i+1)
+	}
+
+	stage := func(workers int) string {
+		setWorkers(t, workers)
+		repoPath := fixtures.InitRepo(t)
+		fixtures.CreateFiles(t, repoPath, files)
+		cleanup := fixtures.Chdir(t, repoPath)
+		defer cleanup()
+
+		fixtures.RunCLI(t, "add", ".")
+		return fixtures.CaptureCLI(t, "ls-files", "--stage")
+	}
+
+	sequential := stage(1)
+	if strings.Count(sequential, "\n") != len(files) {
+		t.Fatalf("expected %d entries, got:\n%s", len(files), sequential)
+	}
+	if got := stage(8); got != sequential {
+		t.Fatalf("parallel add staged:\n%s\nwant:\n%s", got, sequential)
+	}
+}
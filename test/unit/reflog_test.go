@@ -0,0 +1,121 @@
+package unit
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"minigit/test/fixtures"
+)
+
+func TestReflogRecordsRefUpdates(t *testing.T) {
+	repoPath := fixtures.InitRepo(t)
+	cleanup := fixtures.Chdir(t, repoPath)
+	defer cleanup()
+
+	fixtures.CommitFile(t, repoPath, "file.txt", "one\n", "First")
+	fixtures.CommitFile(t, repoPath, "file.txt", "two\n", "Second")
+	fixtures.RunCLI(t, "reset", "--hard", "HEAD~1")
+	fixtures.RunCLI(t, "checkout", "-b", "feature")
+
+	first := strings.TrimSpace(fixtures.CaptureCLI(t, "rev-parse", "--short", "main"))
+	second := strings.TrimSpace(fixtures.CaptureCLI(t, "rev-parse", "--short", "main@{1}"))
+
+	want := strings.Join([]string{
+		first + " HEAD@{0}: checkout: moving from main to feature",
+		first + " HEAD@{1}: reset: moving to HEAD~1",
+		second + " HEAD@{2}: commit: Second",
+		first + " HEAD@{3}: commit (initial): First",
+	}, "\n") + "\n"
+	if got := fixtures.CaptureCLI(t, "reflog"); got != want {
+		t.Fatalf("unexpected HEAD reflog:\n%s\nwant:\n%s", got, want)
+	}
+
+	want = strings.Join([]string{
+		first + " main@{0}: reset: moving to HEAD~1",
+		second + " main@{1}: commit: Second",
+		first + " main@{2}: commit (initial): First",
+	}, "\n") + "\n"
+	if got := fixtures.CaptureCLI(t, "reflog", "show", "main"); got != want {
+		t.Fatalf("unexpected main reflog:\n%s\nwant:\n%s", got, want)
+	}
+
+	if got := fixtures.CaptureCLI(t, "reflog", "feature"); got != first+" feature@{0}: branch: Created from HEAD\n" {
+		t.Fatalf("unexpected feature reflog:\n%s", got)
+	}
+	if got := fixtures.CaptureCLI(t, "reflog", "-n", "1"); strings.Count(got, "\n") != 1 {
+		t.Fatalf("-n 1 printed:\n%s", got)
+	}
+
+	// Entries use Git's line format with the committer's identity
+	data, err := os.ReadFile(filepath.Join(repoPath, ".minigit", "logs", "refs", "heads", "main"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	line := regexp.MustCompile(`^0{40} [0-9a-f]{40} Test User <test@example\.com> \d+ [+-]\d{4}\tcommit \(initial\): First$`)
+	if firstLine, _, _ := strings.Cut(string(data), "\n"); !line.MatchString(firstLine) {
+		t.Fatalf("unexpected reflog line: %q", firstLine)
+	}
+}
+
+func TestReflogSyntaxRecoversLostCommits(t *testing.T) {
+	repoPath := fixtures.InitRepo(t)
+	cleanup := fixtures.Chdir(t, repoPath)
+	defer cleanup()
+
+	fixtures.CommitFile(t, repoPath, "file.txt", "one\n", "First")
+	fixtures.CommitFile(t, repoPath, "file.txt", "two\n", "Second")
+	lost := fixtures.CaptureCLI(t, "rev-parse", "HEAD")
+
+	fixtures.RunCLI(t, "reset", "--hard", "HEAD~1")
+	if got := fixtures.CaptureCLI(t, "rev-parse", "HEAD@{1}"); got != lost {
+		t.Fatalf("HEAD@{1} = %q, want %q", got, lost)
+	}
+	if got := fixtures.CaptureCLI(t, "rev-parse", "@{1}"); got != lost {
+		t.Fatalf("@{1} = %q, want %q", got, lost)
+	}
+	if got, want := fixtures.CaptureCLI(t, "rev-parse", "main@{1}~1"), fixtures.CaptureCLI(t, "rev-parse", "HEAD"); got != want {
+		t.Fatalf("main@{1}~1 = %q, want %q", got, want)
+	}
+
+	fixtures.RunCLI(t, "reset", "--hard", "HEAD@{1}")
+	if got := fixtures.CaptureCLI(t, "rev-parse", "HEAD"); got != lost {
+		t.Fatalf("reset to HEAD@{1} left HEAD at %q", got)
+	}
+	content, _ := os.ReadFile(filepath.Join(repoPath, "file.txt"))
+	if string(content) != "two\n" {
+		t.Fatalf("unexpected restored content %q", content)
+	}
+
+	err := fixtures.TryCLI(t, "rev-parse", "main@{10}")
+	if err == nil || !strings.Contains(err.Error(), "log for 'main' only has 4 entries") {
+		t.Fatalf("unexpected error for an entry past the log: %v", err)
+	}
+}
+
+func TestReflogFollowsBranchRenamesAndDeletes(t *testing.T) {
+	repoPath := fixtures.InitRepo(t)
+	cleanup := fixtures.Chdir(t, repoPath)
+	defer cleanup()
+
+	fixtures.CommitFile(t, repoPath, "file.txt", "one\n", "First")
+	fixtures.RunCLI(t, "branch", "topic")
+	fixtures.RunCLI(t, "branch", "-m", "topic", "renamed")
+
+	logsDir := filepath.Join(repoPath, ".minigit", "logs", "refs", "heads")
+	if _, err := os.Stat(filepath.Join(logsDir, "topic")); !os.IsNotExist(err) {
+		t.Fatalf("old reflog still present: %v", err)
+	}
+	got := fixtures.CaptureCLI(t, "reflog", "renamed")
+	if !strings.Contains(got, "renamed@{0}: Branch: renamed refs/heads/topic to refs/heads/renamed\n") ||
+		!strings.Contains(got, "renamed@{1}: branch: Created from HEAD\n") {
+		t.Fatalf("unexpected reflog after rename:\n%s", got)
+	}
+
+	fixtures.RunCLI(t, "branch", "-d", "renamed")
+	if _, err := os.Stat(filepath.Join(logsDir, "renamed")); !os.IsNotExist(err) {
+		t.Fatalf("reflog of a deleted branch still present: %v", err)
+	}
+}
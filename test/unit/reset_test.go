@@ -0,0 +1,163 @@
+package unit
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"minigit/internal/objects"
+	"minigit/internal/repository"
+	"minigit/test/fixtures"
+)
+
+// Commits file.txt, then extra.txt, and returns both commit hashes
+func setupResetHistory(t *testing.T, repoPath string) (string, string) {
+	t.Helper()
+
+	commitSnapshot(t, repoPath, "file.txt", "first", "First")
+	commitSnapshot(t, repoPath, "extra.txt", "extra", "Second")
+
+	repo, _ := repository.NewRepository(repoPath)
+	refsMan, _ := repo.GetRefsManager()
+	second, _ := refsMan.GetBranch("main")
+	store, _ := repo.GetObjectStore()
+	commit, _ := store.ParseCommit(mustLoad(t, store, second))
+
+	return commit.Parents[0], second
+}
+
+func TestResetHard(t *testing.T) {
+	repoPath := fixtures.InitRepo(t)
+	cleanup := fixtures.Chdir(t, repoPath)
+	defer cleanup()
+
+	first, _ := setupResetHistory(t, repoPath)
+	fixtures.CreateFiles(t, repoPath, map[string]string{"file.txt": "dirty"})
+
+	out := fixtures.CaptureCLI(t, "reset", "--hard", first)
+	if !strings.HasPrefix(out, "HEAD is now at "+first[:7]) {
+		t.Fatalf("unexpected output: %q", out)
+	}
+
+	if got := readFile(t, filepath.Join(repoPath, "file.txt")); got != "first" {
+		t.Fatalf("file.txt = %q, want first", got)
+	}
+	if _, err := os.Stat(filepath.Join(repoPath, "extra.txt")); !os.IsNotExist(err) {
+		t.Fatal("extra.txt should be removed by hard reset")
+	}
+
+	repo, _ := repository.NewRepository(repoPath)
+	refsMan, _ := repo.GetRefsManager()
+	if head, _ := refsMan.GetBranch("main"); head != first {
+		t.Fatalf("main = %s, want %s", head, first)
+	}
+	index, _ := repo.GetIndex()
+	if entry, ok := index.GetEntries()["file.txt"]; ok && entry.Hash != calculateBlobHash("first") {
+		t.Fatal("index should match the reset commit")
+	}
+}
+
+func TestResetSoftKeepsChangesStaged(t *testing.T) {
+	repoPath := fixtures.InitRepo(t)
+	cleanup := fixtures.Chdir(t, repoPath)
+	defer cleanup()
+
+	first, _ := setupResetHistory(t, repoPath)
+	fixtures.RunCLI(t, "reset", "--soft", first)
+
+	if _, err := os.Stat(filepath.Join(repoPath, "extra.txt")); err != nil {
+		t.Fatal("soft reset must not touch the working tree")
+	}
+
+	repo, _ := repository.NewRepository(repoPath)
+	refsMan, _ := repo.GetRefsManager()
+	if head, _ := refsMan.GetBranch("main"); head != first {
+		t.Fatalf("main = %s, want %s", head, first)
+	}
+
+	index, _ := repo.GetIndex()
+	if _, ok := index.GetEntries()["extra.txt"]; !ok {
+		t.Fatal("extra.txt should remain staged after soft reset")
+	}
+
+	status := fixtures.CaptureCLI(t, "status")
+	if !strings.Contains(status, "new file:   extra.txt") {
+		t.Fatalf("extra.txt should show as staged:\n%s", status)
+	}
+}
+
+func TestResetMixedUnstages(t *testing.T) {
+	repoPath := fixtures.InitRepo(t)
+	cleanup := fixtures.Chdir(t, repoPath)
+	defer cleanup()
+
+	first, _ := setupResetHistory(t, repoPath)
+	fixtures.CreateFiles(t, repoPath, map[string]string{"file.txt": "changed"})
+	fixtures.RunCLI(t, "add", "file.txt")
+
+	out := fixtures.CaptureCLI(t, "reset", first)
+	if !strings.Contains(out, "M\tfile.txt") {
+		t.Fatalf("expected file.txt listed as unstaged:\n%s", out)
+	}
+
+	if got := readFile(t, filepath.Join(repoPath, "file.txt")); got != "changed" {
+		t.Fatalf("mixed reset must not touch the working tree, got %q", got)
+	}
+
+	repo, _ := repository.NewRepository(repoPath)
+	index, _ := repo.GetIndex()
+	if entry, ok := index.GetEntries()["file.txt"]; ok && entry.Hash == calculateBlobHash("changed") {
+		t.Fatal("staged change should have been reset")
+	}
+}
+
+func TestResetPathUnstages(t *testing.T) {
+	repoPath := fixtures.InitRepo(t)
+	cleanup := fixtures.Chdir(t, repoPath)
+	defer cleanup()
+
+	commitSnapshot(t, repoPath, "file.txt", "committed", "Initial commit")
+
+	fixtures.CreateFiles(t, repoPath, map[string]string{
+		"file.txt":    "staged edit",
+		"new/one.txt": "new",
+	})
+	fixtures.RunCLI(t, "add", "file.txt")
+	fixtures.RunCLI(t, "add", "new")
+
+	fixtures.RunCLI(t, "reset", "file.txt", "new")
+
+	repo, _ := repository.NewRepository(repoPath)
+	index, _ := repo.GetIndex()
+	entries := index.GetEntries()
+	if entry, ok := entries["file.txt"]; ok && entry.Hash != calculateBlobHash("committed") {
+		t.Fatal("file.txt should be back to HEAD's version in the index")
+	}
+	if _, ok := entries["new/one.txt"]; ok {
+		t.Fatal("new/one.txt is not in HEAD and should be dropped from the index")
+	}
+	if got := readFile(t, filepath.Join(repoPath, "file.txt")); got != "staged edit" {
+		t.Fatalf("path reset must not touch the working tree, got %q", got)
+	}
+}
+
+func TestResetErrors(t *testing.T) {
+	repoPath := fixtures.InitRepo(t)
+	cleanup := fixtures.Chdir(t, repoPath)
+	defer cleanup()
+
+	commitSnapshot(t, repoPath, "file.txt", "content", "Initial commit")
+
+	if err := fixtures.TryCLI(t, "reset", "--hard", "--", "file.txt"); err == nil || !strings.Contains(err.Error(), "Cannot do --hard reset with paths") {
+		t.Fatalf("expected hard reset with paths error, got %v", err)
+	}
+	if err := fixtures.TryCLI(t, "reset", "missing.txt"); err == nil || !strings.Contains(err.Error(), "did not match any file") {
+		t.Fatalf("expected pathspec error, got %v", err)
+	}
+}
+
+func calculateBlobHash(content string) string {
+	store := &objects.Store{}
+	return store.HashContent(objects.BlobObject, []byte(content))
+}
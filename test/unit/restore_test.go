@@ -0,0 +1,111 @@
+package unit
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"minigit/internal/repository"
+	"minigit/test/fixtures"
+)
+
+func TestRestoreWorkingTreeFromIndex(t *testing.T) {
+	repoPath := fixtures.InitRepo(t)
+	cleanup := fixtures.Chdir(t, repoPath)
+	defer cleanup()
+
+	commitSnapshot(t, repoPath, "file.txt", "committed", "Initial commit")
+
+	// Staged content wins over HEAD when restoring the working tree
+	fixtures.CreateFiles(t, repoPath, map[string]string{"file.txt": "staged"})
+	fixtures.RunCLI(t, "add", "file.txt")
+	fixtures.CreateFiles(t, repoPath, map[string]string{"file.txt": "scribbled"})
+
+	fixtures.RunCLI(t, "restore", "file.txt")
+
+	if got := readFile(t, filepath.Join(repoPath, "file.txt")); got != "staged" {
+		t.Fatalf("file.txt = %q, want staged", got)
+	}
+}
+
+func TestRestoreDeletedFile(t *testing.T) {
+	repoPath := fixtures.InitRepo(t)
+	cleanup := fixtures.Chdir(t, repoPath)
+	defer cleanup()
+
+	commitSnapshot(t, repoPath, "dir/file.txt", "committed", "Initial commit")
+	if err := os.RemoveAll(filepath.Join(repoPath, "dir")); err != nil {
+		t.Fatalf("remove: %v", err)
+	}
+
+	fixtures.RunCLI(t, "restore", "dir")
+
+	if got := readFile(t, filepath.Join(repoPath, "dir", "file.txt")); got != "committed" {
+		t.Fatalf("dir/file.txt = %q, want committed", got)
+	}
+}
+
+func TestRestoreFromSource(t *testing.T) {
+	repoPath := fixtures.InitRepo(t)
+	cleanup := fixtures.Chdir(t, repoPath)
+	defer cleanup()
+
+	commitSnapshot(t, repoPath, "file.txt", "v1", "First")
+
+	repo, _ := repository.NewRepository(repoPath)
+	refsMan, _ := repo.GetRefsManager()
+	first, _ := refsMan.GetBranch("main")
+
+	commitSnapshot(t, repoPath, "file.txt", "v2", "Second")
+
+	fixtures.RunCLI(t, "restore", "--source", first, "file.txt")
+
+	if got := readFile(t, filepath.Join(repoPath, "file.txt")); got != "v1" {
+		t.Fatalf("file.txt = %q, want v1", got)
+	}
+}
+
+func TestRestoreStaged(t *testing.T) {
+	repoPath := fixtures.InitRepo(t)
+	cleanup := fixtures.Chdir(t, repoPath)
+	defer cleanup()
+
+	commitSnapshot(t, repoPath, "file.txt", "committed", "Initial commit")
+
+	fixtures.CreateFiles(t, repoPath, map[string]string{
+		"file.txt": "edited",
+		"new.txt":  "brand new",
+	})
+	fixtures.RunCLI(t, "add", "file.txt")
+	fixtures.RunCLI(t, "add", "new.txt")
+
+	fixtures.RunCLI(t, "restore", "--staged", "file.txt", "new.txt")
+
+	status := fixtures.CaptureCLI(t, "status")
+	if strings.Contains(status, "Changes to be committed") {
+		t.Fatalf("nothing should remain staged:\n%s", status)
+	}
+	if !strings.Contains(status, "modified:   file.txt") {
+		t.Fatalf("file.txt should show as an unstaged modification:\n%s", status)
+	}
+
+	if got := readFile(t, filepath.Join(repoPath, "file.txt")); got != "edited" {
+		t.Fatalf("--staged must not touch the working tree, got %q", got)
+	}
+}
+
+func TestRestoreUnknownPath(t *testing.T) {
+	repoPath := fixtures.InitRepo(t)
+	cleanup := fixtures.Chdir(t, repoPath)
+	defer cleanup()
+
+	commitSnapshot(t, repoPath, "file.txt", "committed", "Initial commit")
+
+	if err := fixtures.TryCLI(t, "restore", "missing.txt"); err == nil || !strings.Contains(err.Error(), "did not match any file") {
+		t.Fatalf("expected pathspec error, got %v", err)
+	}
+	if err := fixtures.TryCLI(t, "restore"); err == nil {
+		t.Fatal("expected error without paths")
+	}
+}
@@ -0,0 +1,238 @@
+package unit
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"minigit/internal/objects"
+	"minigit/internal/repository"
+	"minigit/test/fixtures"
+)
+
+// Builds main with three commits and a side branch forked from the second
+// with one commit of its own; returns the commit hashes by message
+func setupRevisionHistory(t *testing.T, repoPath string) map[string]string {
+	t.Helper()
+
+	fixtures.CommitFile(t, repoPath, "file.txt", "one\n", "c1")
+	fixtures.CommitFile(t, repoPath, "file.txt", "two\n", "c2")
+	fixtures.CommitFile(t, repoPath, "file.txt", "three\n", "c3")
+	fixtures.RunCLI(t, "checkout", "-b", "side", "HEAD~1")
+	fixtures.CommitFile(t, repoPath, "side.txt", "side\n", "s1")
+	fixtures.RunCLI(t, "checkout", "main")
+
+	hashes := make(map[string]string)
+	for _, line := range strings.Split(strings.TrimSpace(fixtures.CaptureCLI(t, "log", "--oneline", "main", "side")), "\n") {
+		short, subject, _ := strings.Cut(line, " ")
+		hashes[subject] = strings.TrimSpace(fixtures.CaptureCLI(t, "rev-parse", short))
+	}
+	return hashes
+}
+
+func TestRevParseResolvesExpressions(t *testing.T) {
+	repoPath := fixtures.InitRepo(t)
+	cleanup := fixtures.Chdir(t, repoPath)
+	defer cleanup()
+
+	hashes := setupRevisionHistory(t, repoPath)
+
+	cases := map[string]string{
+		"HEAD":                 hashes["c3"],
+		"@":                    hashes["c3"],
+		"main":                 hashes["c3"],
+		"refs/heads/side":      hashes["s1"],
+		"HEAD~2":               hashes["c1"],
+		"main^":                hashes["c2"],
+		"main^^":               hashes["c1"],
+		"side~1^0":             hashes["c2"],
+		hashes["c1"][:7]:       hashes["c1"],
+		hashes["c2"][:7] + "~": hashes["c1"],
+		"HEAD:file.txt":        "2bdf67abb163a4ffb2d7f3f0880c9fe5068ce782",
+	}
+	for expr, want := range cases {
+		if got := fixtures.CaptureCLI(t, "rev-parse", expr); got != want+"\n" {
+			t.Errorf("rev-parse %s = %q, want %s", expr, got, want)
+		}
+	}
+
+	repo, _ := repository.NewRepository(repoPath)
+	store, _ := repo.GetObjectStore()
+	commit, _ := store.ParseCommit(mustLoad(t, store, hashes["c3"]))
+	if got := fixtures.CaptureCLI(t, "rev-parse", "HEAD^{tree}"); got != commit.Tree+"\n" {
+		t.Errorf("HEAD^{tree} = %q, want %s", got, commit.Tree)
+	}
+
+	for _, expr := range []string{"HEAD~5", "main^2", "nope", "HEAD:missing.txt"} {
+		if err := fixtures.TryCLI(t, "rev-parse", expr); err == nil {
+			t.Errorf("expected rev-parse %s to fail", expr)
+		}
+	}
+}
+
+func TestRevParseRanges(t *testing.T) {
+	repoPath := fixtures.InitRepo(t)
+	cleanup := fixtures.Chdir(t, repoPath)
+	defer cleanup()
+
+	hashes := setupRevisionHistory(t, repoPath)
+
+	if got, want := fixtures.CaptureCLI(t, "rev-parse", "main..side"), hashes["s1"]+"\n^"+hashes["c3"]+"\n"; got != want {
+		t.Errorf("main..side = %q, want %q", got, want)
+	}
+	want := hashes["s1"] + "\n" + hashes["c3"] + "\n^" + hashes["c2"] + "\n"
+	if got := fixtures.CaptureCLI(t, "rev-parse", "main...side"); got != want {
+		t.Errorf("main...side = %q, want %q", got, want)
+	}
+
+	if got := fixtures.CaptureCLI(t, "log", "--oneline", "main..side"); !strings.HasSuffix(got, " s1\n") || strings.Count(got, "\n") != 1 {
+		t.Errorf("unexpected log main..side:\n%s", got)
+	}
+	if got := fixtures.CaptureCLI(t, "log", "--oneline", "side", "^main"); !strings.HasSuffix(got, " s1\n") || strings.Count(got, "\n") != 1 {
+		t.Errorf("unexpected log side ^main:\n%s", got)
+	}
+	got := fixtures.CaptureCLI(t, "log", "--oneline", "main...side")
+	if strings.Count(got, "\n") != 2 || !strings.Contains(got, " c3\n") || !strings.Contains(got, " s1\n") {
+		t.Errorf("unexpected log main...side:\n%s", got)
+	}
+
+	// The three-dot diff only shows what side changed since forking
+	if got := fixtures.CaptureCLI(t, "diff", "--name-only", "main...side"); got != "side.txt\n" {
+		t.Errorf("unexpected diff main...side: %q", got)
+	}
+	if got := fixtures.CaptureCLI(t, "diff", "--name-only", "main..side"); got != "file.txt\nside.txt\n" {
+		t.Errorf("unexpected diff main..side: %q", got)
+	}
+}
+
+func TestRevParseSymbolicNamesAndPreviousBranch(t *testing.T) {
+	repoPath := fixtures.InitRepo(t)
+	cleanup := fixtures.Chdir(t, repoPath)
+	defer cleanup()
+
+	hashes := setupRevisionHistory(t, repoPath)
+
+	if got := fixtures.CaptureCLI(t, "rev-parse", "--abbrev-ref", "HEAD"); got != "main\n" {
+		t.Errorf("--abbrev-ref HEAD = %q", got)
+	}
+	if got := fixtures.CaptureCLI(t, "rev-parse", "--symbolic-full-name", "side"); got != "refs/heads/side\n" {
+		t.Errorf("--symbolic-full-name side = %q", got)
+	}
+	if got := fixtures.CaptureCLI(t, "rev-parse", "@{-1}"); got != hashes["s1"]+"\n" {
+		t.Errorf("@{-1} = %q, want %s", got, hashes["s1"])
+	}
+
+	fixtures.RunCLI(t, "checkout", "-")
+	if got := fixtures.CaptureCLI(t, "rev-parse", "--abbrev-ref", "HEAD"); got != "side\n" {
+		t.Errorf("checkout - went to %q", got)
+	}
+	fixtures.RunCLI(t, "checkout", "-")
+	if got := fixtures.CaptureCLI(t, "rev-parse", "--abbrev-ref", "HEAD"); got != "main\n" {
+		t.Errorf("checkout - went back to %q", got)
+	}
+
+	// Detaching records the commit as where HEAD came from
+	fixtures.RunCLI(t, "checkout", "HEAD~1")
+	fixtures.RunCLI(t, "checkout", "main")
+	if got := fixtures.CaptureCLI(t, "rev-parse", "@{-1}"); got != hashes["c2"]+"\n" {
+		t.Errorf("@{-1} after detaching = %q, want %s", got, hashes["c2"])
+	}
+	if got := fixtures.CaptureCLI(t, "rev-parse", "--abbrev-ref", "@{-2}"); got != "main\n" {
+		t.Errorf("@{-2} = %q", got)
+	}
+}
+
+func TestRevParseShortAndVerify(t *testing.T) {
+	repoPath := fixtures.InitRepo(t)
+	cleanup := fixtures.Chdir(t, repoPath)
+	defer cleanup()
+
+	hashes := setupRevisionHistory(t, repoPath)
+
+	if got := fixtures.CaptureCLI(t, "rev-parse", "--short", "HEAD"); got != hashes["c3"][:7]+"\n" {
+		t.Errorf("--short HEAD = %q", got)
+	}
+	if got := fixtures.CaptureCLI(t, "rev-parse", "--short=10", "--verify", "side"); got != hashes["s1"][:10]+"\n" {
+		t.Errorf("--short=10 --verify side = %q", got)
+	}
+
+	err := fixtures.TryCLI(t, "rev-parse", "--verify", "nope")
+	if err == nil || err.Error() != "fatal: Needed a single revision" {
+		t.Errorf("unexpected --verify error: %v", err)
+	}
+	if err := fixtures.TryCLI(t, "rev-parse", "--verify", "main", "side"); err == nil {
+		t.Errorf("expected --verify with two revisions to fail")
+	}
+}
+
+func TestRevParseReportsAmbiguousAbbreviations(t *testing.T) {
+	repoPath := fixtures.InitRepo(t)
+	cleanup := fixtures.Chdir(t, repoPath)
+	defer cleanup()
+
+	repo, _ := repository.NewRepository(repoPath)
+	store, _ := repo.GetObjectStore()
+
+	// Store blobs until two share a four character prefix
+	byPrefix := make(map[string]string)
+	var first, second string
+	for i := 0; second == ""; i++ {
+		content := []byte(fmt.Sprintf("blob %d\n", i))
+		hash, err := store.StoreObject(objects.BlobObject, content)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if other, ok := byPrefix[hash[:4]]; ok {
+			first, second = other, hash
+		}
+		byPrefix[hash[:4]] = hash
+	}
+
+	_, err := fixtures.TryCaptureCLI(t, "rev-parse", first[:4])
+	if err == nil || !strings.Contains(err.Error(), "short object ID "+first[:4]+" is ambiguous") ||
+		!strings.Contains(err.Error(), first[:7]+" blob") || !strings.Contains(err.Error(), second[:7]+" blob") {
+		t.Fatalf("unexpected error for ambiguous prefix: %v", err)
+	}
+
+	// A longer prefix singles one out, and --short picks one that is long enough
+	if got := fixtures.CaptureCLI(t, "rev-parse", first[:12]); got != first+"\n" {
+		t.Errorf("rev-parse %s = %q", first[:12], got)
+	}
+	short := strings.TrimSpace(fixtures.CaptureCLI(t, "rev-parse", "--short=4", first))
+	if len(short) <= 4 || !strings.HasPrefix(first, short) {
+		t.Errorf("--short=4 gave %q for %s", short, first)
+	}
+}
+
+func TestLoadObjectRejectsShortNames(t *testing.T) {
+	repoPath := fixtures.InitRepo(t)
+	repo, _ := repository.NewRepository(repoPath)
+	store, _ := repo.GetObjectStore()
+
+	for _, name := range []string{"", "a", "abc", "zzzzzzzzzzzzzzzzzzzzzzzzzzzzzzzzzzzzzzzz"} {
+		if _, err := store.LoadObject(name); err == nil {
+			t.Errorf("expected LoadObject(%q) to fail", name)
+		}
+	}
+}
+
+func TestResetAndCheckoutAcceptRevisionExpressions(t *testing.T) {
+	repoPath := fixtures.InitRepo(t)
+	cleanup := fixtures.Chdir(t, repoPath)
+	defer cleanup()
+
+	hashes := setupRevisionHistory(t, repoPath)
+
+	fixtures.RunCLI(t, "reset", "--hard", "HEAD~1")
+	if got := fixtures.CaptureCLI(t, "rev-parse", "main"); got != hashes["c2"]+"\n" {
+		t.Errorf("reset HEAD~1 left main at %q", got)
+	}
+
+	fixtures.RunCLI(t, "checkout", hashes["c1"][:8])
+	if got := fixtures.CaptureCLI(t, "rev-parse", "HEAD"); got != hashes["c1"]+"\n" {
+		t.Errorf("checkout of an abbreviated hash left HEAD at %q", got)
+	}
+	if got := fixtures.CaptureCLI(t, "rev-parse", "--abbrev-ref", "HEAD"); got != "HEAD\n" {
+		t.Errorf("expected a detached HEAD, got %q", got)
+	}
+}
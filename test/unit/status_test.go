@@ -1,9 +1,13 @@
 package unit
 
 import (
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
+	"minigit/internal/repository"
 	"minigit/test/fixtures"
 )
 
@@ -185,3 +189,209 @@ func TestStatusWithDirectories(t *testing.T) {
 		t.Fatalf("status command failed with directories: %v", err)
 	}
 }
+
+func TestStatusComparesIndexWithHeadAndWorkingTree(t *testing.T) {
+	repoPath := fixtures.InitRepo(t)
+	cleanup := fixtures.Chdir(t, repoPath)
+	defer cleanup()
+
+	fixtures.CreateFiles(t, repoPath, map[string]string{
+		"kept.txt":    "kept",
+		"edited.txt":  "original",
+		"removed.txt": "gone soon",
+	})
+	fixtures.RunCLI(t, "add", ".")
+	fixtures.RunCLI(t, "commit", "-m", "Initial commit")
+
+	// Committed files that were not touched must not show up at all
+	out := fixtures.CaptureCLI(t, "status")
+	if !strings.Contains(out, "nothing to commit, working tree clean") {
+		t.Fatalf("expected clean tree after commit:\n%s", out)
+	}
+
+	fixtures.CreateFiles(t, repoPath, map[string]string{"edited.txt": "staged"})
+	fixtures.RunCLI(t, "add", "edited.txt")
+	fixtures.CreateFiles(t, repoPath, map[string]string{"edited.txt": "staged then edited"})
+	if err := os.Remove(filepath.Join(repoPath, "removed.txt")); err != nil {
+		t.Fatalf("remove: %v", err)
+	}
+
+	out = fixtures.CaptureCLI(t, "status")
+	staged, unstaged, _ := strings.Cut(out, "Changes not staged for commit:")
+	if !strings.Contains(staged, "modified:   edited.txt") {
+		t.Fatalf("edited.txt should be staged:\n%s", out)
+	}
+	if !strings.Contains(unstaged, "modified:   edited.txt") || !strings.Contains(unstaged, "deleted:    removed.txt") {
+		t.Fatalf("expected unstaged modification and deletion:\n%s", out)
+	}
+	if strings.Contains(out, "kept.txt") {
+		t.Fatalf("unchanged file should not be listed:\n%s", out)
+	}
+
+	// Staging the removal moves it to the staged section
+	fixtures.RunCLI(t, "add", "removed.txt")
+	out = fixtures.CaptureCLI(t, "status")
+	staged, _, _ = strings.Cut(out, "Changes not staged for commit:")
+	if !strings.Contains(staged, "deleted:    removed.txt") {
+		t.Fatalf("removed.txt deletion should be staged:\n%s", out)
+	}
+}
+
+func TestStatusReportsModeChanges(t *testing.T) {
+	repoPath := fixtures.InitRepo(t)
+	cleanup := fixtures.Chdir(t, repoPath)
+	defer cleanup()
+
+	fixtures.CommitFile(t, repoPath, "f", "echo hi\n", "Initial commit")
+	if err := os.Chmod(filepath.Join(repoPath, "f"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	out := fixtures.CaptureCLI(t, "status")
+	if _, unstaged, _ := strings.Cut(out, "Changes not staged for commit:"); !strings.Contains(unstaged, "modified:   f\n") {
+		t.Fatalf("chmod should show as an unstaged change:\n%s", out)
+	}
+
+	fixtures.RunCLI(t, "add", "f")
+	out = fixtures.CaptureCLI(t, "status")
+	staged, unstaged, _ := strings.Cut(out, "Changes not staged for commit:")
+	if !strings.Contains(staged, "Changes to be committed:") || !strings.Contains(staged, "modified:   f\n") || unstaged != "" {
+		t.Fatalf("staged chmod should show as a staged change only:\n%s", out)
+	}
+
+	// Without a trusted executable bit only the staged change is left
+	fixtures.RunCLI(t, "commit", "-m", "Make f executable")
+	fixtures.RunCLI(t, "config", "core.filemode", "false")
+	if err := os.Chmod(filepath.Join(repoPath, "f"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if out := fixtures.CaptureCLI(t, "status"); !strings.Contains(out, "working tree clean") {
+		t.Fatalf("chmod should be ignored with core.filemode off:\n%s", out)
+	}
+}
+
+func TestStatusSeedsEmptyIndexFromHead(t *testing.T) {
+	repoPath := fixtures.InitRepo(t)
+	cleanup := fixtures.Chdir(t, repoPath)
+	defer cleanup()
+
+	fixtures.CommitFile(t, repoPath, "file.txt", "content", "Initial commit")
+
+	// Indexes written by older versions were emptied after every commit
+	if err := os.WriteFile(filepath.Join(repoPath, ".minigit", "index"), []byte("{}"), 0644); err != nil {
+		t.Fatalf("write index: %v", err)
+	}
+
+	out := fixtures.CaptureCLI(t, "status")
+	if !strings.Contains(out, "nothing to commit, working tree clean") {
+		t.Fatalf("expected index to be rebuilt from HEAD:\n%s", out)
+	}
+}
+
+func TestRemovingEveryFileIsStagedAndCommitted(t *testing.T) {
+	repoPath := fixtures.InitRepo(t)
+	cleanup := fixtures.Chdir(t, repoPath)
+	defer cleanup()
+
+	fixtures.CreateFiles(t, repoPath, map[string]string{"f.txt": "f\n", "g.txt": "g\n"})
+	fixtures.RunCLI(t, "add", ".")
+	fixtures.RunCLI(t, "commit", "-m", "Initial commit")
+
+	os.Remove(filepath.Join(repoPath, "f.txt"))
+	os.Remove(filepath.Join(repoPath, "g.txt"))
+	fixtures.RunCLI(t, "add", ".")
+
+	// An empty index is not mistaken for one older versions cleared
+	if out := fixtures.CaptureCLI(t, "ls-files"); out != "" {
+		t.Fatalf("expected an empty index, got:\n%s", out)
+	}
+	out := fixtures.CaptureCLI(t, "status")
+	if !strings.Contains(out, "Changes to be committed") || strings.Contains(out, "Changes not staged") {
+		t.Fatalf("expected staged deletions only:\n%s", out)
+	}
+
+	out = fixtures.CaptureCLI(t, "commit", "-m", "Remove everything")
+	if !strings.Contains(out, " 2 files changed, 2 deletions(-)\n") {
+		t.Fatalf("unexpected commit summary:\n%s", out)
+	}
+	if tree := fixtures.CaptureCLI(t, "rev-parse", "HEAD^{tree}"); tree != "4b825dc642cb6eb9a060e54bf8d69288fbee4904\n" {
+		t.Fatalf("expected the empty tree, got %s", tree)
+	}
+	if out := fixtures.CaptureCLI(t, "status"); !strings.Contains(out, "nothing to commit") {
+		t.Fatalf("expected a clean status:\n%s", out)
+	}
+}
+
+// Stages path with a hash that doesn't match its content, so whether status
+// reports it as modified shows if the file was rehashed
+func stageWithWrongHash(t *testing.T, repoPath, path string) {
+	t.Helper()
+
+	repo, err := repository.NewRepository(repoPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	idx, _ := repo.GetIndex()
+	info, err := os.Lstat(filepath.Join(repoPath, path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	idx.AddEntry(path, calculateBlobHash("something else"), info)
+	if err := idx.Write(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestStatusTrustsMatchingStatData(t *testing.T) {
+	repoPath := fixtures.InitRepo(t)
+	cleanup := fixtures.Chdir(t, repoPath)
+	defer cleanup()
+
+	fixtures.CreateFiles(t, repoPath, map[string]string{"file.txt": "content"})
+	old := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(filepath.Join(repoPath, "file.txt"), old, old); err != nil {
+		t.Fatal(err)
+	}
+	stageWithWrongHash(t, repoPath, "file.txt")
+
+	if out := fixtures.CaptureCLI(t, "status"); strings.Contains(out, "Changes not staged") {
+		t.Fatalf("file with unchanged stat data was rehashed:\n%s", out)
+	}
+
+	// Touching the file makes status look at its content again
+	if err := os.Chtimes(filepath.Join(repoPath, "file.txt"), time.Now(), time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if out := fixtures.CaptureCLI(t, "status"); !strings.Contains(out, "modified:   file.txt") {
+		t.Fatalf("touched file was not rehashed:\n%s", out)
+	}
+}
+
+func TestStatusRehashesRacilyCleanEntries(t *testing.T) {
+	repoPath := fixtures.InitRepo(t)
+	cleanup := fixtures.Chdir(t, repoPath)
+	defer cleanup()
+
+	// Modified no earlier than the index is written, so its stat data can't be trusted
+	fixtures.CreateFiles(t, repoPath, map[string]string{"file.txt": "content", "other.txt": "other"})
+	future := time.Now().Add(time.Hour)
+	if err := os.Chtimes(filepath.Join(repoPath, "file.txt"), future, future); err != nil {
+		t.Fatal(err)
+	}
+	stageWithWrongHash(t, repoPath, "file.txt")
+
+	if out := fixtures.CaptureCLI(t, "status"); !strings.Contains(out, "modified:   file.txt") {
+		t.Fatalf("racily clean file was trusted:\n%s", out)
+	}
+
+	// Rewriting the index smudges the entry, so it stays suspect once the
+	// index is newer than the file
+	fixtures.RunCLI(t, "add", "other.txt")
+	later := future.Add(time.Hour)
+	if err := os.Chtimes(filepath.Join(repoPath, ".minigit", "index"), later, later); err != nil {
+		t.Fatal(err)
+	}
+	if out := fixtures.CaptureCLI(t, "status"); !strings.Contains(out, "modified:   file.txt") {
+		t.Fatalf("smudged entry was trusted:\n%s", out)
+	}
+}
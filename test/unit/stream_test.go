@@ -0,0 +1,127 @@
+package unit
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"minigit/internal/objects"
+	"minigit/test/fixtures"
+)
+
+func TestStoreObjectFromReaderMatchesStoreObject(t *testing.T) {
+	minigitDir := t.TempDir()
+	store, _ := objects.NewStore(minigitDir)
+
+	for _, content := range []string{"", "test content\n", strings.Repeat("large ", 100000)} {
+		want := store.HashContent(objects.BlobObject, []byte(content))
+		hash, err := store.StoreObjectFromReader(objects.BlobObject, int64(len(content)), strings.NewReader(content))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if hash != want {
+			t.Fatalf("streamed blob hashed to %s, want %s", hash, want)
+		}
+
+		header, reader, err := store.OpenObject(hash)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := io.ReadAll(reader)
+		reader.Close()
+		if err != nil || string(data) != content || header.Type != objects.BlobObject || header.Size != int64(len(content)) {
+			t.Fatalf("unexpected object %+v (%d bytes, %v)", header, len(data), err)
+		}
+	}
+
+	// Storing an existing object again leaves no temporary file behind
+	if _, err := store.StoreObjectFromReader(objects.BlobObject, 0, strings.NewReader("")); err != nil {
+		t.Fatal(err)
+	}
+	if leftovers, _ := filepath.Glob(filepath.Join(minigitDir, "objects", "tmp_*")); len(leftovers) > 0 {
+		t.Fatalf("temporary files left: %v", leftovers)
+	}
+}
+
+func TestStoreObjectFromReaderChecksSize(t *testing.T) {
+	minigitDir := t.TempDir()
+	store, _ := objects.NewStore(minigitDir)
+
+	if _, err := store.StoreObjectFromReader(objects.BlobObject, 10, strings.NewReader("short")); err == nil {
+		t.Fatal("expected content shorter than its size to fail")
+	}
+	if _, err := store.StoreObjectFromReader(objects.BlobObject, 2, strings.NewReader("longer")); err == nil {
+		t.Fatal("expected content longer than its size to fail")
+	}
+
+	if loose, _ := store.LooseObjects(); len(loose) > 0 {
+		t.Fatalf("failed writes stored objects: %v", loose)
+	}
+	if leftovers, _ := filepath.Glob(filepath.Join(minigitDir, "objects", "tmp_*")); len(leftovers) > 0 {
+		t.Fatalf("temporary files left: %v", leftovers)
+	}
+}
+
+func TestOpenObjectDetectsTruncatedContent(t *testing.T) {
+	minigitDir := t.TempDir()
+	store, _ := objects.NewStore(minigitDir)
+
+	// The header promises more content than follows it
+	var compressed bytes.Buffer
+	writer := zlib.NewWriter(&compressed)
+	writer.Write([]byte("blob 10\x00abc"))
+	writer.Close()
+
+	hash := "0123456789abcdef0123456789abcdef01234567"
+	path := filepath.Join(minigitDir, "objects", hash[:2], hash[2:])
+	os.MkdirAll(filepath.Dir(path), 0755)
+	if err := os.WriteFile(path, compressed.Bytes(), 0444); err != nil {
+		t.Fatal(err)
+	}
+
+	_, reader, err := store.OpenObject(hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+	if _, err := io.ReadAll(reader); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatalf("expected truncation to be reported, got %v", err)
+	}
+	if _, err := store.LoadObject(hash); err == nil {
+		t.Fatal("expected LoadObject to refuse the truncated object")
+	}
+}
+
+func TestLargeFilesRoundTripThroughAddCheckoutAndCatFile(t *testing.T) {
+	repoPath := fixtures.InitRepo(t)
+	cleanup := fixtures.Chdir(t, repoPath)
+	defer cleanup()
+
+	// A single line keeps the diff stats commit prints cheap
+	large := strings.Repeat("0123456789abcdef", 1<<18)
+	fixtures.CreateFiles(t, repoPath, map[string]string{"asset.bin": large})
+	fixtures.RunCLI(t, "add", "asset.bin")
+	fixtures.RunCLI(t, "commit", "-m", "Add asset")
+
+	if got := fixtures.CaptureCLI(t, "cat-file", "-s", "HEAD:asset.bin"); got != "4194304\n" {
+		t.Fatalf("unexpected size %q", got)
+	}
+	if got := fixtures.CaptureCLI(t, "cat-file", "blob", "HEAD:asset.bin"); got != large {
+		t.Fatalf("cat-file printed %d bytes, want %d", len(got), len(large))
+	}
+
+	os.Remove(filepath.Join(repoPath, "asset.bin"))
+	fixtures.RunCLI(t, "checkout", "-b", "other")
+	fixtures.CommitFile(t, repoPath, "asset.bin", "small\n", "Shrink asset")
+	fixtures.RunCLI(t, "checkout", "main")
+
+	content, err := os.ReadFile(filepath.Join(repoPath, "asset.bin"))
+	if err != nil || string(content) != large {
+		t.Fatalf("checkout restored %d bytes, want %d (%v)", len(content), len(large), err)
+	}
+}
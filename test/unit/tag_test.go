@@ -0,0 +1,138 @@
+package unit
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"minigit/internal/repository"
+	"minigit/test/fixtures"
+)
+
+func TestTagCreateListAndDelete(t *testing.T) {
+	repoPath := fixtures.InitRepo(t)
+	cleanup := fixtures.Chdir(t, repoPath)
+	defer cleanup()
+
+	fixtures.CommitFile(t, repoPath, "a.txt", "a", "First\n\nBody")
+	fixtures.RunCLI(t, "tag", "v1.0")
+	fixtures.CommitFile(t, repoPath, "a.txt", "b", "Second")
+	fixtures.RunCLI(t, "tag", "v2.0")
+	fixtures.RunCLI(t, "tag", "release/old", "HEAD~1")
+
+	if out := fixtures.CaptureCLI(t, "tag"); out != "release/old\nv1.0\nv2.0\n" {
+		t.Fatalf("unexpected tag list:\n%s", out)
+	}
+	if out := fixtures.CaptureCLI(t, "tag", "-l", "v1*", "release/*"); out != "release/old\nv1.0\n" {
+		t.Fatalf("unexpected filtered list:\n%s", out)
+	}
+
+	repo, _ := repository.NewRepository(repoPath)
+	refsMan, _ := repo.GetRefsManager()
+	first, _ := refsMan.GetTag("v1.0")
+	if old, _ := refsMan.GetTag("release/old"); old != first {
+		t.Fatalf("tag at HEAD~1 points to %s, want %s", old, first)
+	}
+	// Tags keep no reflog
+	if _, err := os.Stat(filepath.Join(repoPath, ".minigit", "logs", "refs", "tags")); !os.IsNotExist(err) {
+		t.Fatalf("expected no tag reflogs, got %v", err)
+	}
+
+	if err := fixtures.TryCLI(t, "tag", "v1.0"); err == nil || !strings.Contains(err.Error(), "tag 'v1.0' already exists") {
+		t.Fatalf("expected duplicate tag error, got %v", err)
+	}
+	if err := fixtures.TryCLI(t, "tag", "bad..name"); err == nil || !strings.Contains(err.Error(), "not a valid tag name") {
+		t.Fatalf("expected invalid name error, got %v", err)
+	}
+
+	out, err := fixtures.TryCaptureCLI(t, "tag", "-d", "release/old", "missing")
+	if err == nil {
+		t.Fatal("expected deleting a missing tag to fail")
+	}
+	want := "Deleted tag 'release/old' (was " + first[:7] + ")\nerror: tag 'missing' not found.\n"
+	if out != want {
+		t.Fatalf("unexpected delete output:\n%s\nwant:\n%s", out, want)
+	}
+	if _, err := os.Stat(filepath.Join(repoPath, ".minigit", "refs", "tags", "release")); !os.IsNotExist(err) {
+		t.Fatalf("expected empty tag directory to be pruned, got %v", err)
+	}
+}
+
+func TestAnnotatedTag(t *testing.T) {
+	repoPath := fixtures.InitRepo(t)
+	cleanup := fixtures.Chdir(t, repoPath)
+	defer cleanup()
+
+	fixtures.CommitFile(t, repoPath, "a.txt", "a", "Initial commit")
+	t.Setenv("MINIGIT_COMMITTER_DATE", "1700000000 +0000")
+	fixtures.RunCLI(t, "tag", "-a", "-m", "Release 1.0\nFirst stable", "-m", "Notes", "v1.0")
+
+	commit := strings.TrimSpace(fixtures.CaptureCLI(t, "rev-parse", "HEAD"))
+	tagHash := strings.TrimSpace(fixtures.CaptureCLI(t, "rev-parse", "v1.0"))
+	if tagHash == commit {
+		t.Fatal("annotated tag should point to a tag object")
+	}
+	if got := fixtures.CaptureCLI(t, "cat-file", "-t", "v1.0"); got != "tag\n" {
+		t.Fatalf("unexpected type %q", got)
+	}
+
+	want := "object " + commit + "\ntype commit\ntag v1.0\n" +
+		"tagger Test User <test@example.com> 1700000000 +0000\n\n" +
+		"Release 1.0\nFirst stable\n\nNotes\n"
+	if got := fixtures.CaptureCLI(t, "cat-file", "-p", "v1.0"); got != want {
+		t.Fatalf("unexpected tag object:\n%s\nwant:\n%s", got, want)
+	}
+
+	// Revisions peel through the tag
+	for _, rev := range []string{"v1.0^{}", "v1.0^{commit}", "v1.0~0"} {
+		if got := strings.TrimSpace(fixtures.CaptureCLI(t, "rev-parse", rev)); got != commit {
+			t.Fatalf("%s resolved to %s, want %s", rev, got, commit)
+		}
+	}
+	if got := strings.TrimSpace(fixtures.CaptureCLI(t, "rev-parse", "v1.0^{tag}")); got != tagHash {
+		t.Fatalf("v1.0^{tag} resolved to %s, want %s", got, tagHash)
+	}
+	if got := fixtures.CaptureCLI(t, "ls-tree", "--name-only", "v1.0"); got != "a.txt\n" {
+		t.Fatalf("unexpected ls-tree output %q", got)
+	}
+
+	if err := fixtures.TryCLI(t, "tag", "-a", "v2.0"); err == nil || !strings.Contains(err.Error(), "-m") {
+		t.Fatalf("expected -a without a message to fail, got %v", err)
+	}
+
+	if out := fixtures.CaptureCLI(t, "fsck"); out != "" {
+		t.Fatalf("fsck reported problems:\n%s", out)
+	}
+
+	gitPath, err := exec.LookPath("git")
+	if err != nil {
+		t.Skip("git not installed")
+	}
+	cmd := exec.Command(gitPath, "--git-dir", ".minigit", "cat-file", "-p", tagHash)
+	if out, err := cmd.CombinedOutput(); err != nil || string(out) != want {
+		t.Fatalf("git reads the tag as:\n%s\n(%v)", out, err)
+	}
+}
+
+func TestTagListShowsAnnotationLines(t *testing.T) {
+	repoPath := fixtures.InitRepo(t)
+	cleanup := fixtures.Chdir(t, repoPath)
+	defer cleanup()
+
+	fixtures.CommitFile(t, repoPath, "a.txt", "a", "Commit subject\n\nCommit body")
+	fixtures.RunCLI(t, "tag", "light")
+	fixtures.RunCLI(t, "tag", "-m", "Tag subject\nTag body", "annotated")
+
+	// Lightweight tags show the message of the commit they name
+	want := "annotated       Tag subject\nlight           Commit subject\n"
+	if out := fixtures.CaptureCLI(t, "tag", "-n"); out != want {
+		t.Fatalf("unexpected -n output:\n%s\nwant:\n%s", out, want)
+	}
+
+	want = "light           Commit subject\n    \n    Commit body\n"
+	if out := fixtures.CaptureCLI(t, "tag", "-n3", "li*"); out != want {
+		t.Fatalf("unexpected -n3 output:\n%q\nwant:\n%q", out, want)
+	}
+}